@@ -0,0 +1,373 @@
+package appsec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type (
+	// ExportFile is a file of a configuration version split by SplitExportConfiguration
+	ExportFile struct {
+		// Path is the slash separated path of the file relative to the export directory
+		Path    string
+		Content []byte
+	}
+)
+
+const (
+	// ExportConfigFile contains the name, target product and hostnames of the configuration
+	ExportConfigFile = "config.json"
+	// ExportSecurityPoliciesDir contains a file per security policy named after its ID
+	ExportSecurityPoliciesDir = "security-policies"
+	// ExportCustomRulesDir contains a file per custom rule named after its ID and name
+	ExportCustomRulesDir = "custom-rules"
+	// ExportRatePoliciesDir contains a file per rate policy named after its ID and name
+	ExportRatePoliciesDir = "rate-policies"
+	// ExportReputationProfilesDir contains a file per reputation profile named after its ID and name
+	ExportReputationProfilesDir = "reputation-profiles"
+	// ExportMalwarePoliciesDir contains a file per malware policy named after its ID and name
+	ExportMalwarePoliciesDir = "malware-policies"
+	// ExportMatchTargetsDir contains a file per match target named after its type and sequence
+	ExportMatchTargetsDir = "match-targets"
+	// ExportAdvancedSettingsDir contains a file per advanced option, advanced setting and SIEM settings
+	ExportAdvancedSettingsDir = "advanced-settings"
+
+	maxSlugLength = 48
+)
+
+var (
+	// ErrExportConfigurationFiles is returned when a configuration version can't be split into files or written
+	ErrExportConfigurationFiles = errors.New("export configuration files")
+
+	// exportVersionFields change with every configuration version, and are left out of the export
+	exportVersionFields = []string{"version", "basedOn", "staging", "production", "createDate", "createdBy"}
+	// exportConfigFields are written to ExportConfigFile
+	exportConfigFields = []string{"configId", "configName", "targetProduct", "selectedHosts", "selectableHosts"}
+
+	// exportDirs contain files of objects, named after their IDs
+	exportDirs = []string{
+		ExportSecurityPoliciesDir,
+		ExportCustomRulesDir,
+		ExportRatePoliciesDir,
+		ExportReputationProfilesDir,
+		ExportMalwarePoliciesDir,
+		ExportMatchTargetsDir,
+		ExportAdvancedSettingsDir,
+	}
+
+	slugRegexp     = regexp.MustCompile(`[^a-z0-9]+`)
+	fileNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+)
+
+// SplitExportConfiguration splits the configuration version into files of a stable directory layout, so that
+// configurations can be kept in version control and changes reviewed file by file:
+//
+//	config.json
+//	security-policies/<policy ID>.json
+//	custom-rules/<ID>-<name>.json
+//	rate-policies/<ID>-<name>.json
+//	reputation-profiles/<ID>-<name>.json
+//	malware-policies/<ID>-<name>.json
+//	match-targets/<website|api>-<sequence>.json
+//	advanced-settings/<setting>.json
+//	<section>.json for remaining sections, e.g. rulesets.json or custom-deny-list.json
+//
+// Files are indented JSON with sorted keys. Fields which change with every version, such as the version number,
+// activation status and author, are left out. Match targets are renumbered with every version, so their IDs are
+// left out and files are numbered in the order of their sequence. Hostnames, file paths and extensions of match targets,
+// and actions of security policies are sorted. Files are returned sorted by path.
+func SplitExportConfiguration(config *GetExportConfigurationResponse) ([]ExportFile, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+	}
+	var sections map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&sections); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+	}
+	for _, field := range exportVersionFields {
+		delete(sections, field)
+	}
+
+	e := exporter{files: make(map[string]interface{})}
+	header := make(map[string]interface{})
+	for _, field := range exportConfigFields {
+		if value, ok := sections[field]; ok {
+			header[field] = value
+			sortStrings(value)
+			delete(sections, field)
+		}
+	}
+	e.add(ExportConfigFile, header)
+
+	for _, p := range asSlice(sections["securityPolicies"]) {
+		policy := asMap(p)
+		sortPolicyActions(policy)
+		e.add(path.Join(ExportSecurityPoliciesDir, fileNameRegexp.ReplaceAllString(fmt.Sprint(policy["id"]), "-")+".json"), policy)
+	}
+	for dir, section := range map[string]string{
+		ExportCustomRulesDir:        "customRules",
+		ExportRatePoliciesDir:       "ratePolicies",
+		ExportReputationProfilesDir: "reputationProfiles",
+		ExportMalwarePoliciesDir:    "malwarePolicies",
+	} {
+		for _, o := range asSlice(sections[section]) {
+			object := asMap(o)
+			e.add(path.Join(dir, namedFile(object)), object)
+		}
+		delete(sections, section)
+	}
+	delete(sections, "securityPolicies")
+
+	targets := asMap(sections["matchTargets"])
+	for _, target := range []struct{ key, prefix string }{{"websiteTargets", "website"}, {"apiTargets", "api"}} {
+		sortByKey(targets[target.key], "sequence")
+		for i, t := range asSlice(targets[target.key]) {
+			matchTarget := asMap(t)
+			delete(matchTarget, "id")
+			delete(matchTarget, "targetId")
+			delete(matchTarget, "sequence")
+			sortStrings(matchTarget["hostnames"])
+			sortStrings(matchTarget["fileExtensions"])
+			sortStrings(matchTarget["filePaths"])
+			e.add(path.Join(ExportMatchTargetsDir, fmt.Sprintf("%s-%03d.json", target.prefix, i+1)), matchTarget)
+		}
+	}
+	delete(sections, "matchTargets")
+
+	for _, section := range []string{"advancedOptions", "advancedSettings"} {
+		for name, setting := range asMap(sections[section]) {
+			if setting != nil {
+				e.add(path.Join(ExportAdvancedSettingsDir, kebabCase(name)+".json"), setting)
+			}
+		}
+		delete(sections, section)
+	}
+	if siem, ok := sections["siem"]; ok {
+		e.add(path.Join(ExportAdvancedSettingsDir, "siem.json"), siem)
+		delete(sections, "siem")
+	}
+
+	for name, section := range sections {
+		if !isEmpty(section) {
+			e.add(kebabCase(name)+".json", section)
+		}
+	}
+	return e.sorted()
+}
+
+// WriteExportFiles writes the files to the directory, creating it if needed. Files which SplitExportConfiguration
+// could have written before, but which are not part of the export, are removed, so that objects deleted from
+// the configuration disappear as well. Those are JSON files in the export directories, such as ExportCustomRulesDir,
+// and ExportConfigFile or section files in the directory itself. Other files are left untouched.
+func WriteExportFiles(dir string, files []ExportFile) error {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f.Path] = true
+	}
+	for _, exportDir := range exportDirs {
+		entries, err := os.ReadDir(filepath.Join(dir, exportDir))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+		}
+		for _, entry := range entries {
+			if name := path.Join(exportDir, entry.Name()); !entry.IsDir() && path.Ext(name) == ".json" && !keep[name] {
+				if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					return fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+				}
+			}
+		}
+	}
+	for _, name := range exportSectionFiles() {
+		if keep[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+		}
+	}
+
+	for _, f := range files {
+		name := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+		}
+		if err := os.WriteFile(name, f.Content, 0644); err != nil {
+			return fmt.Errorf("%w: %w", ErrExportConfigurationFiles, err)
+		}
+	}
+	return nil
+}
+
+// ExportConfigurationToDir fetches the configuration version with GetExportConfiguration, splits it with
+// SplitExportConfiguration and writes the files to the directory with WriteExportFiles
+func ExportConfigurationToDir(ctx context.Context, client APPSEC, params GetExportConfigurationRequest, dir string) ([]ExportFile, error) {
+	config, err := client.GetExportConfiguration(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	files, err := SplitExportConfiguration(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteExportFiles(dir, files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// exportSectionFiles returns names of files which SplitExportConfiguration can write to the export directory itself
+func exportSectionFiles() []string {
+	names := []string{ExportConfigFile}
+	config := reflect.TypeOf(GetExportConfigurationResponse{})
+	for i := 0; i < config.NumField(); i++ {
+		name, _, _ := strings.Cut(config.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, kebabCase(name)+".json")
+		}
+	}
+	return names
+}
+
+// exporter collects files of an export and detects path collisions
+type exporter struct {
+	files map[string]interface{}
+	err   error
+}
+
+func (e *exporter) add(name string, content interface{}) {
+	if _, ok := e.files[name]; ok && e.err == nil {
+		e.err = fmt.Errorf("%w: duplicate file %s", ErrExportConfigurationFiles, name)
+	}
+	e.files[name] = content
+}
+
+func (e *exporter) sorted() ([]ExportFile, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	files := make([]ExportFile, 0, len(e.files))
+	for name, content := range e.files {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrExportConfigurationFiles, name, err)
+		}
+		files = append(files, ExportFile{Path: name, Content: append(data, '\n')})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// sortPolicyActions sorts actions of a security policy, whose order has no meaning, by ID
+func sortPolicyActions(policy map[string]interface{}) {
+	sortByKey(policy["customRuleActions"], "id")
+	sortByKey(policy["ratePolicyActions"], "id")
+	sortByKey(policy["malwarePolicyActions"], "id")
+	sortByKey(asMap(policy["clientReputation"])["reputationProfileActions"], "id")
+	waf := asMap(policy["webApplicationFirewall"])
+	sortByKey(waf["ruleActions"], "id")
+	sortByKey(waf["attackGroupActions"], "group")
+	evaluation := asMap(waf["evaluation"])
+	sortByKey(evaluation["ruleActions"], "id")
+	sortByKey(evaluation["attackGroupActions"], "group")
+}
+
+// sortByKey sorts a slice of objects by the value of the key, numerically if both values are numbers
+func sortByKey(value interface{}, key string) {
+	objects := asSlice(value)
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := asMap(objects[i])[key], asMap(objects[j])[key]
+		x, errX := numberValue(a)
+		y, errY := numberValue(b)
+		if errX == nil && errY == nil {
+			return x < y
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+}
+
+func numberValue(value interface{}) (float64, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("not a number: %v", value)
+	}
+	return n.Float64()
+}
+
+// sortStrings sorts a slice of strings in place, ignoring other values
+func sortStrings(value interface{}) {
+	values := asSlice(value)
+	sort.SliceStable(values, func(i, j int) bool { return fmt.Sprint(values[i]) < fmt.Sprint(values[j]) })
+}
+
+// namedFile returns the file name of an object with an ID and a name
+func namedFile(object map[string]interface{}) string {
+	name := fmt.Sprint(object["id"])
+	if s := slug(fmt.Sprint(object["name"])); s != "" && object["name"] != nil {
+		name += "-" + s
+	}
+	return name + ".json"
+}
+
+// slug returns the lowercase text with runs of characters other than letters and digits replaced with a hyphen
+func slug(text string) string {
+	s := strings.Trim(slugRegexp.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if len(s) > maxSlugLength {
+		s = strings.TrimRight(s[:maxSlugLength], "-")
+	}
+	return s
+}
+
+// kebabCase converts a camelCase JSON field name, e.g. customDenyList to custom-deny-list
+func kebabCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func asMap(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func asSlice(value interface{}) []interface{} {
+	s, _ := value.([]interface{})
+	return s
+}
@@ -0,0 +1,173 @@
+package appsec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadExportConfiguration(t *testing.T) *GetExportConfigurationResponse {
+	var config GetExportConfigurationResponse
+	require.NoError(t, json.Unmarshal(loadFixtureBytes("testdata/TestExportConfiguration/ExportConfiguration.json"), &config))
+	return &config
+}
+
+func exportFile(files []ExportFile, path string) map[string]interface{} {
+	for _, f := range files {
+		if f.Path == path {
+			var content map[string]interface{}
+			if err := json.Unmarshal(f.Content, &content); err != nil {
+				return nil
+			}
+			return content
+		}
+	}
+	return nil
+}
+
+func TestSplitExportConfiguration(t *testing.T) {
+	config := loadExportConfiguration(t)
+	files, err := SplitExportConfiguration(config)
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	assert.IsIncreasing(t, paths)
+	assert.Subset(t, paths, []string{
+		"config.json",
+		"security-policies/AAAA_81230.json",
+		"custom-rules/60036362-existing-test-rule-1.json",
+		"rate-policies/0-ui-created.json",
+		"reputation-profiles/1685092-web-attackers-high-threat.json",
+		"match-targets/website-001.json",
+		"advanced-settings/logging.json",
+		"advanced-settings/bot-analytics-cookie-settings.json",
+		"response-actions.json",
+	})
+	assert.Len(t, paths, 52)
+
+	assert.Equal(t, map[string]interface{}{
+		"configId":        float64(43253),
+		"configName":      "Akamai Tools",
+		"targetProduct":   "KSD",
+		"selectedHosts":   []interface{}{"rinaldi.sandbox.akamaideveloper.com", "sujala.sandbox.akamaideveloper.com"},
+		"selectableHosts": exportFile(files, "config.json")["selectableHosts"],
+	}, exportFile(files, "config.json"))
+	selectableHosts := asSlice(exportFile(files, "config.json")["selectableHosts"])
+	for i := 1; i < len(selectableHosts); i++ {
+		assert.LessOrEqual(t, selectableHosts[i-1].(string), selectableHosts[i].(string))
+	}
+
+	config.MatchTargets.APITargets = make([]struct {
+		Sequence int    `json:"sequence"`
+		ID       int    `json:"id,omitempty"`
+		TargetID int    `json:"targetId"`
+		Type     string `json:"type,omitempty"`
+		Apis     []struct {
+			ID   int    `json:"id,omitempty"`
+			Name string `json:"name,omitempty"`
+		} `json:"apis,omitempty"`
+		SecurityPolicy struct {
+			PolicyID string `json:"policyId,omitempty"`
+		} `json:"securityPolicy,omitempty"`
+		BypassNetworkLists []struct {
+			Name string `json:"name,omitempty"`
+			ID   string `json:"id,omitempty"`
+		} `json:"bypassNetworkLists,omitempty"`
+	}, 2)
+	config.MatchTargets.APITargets[0].Sequence, config.MatchTargets.APITargets[0].SecurityPolicy.PolicyID = 2, "second"
+	config.MatchTargets.APITargets[1].Sequence, config.MatchTargets.APITargets[1].SecurityPolicy.PolicyID = 1, "first"
+	withAPITargets, err := SplitExportConfiguration(config)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"policyId": "first"}, exportFile(withAPITargets, "match-targets/api-001.json")["securityPolicy"])
+	assert.Equal(t, map[string]interface{}{"policyId": "second"}, exportFile(withAPITargets, "match-targets/api-002.json")["securityPolicy"])
+	config.MatchTargets.APITargets = nil
+
+	target := exportFile(files, "match-targets/website-001.json")
+	assert.NotContains(t, target, "id")
+	assert.Equal(t, []interface{}{"carb", "cct", "hdml", "jpeg", "js", "pct", "pdf", "pws", "swf", "wmls"}, target["fileExtensions"])
+
+	// the order of objects and actions in the export doesn't change files
+	reordered := loadExportConfiguration(t)
+	rules := reordered.CustomRules
+	for i, j := 0, len(rules)-1; i < j; i, j = i+1, j-1 {
+		rules[i], rules[j] = rules[j], rules[i]
+	}
+	reordered.Version++
+	reordered.MatchTargets.WebsiteTargets[0].ID++
+	waf := &reordered.SecurityPolicies[0].WebApplicationFirewall
+	waf.RuleActions = append(waf.RuleActions, waf.RuleActions[0])
+	waf.RuleActions[0].ID = 1
+	reorderedFiles, err := SplitExportConfiguration(reordered)
+	require.NoError(t, err)
+	require.Len(t, reorderedFiles, len(files))
+	for i := range files {
+		if files[i].Path != "security-policies/AAAA_81230.json" {
+			assert.Equal(t, string(files[i].Content), string(reorderedFiles[i].Content), files[i].Path)
+		}
+	}
+	ruleActions := exportFile(reorderedFiles, "security-policies/AAAA_81230.json")["webApplicationFirewall"].(map[string]interface{})["ruleActions"].([]interface{})
+	assert.Equal(t, float64(1), ruleActions[0].(map[string]interface{})["id"])
+
+	config.SecurityPolicies = append(config.SecurityPolicies, config.SecurityPolicies[0])
+	_, err = SplitExportConfiguration(config)
+	assert.True(t, errors.Is(err, ErrExportConfigurationFiles), "want: %s; got: %s", ErrExportConfigurationFiles, err)
+	assert.Contains(t, err.Error(), "duplicate file security-policies/AAAA_81230.json")
+}
+
+func TestExportConfigurationToDir(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/appsec/v1/export/configs/43253/versions/15", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write(loadFixtureBytes("testdata/TestExportConfiguration/ExportConfiguration.json"))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "custom-rules", "1-deleted-rule.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0755))
+	require.NoError(t, os.WriteFile(stale, []byte("{}"), 0644))
+	staleSection := filepath.Join(dir, "custom-deny-list.json")
+	require.NoError(t, os.WriteFile(staleSection, []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644))
+	unrelated := []string{
+		filepath.Join(dir, "package.json"),
+		filepath.Join(dir, ".vscode", "settings.json"),
+		filepath.Join(dir, "custom-rules", "notes", "rule.json"),
+	}
+	for _, name := range unrelated {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte("{}"), 0644))
+	}
+
+	files, err := ExportConfigurationToDir(context.Background(), client, GetExportConfigurationRequest{ConfigID: 43253, Version: 15}, dir)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, stale)
+	assert.NoFileExists(t, staleSection)
+	assert.FileExists(t, filepath.Join(dir, "README.md"))
+	for _, name := range unrelated {
+		assert.FileExists(t, name)
+	}
+	for _, f := range files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		require.NoError(t, err)
+		assert.Equal(t, f.Content, content)
+	}
+
+	// writing to a directory which doesn't exist creates it
+	require.NoError(t, WriteExportFiles(filepath.Join(dir, "new"), files[:1]))
+	assert.FileExists(t, filepath.Join(dir, "new", files[0].Path))
+}
@@ -0,0 +1,247 @@
+package cps
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// CertificateInventoryRequest contains parameters of CertificateInventory
+	CertificateInventoryRequest struct {
+		ContractID string
+		// ExpiringWithin limits the inventory to certificates expiring within the duration. No limit if zero
+		ExpiringWithin time.Duration
+		// Hostname limits the inventory to certificates valid for the hostname, including wildcard SANs
+		Hostname string
+		// Network limits the inventory to certificates deployed to the network, either 'production' or 'staging'
+		Network string
+		// Now is the time used for expiry checks. Defaults to the current time
+		Now time.Time
+	}
+
+	// CertificateInventoryEntry describes a certificate deployed for an enrollment. If deployments of the enrollment
+	// or the certificate can't be read, Error describes the reason and certificate details are empty.
+	CertificateInventoryEntry struct {
+		EnrollmentID         int       `json:"enrollmentId"`
+		CN                   string    `json:"cn"`
+		Network              string    `json:"network"`
+		ValidationType       string    `json:"validationType"`
+		CertificateType      string    `json:"certificateType"`
+		SANs                 []string  `json:"sans"`
+		Issuer               string    `json:"issuer"`
+		KeyAlgorithm         string    `json:"keyAlgorithm"`
+		SerialNumber         string    `json:"serialNumber"`
+		NotBefore            time.Time `json:"notBefore"`
+		NotAfter             time.Time `json:"notAfter"`
+		AutoRenewal          bool      `json:"autoRenewal"`
+		AutoRenewalStartTime string    `json:"autoRenewalStartTime,omitempty"`
+		PendingChanges       []string  `json:"pendingChanges,omitempty"`
+		Error                string    `json:"error,omitempty"`
+	}
+)
+
+const (
+	// NetworkProduction is the production network of deployments
+	NetworkProduction = "production"
+	// NetworkStaging is the staging network of deployments
+	NetworkStaging = "staging"
+
+	// validationTypeThirdParty is the validation type of enrollments with certificates which are not renewed by CPS
+	validationTypeThirdParty = "third-party"
+)
+
+var (
+	// ErrCertificateInventory is returned when CertificateInventory fails
+	ErrCertificateInventory = errors.New("certificate inventory")
+)
+
+// certificateInventoryCSVHeader is the header of CSV written by WriteCertificateInventoryCSV
+var certificateInventoryCSVHeader = []string{
+	"enrollmentId", "cn", "network", "validationType", "certificateType", "sans", "issuer", "keyAlgorithm",
+	"serialNumber", "notBefore", "notAfter", "autoRenewal", "autoRenewalStartTime", "pendingChanges", "error",
+}
+
+// CertificateInventory lists enrollments of the contract, fetches their production and staging deployments and returns
+// an entry for each deployed certificate, including multi-stacked ones, sorted by expiry.
+// CPS renews certificates of all enrollments except third-party ones, which is reported as AutoRenewal.
+//
+// An enrollment whose deployments can't be fetched, or a certificate which can't be parsed, doesn't stop the inventory.
+// It's reported with an entry with Error set instead, which is not filtered by expiry or hostname and sorts first.
+func CertificateInventory(ctx context.Context, client CPS, params CertificateInventoryRequest) ([]CertificateInventoryEntry, error) {
+	if params.Network != "" && params.Network != NetworkProduction && params.Network != NetworkStaging {
+		return nil, fmt.Errorf("%w: %w: Network: must be %q or %q", ErrCertificateInventory, ErrStructValidation, NetworkProduction, NetworkStaging)
+	}
+	if params.Now.IsZero() {
+		params.Now = time.Now()
+	}
+
+	enrollments, err := client.ListEnrollments(ctx, ListEnrollmentsRequest{ContractID: params.ContractID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCertificateInventory, err)
+	}
+
+	var entries []CertificateInventoryEntry
+	for _, enrollment := range enrollments.Enrollments {
+		id := enrollment.ID
+		if id == 0 {
+			if id, err = GetIDFromLocation(enrollment.Location); err != nil {
+				entries = append(entries, failedInventoryEntry(0, enrollment, "", fmt.Errorf("enrollment %q: %w", enrollment.Location, err)))
+				continue
+			}
+		}
+		deployments, err := client.ListDeployments(ctx, ListDeploymentsRequest{EnrollmentID: id})
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			entries = append(entries, failedInventoryEntry(id, enrollment, "", err))
+			continue
+		}
+
+		for _, d := range []struct {
+			network    string
+			deployment *Deployment
+		}{{NetworkProduction, deployments.Production}, {NetworkStaging, deployments.Staging}} {
+			if d.deployment == nil || params.Network != "" && params.Network != d.network {
+				continue
+			}
+			certificates := append([]DeploymentCertificate{d.deployment.PrimaryCertificate}, d.deployment.MultiStackedCertificates...)
+			for _, c := range certificates {
+				if c.Certificate == "" {
+					continue
+				}
+				entry, err := newInventoryEntry(id, enrollment, d.network, c)
+				if err != nil {
+					entries = append(entries, failedInventoryEntry(id, enrollment, d.network, fmt.Errorf("%s certificate: %w", d.network, err)))
+					continue
+				}
+				if params.matches(entry) {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].NotAfter.Before(entries[j].NotAfter) })
+	return entries, nil
+}
+
+func failedInventoryEntry(id int, enrollment Enrollment, network string, err error) CertificateInventoryEntry {
+	return CertificateInventoryEntry{
+		EnrollmentID:    id,
+		Network:         network,
+		ValidationType:  enrollment.ValidationType,
+		CertificateType: enrollment.CertificateType,
+		AutoRenewal:     enrollment.ValidationType != validationTypeThirdParty,
+		Error:           err.Error(),
+	}
+}
+
+func newInventoryEntry(id int, enrollment Enrollment, network string, c DeploymentCertificate) (CertificateInventoryEntry, error) {
+	certs, err := parseCertificates(c.Certificate)
+	if err != nil {
+		return CertificateInventoryEntry{}, err
+	}
+	leaf := certs[0]
+
+	entry := CertificateInventoryEntry{
+		EnrollmentID:         id,
+		CN:                   leaf.Subject.CommonName,
+		Network:              network,
+		ValidationType:       enrollment.ValidationType,
+		CertificateType:      enrollment.CertificateType,
+		SANs:                 leaf.DNSNames,
+		Issuer:               leaf.Issuer.CommonName,
+		KeyAlgorithm:         c.KeyAlgorithm,
+		SerialNumber:         leaf.SerialNumber.Text(16),
+		NotBefore:            leaf.NotBefore.UTC(),
+		NotAfter:             leaf.NotAfter.UTC(),
+		AutoRenewal:          enrollment.ValidationType != validationTypeThirdParty,
+		AutoRenewalStartTime: enrollment.AutoRenewalStartTime,
+	}
+	if entry.KeyAlgorithm == "" {
+		entry.KeyAlgorithm = keyAlgorithmName(leaf.PublicKeyAlgorithm)
+	}
+	for _, p := range enrollment.PendingChanges {
+		entry.PendingChanges = append(entry.PendingChanges, p.ChangeType)
+	}
+	return entry, nil
+}
+
+func keyAlgorithmName(algorithm x509.PublicKeyAlgorithm) string {
+	for name, a := range keyAlgorithms {
+		if a == algorithm {
+			return name
+		}
+	}
+	return algorithm.String()
+}
+
+func (r CertificateInventoryRequest) matches(entry CertificateInventoryEntry) bool {
+	if r.ExpiringWithin > 0 && entry.NotAfter.After(r.Now.Add(r.ExpiringWithin)) {
+		return false
+	}
+	if r.Hostname == "" {
+		return true
+	}
+	for _, san := range append([]string{entry.CN}, entry.SANs...) {
+		if matchesHostname(san, r.Hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHostname tells whether the certificate name, which can have a wildcard in the leftmost label, covers the hostname
+func matchesHostname(name, hostname string) bool {
+	name, hostname = strings.ToLower(strings.TrimSuffix(name, ".")), strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if name == hostname {
+		return true
+	}
+	if !strings.HasPrefix(name, "*.") {
+		return false
+	}
+	_, parent, found := strings.Cut(hostname, ".")
+	return found && parent == name[2:]
+}
+
+// WriteCertificateInventoryCSV writes inventory entries as CSV with a header. SANs and pending changes are separated with spaces.
+func WriteCertificateInventoryCSV(w io.Writer, entries []CertificateInventoryEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(certificateInventoryCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			strconv.Itoa(e.EnrollmentID), e.CN, e.Network, e.ValidationType, e.CertificateType, strings.Join(e.SANs, " "),
+			e.Issuer, e.KeyAlgorithm, e.SerialNumber, e.NotBefore.Format(time.RFC3339), e.NotAfter.Format(time.RFC3339),
+			strconv.FormatBool(e.AutoRenewal), e.AutoRenewalStartTime, strings.Join(e.PendingChanges, " "), e.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCertificateInventoryJSON writes inventory entries as an indented JSON array
+func WriteCertificateInventoryJSON(w io.Writer, entries []CertificateInventoryEntry) error {
+	if entries == nil {
+		entries = []CertificateInventoryEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
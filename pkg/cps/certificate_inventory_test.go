@@ -0,0 +1,211 @@
+package cps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateInventory(t *testing.T) {
+	root := newTestCert(t, "Root CA", nil, nil, testNow.Add(1000*24*time.Hour))
+	soon := newTestCert(t, "www.example.com", nil, root, testNow.Add(10*24*time.Hour), "www.example.com", "*.api.example.com")
+	later := newTestCert(t, "www.example.com", nil, root, testNow.Add(80*24*time.Hour), "www.example.com", "*.api.example.com")
+	thirdParty := newTestCert(t, "shop.example.org", nil, root, testNow.Add(40*24*time.Hour), "shop.example.org")
+
+	deployment := func(certificates ...*testCert) *Deployment {
+		d := &Deployment{PrimaryCertificate: DeploymentCertificate{Certificate: certificates[0].pem, KeyAlgorithm: "ECDSA"}}
+		for _, c := range certificates[1:] {
+			d.MultiStackedCertificates = append(d.MultiStackedCertificates, DeploymentCertificate{Certificate: c.pem})
+		}
+		return d
+	}
+	responses := map[string]interface{}{
+		"/cps/v2/enrollments": ListEnrollmentsResponse{Enrollments: []Enrollment{
+			{Location: "/cps/v2/enrollments/1", ValidationType: "dv", CertificateType: "san",
+				PendingChanges: []PendingChange{{ChangeType: "renewal"}}},
+			{ID: 2, ValidationType: "third-party", CertificateType: "third-party"},
+			{ID: 3, ValidationType: "ov"},
+		}},
+		"/cps/v2/enrollments/1/deployments": ListDeploymentsResponse{Production: deployment(later), Staging: deployment(soon)},
+		"/cps/v2/enrollments/2/deployments": ListDeploymentsResponse{Production: deployment(thirdParty, root)},
+	}
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type": "not-found", "title": "Not Found"}`))
+			return
+		}
+		if r.URL.Path == "/cps/v2/enrollments" {
+			assert.Equal(t, "ctr_1", r.URL.Query().Get("contractId"))
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	type summary struct {
+		EnrollmentID int
+		Network      string
+		CN           string
+		Days         int
+	}
+	summarize := func(entries []CertificateInventoryEntry) []summary {
+		var result []summary
+		for _, e := range entries {
+			result = append(result, summary{e.EnrollmentID, e.Network, e.CN, int(e.NotAfter.Sub(testNow).Hours() / 24)})
+		}
+		return result
+	}
+
+	tests := map[string]struct {
+		params   CertificateInventoryRequest
+		expected []summary
+	}{
+		"all certificates sorted by expiry": {
+			params: CertificateInventoryRequest{},
+			expected: []summary{
+				{1, NetworkStaging, "www.example.com", 10},
+				{2, NetworkProduction, "shop.example.org", 40},
+				{1, NetworkProduction, "www.example.com", 80},
+				{2, NetworkProduction, "Root CA", 1000},
+			},
+		},
+		"expiring within 50 days in production": {
+			params:   CertificateInventoryRequest{ExpiringWithin: 50 * 24 * time.Hour, Network: NetworkProduction},
+			expected: []summary{{2, NetworkProduction, "shop.example.org", 40}},
+		},
+		"hostname matching wildcard SAN": {
+			params: CertificateInventoryRequest{Hostname: "v1.API.example.com"},
+			expected: []summary{
+				{1, NetworkStaging, "www.example.com", 10},
+				{1, NetworkProduction, "www.example.com", 80},
+			},
+		},
+		"hostname not matching wildcard SAN at deeper level": {
+			params: CertificateInventoryRequest{Hostname: "a.v1.api.example.com"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := test.params
+			params.ContractID = "ctr_1"
+			params.Now = testNow
+			entries, err := CertificateInventory(context.Background(), client, params)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, summarize(entries))
+		})
+	}
+
+	entries, err := CertificateInventory(context.Background(), client, CertificateInventoryRequest{ContractID: "ctr_1", Now: testNow, ExpiringWithin: 15 * 24 * time.Hour})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, CertificateInventoryEntry{
+		EnrollmentID:    1,
+		CN:              "www.example.com",
+		Network:         NetworkStaging,
+		ValidationType:  "dv",
+		CertificateType: "san",
+		SANs:            []string{"www.example.com", "*.api.example.com"},
+		Issuer:          "Root CA",
+		KeyAlgorithm:    "ECDSA",
+		SerialNumber:    soon.cert.SerialNumber.Text(16),
+		NotBefore:       soon.cert.NotBefore,
+		NotAfter:        soon.cert.NotAfter,
+		AutoRenewal:     true,
+		PendingChanges:  []string{"renewal"},
+	}, entries[0])
+
+	_, err = CertificateInventory(context.Background(), client, CertificateInventoryRequest{Network: "test"})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+}
+
+func TestCertificateInventory_PartialFailures(t *testing.T) {
+	root := newTestCert(t, "Root CA", nil, nil, testNow.Add(1000*24*time.Hour))
+	cert := newTestCert(t, "www.example.com", nil, root, testNow.Add(10*24*time.Hour), "www.example.com")
+
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/cps/v2/enrollments":
+			response = ListEnrollmentsResponse{Enrollments: []Enrollment{
+				{ID: 1, ValidationType: "dv"},
+				{ID: 2, ValidationType: "ov"},
+				{ID: 3, ValidationType: "third-party"},
+				{Location: "invalid"},
+			}}
+		case "/cps/v2/enrollments/1/deployments":
+			response = ListDeploymentsResponse{Production: &Deployment{PrimaryCertificate: DeploymentCertificate{Certificate: cert.pem}}}
+		case "/cps/v2/enrollments/2/deployments":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"type": "internal-error", "title": "Internal Server Error"}`))
+			return
+		case "/cps/v2/enrollments/3/deployments":
+			response = ListDeploymentsResponse{Staging: &Deployment{PrimaryCertificate: DeploymentCertificate{Certificate: "not a certificate"}}}
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	entries, err := CertificateInventory(context.Background(), client, CertificateInventoryRequest{ContractID: "ctr_1", Now: testNow, Hostname: "www.example.com"})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for _, e := range entries[:3] {
+		assert.NotEmpty(t, e.Error)
+		assert.True(t, e.NotAfter.IsZero())
+	}
+	assert.Equal(t, 2, entries[0].EnrollmentID)
+	assert.Contains(t, entries[0].Error, "Internal Server Error")
+	assert.Equal(t, CertificateInventoryEntry{EnrollmentID: 3, Network: NetworkStaging, ValidationType: "third-party", Error: entries[1].Error}, entries[1])
+	assert.Contains(t, entries[1].Error, "staging certificate")
+	assert.Equal(t, 0, entries[2].EnrollmentID)
+	assert.Contains(t, entries[2].Error, `enrollment "invalid"`)
+	assert.Equal(t, "www.example.com", entries[3].CN)
+	assert.Empty(t, entries[3].Error)
+}
+
+func TestWriteCertificateInventory(t *testing.T) {
+	entries := []CertificateInventoryEntry{{
+		EnrollmentID:   1,
+		CN:             "www.example.com",
+		Network:        NetworkProduction,
+		ValidationType: "dv",
+		SANs:           []string{"www.example.com", "example.com"},
+		Issuer:         "R3",
+		KeyAlgorithm:   "RSA",
+		SerialNumber:   "ab",
+		NotBefore:      testNow,
+		NotAfter:       testNow.Add(90 * 24 * time.Hour),
+		AutoRenewal:    true,
+		PendingChanges: []string{"renewal", "new-certificate"},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCertificateInventoryCSV(&buf, entries))
+	assert.Equal(t, strings.Join([]string{
+		"enrollmentId,cn,network,validationType,certificateType,sans,issuer,keyAlgorithm,serialNumber,notBefore,notAfter,autoRenewal,autoRenewalStartTime,pendingChanges,error",
+		"1,www.example.com,production,dv,,www.example.com example.com,R3,RSA,ab,2024-06-01T00:00:00Z,2024-08-30T00:00:00Z,true,,renewal new-certificate,",
+		"",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteCertificateInventoryJSON(&buf, entries))
+	var decoded []CertificateInventoryEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, entries, decoded)
+
+	buf.Reset()
+	require.NoError(t, WriteCertificateInventoryJSON(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
@@ -0,0 +1,273 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// RunChangeRequest contains parameters of RunChange
+	RunChangeRequest struct {
+		EnrollmentID int
+		ChangeID     int
+		Handlers     ChangeHandlers
+		// PollInterval is the interval between change status checks. Defaults to DefaultChangePollInterval
+		PollInterval time.Duration
+		// Progress receives events about change status and handled inputs, if set
+		Progress chan<- ChangeEvent
+	}
+
+	// ChangeHandlers contains handlers of inputs required by a change to proceed.
+	// RunChange fails with ErrNoChangeHandler when the change requires an input without a handler.
+	ChangeHandlers struct {
+		// LetsEncryptChallenges fulfils DV challenges, e.g. by publishing HTTP tokens or DNS records.
+		// Challenges are acknowledged when it returns without an error.
+		LetsEncryptChallenges func(context.Context, *DVArray) error
+		// PreVerificationWarnings returns true to acknowledge the warnings, or false to deny them
+		PreVerificationWarnings func(context.Context, *PreVerificationWarnings) (bool, error)
+		// PostVerificationWarnings returns true to acknowledge the warnings, or false to deny them
+		PostVerificationWarnings func(context.Context, *PostVerificationWarnings) (bool, error)
+		// ChangeManagement returns true to acknowledge deployment of the certificate to production, or false to deny it
+		ChangeManagement func(context.Context, *ChangeManagementInfoResponse) (bool, error)
+		// ThirdPartyCertificate returns certificates signed with the CSRs, which are then uploaded
+		ThirdPartyCertificate func(context.Context, *ThirdPartyCSRResponse) (*ThirdPartyCertificates, error)
+	}
+
+	// ChangeEvent describes progress of RunChange
+	ChangeEvent struct {
+		Type   ChangeEventType
+		Status *StatusInfo
+		// Input is the type of the handled input, for ChangeEventInputHandled events
+		Input string
+	}
+
+	// ChangeEventType is the type of ChangeEvent
+	ChangeEventType string
+)
+
+const (
+	// ChangeEventStatus is sent after each change status check
+	ChangeEventStatus ChangeEventType = "status"
+	// ChangeEventInputHandled is sent after an input required by the change is handled
+	ChangeEventInputHandled ChangeEventType = "input-handled"
+)
+
+const (
+	// ChangeInputLetsEncryptChallenges is the type of allowed input with DV challenges
+	ChangeInputLetsEncryptChallenges = "lets-encrypt-challenges"
+	// ChangeInputPreVerificationWarnings is the type of allowed input with pre-verification warnings
+	ChangeInputPreVerificationWarnings = "pre-verification-warnings"
+	// ChangeInputPostVerificationWarnings is the type of allowed input with post-verification warnings
+	ChangeInputPostVerificationWarnings = "post-verification-warnings"
+	// ChangeInputChangeManagementInfo is the type of allowed input with change management information
+	ChangeInputChangeManagementInfo = "change-management-info"
+	// ChangeInputThirdPartyCertificate is the type of allowed input with third-party CSRs
+	ChangeInputThirdPartyCertificate = "third-party-certificate"
+)
+
+const (
+	// ChangeStatusComplete is the status of a change deployed to the network
+	ChangeStatusComplete = "complete"
+	// ChangeStatusCancelled is the status of a cancelled change
+	ChangeStatusCancelled = "cancelled"
+	// ChangeStateError is the state of a failed change
+	ChangeStateError = "error"
+)
+
+// DefaultChangePollInterval is the default interval between change status checks of RunChange
+const DefaultChangePollInterval = 30 * time.Second
+
+var (
+	// ErrRunChange is returned when RunChange fails
+	ErrRunChange = errors.New("running change")
+	// ErrChangeFailed is returned when the change ends in the error state or is cancelled
+	ErrChangeFailed = errors.New("change failed")
+	// ErrNoChangeHandler is returned when the change requires an input without a handler
+	ErrNoChangeHandler = errors.New("no handler for required input")
+)
+
+// Validate validates RunChangeRequest
+func (r RunChangeRequest) Validate() error {
+	return validation.Errors{
+		"EnrollmentID": validation.Validate(r.EnrollmentID, validation.Required),
+		"ChangeID":     validation.Validate(r.ChangeID, validation.Required),
+	}.Filter()
+}
+
+// RunChange drives the change until it's deployed or fails. It polls the change status and, whenever the change
+// awaits input required to proceed, fetches the input information, invokes the matching handler and sends
+// its result to CPS. Each input is handled once per change status.
+//
+// The returned change is the last status fetched. Use context to limit the time of waiting.
+// The ID of a change created with CreateEnrollment or UpdateEnrollment can be read with GetIDFromLocation.
+func RunChange(ctx context.Context, client CPS, params RunChangeRequest) (*Change, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrRunChange, ErrStructValidation, err)
+	}
+	pollInterval := params.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultChangePollInterval
+	}
+
+	runner := changeRunner{client: client, params: params}
+	var lastStatus string
+	handled := make(map[string]bool)
+	for {
+		change, err := client.GetChangeStatus(ctx, GetChangeStatusRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRunChange, err)
+		}
+		status := change.StatusInfo
+		if status == nil {
+			status = &StatusInfo{}
+		}
+		if err := runner.sendEvent(ctx, ChangeEvent{Type: ChangeEventStatus, Status: status}); err != nil {
+			return change, fmt.Errorf("%w: %w", ErrRunChange, err)
+		}
+
+		switch {
+		case status.Status == ChangeStatusComplete:
+			return change, nil
+		case status.Error != nil:
+			return change, fmt.Errorf("%w: %w: %s: %s", ErrRunChange, ErrChangeFailed, status.Error.Code, status.Error.Description)
+		case status.State == ChangeStateError, status.Status == ChangeStatusCancelled:
+			return change, fmt.Errorf("%w: %w: %s", ErrRunChange, ErrChangeFailed, status.Description)
+		}
+
+		if status.Status != lastStatus {
+			lastStatus = status.Status
+			handled = make(map[string]bool)
+		}
+		for _, input := range change.AllowedInput {
+			if !input.RequiredToProceed || handled[input.Type] {
+				continue
+			}
+			if err := runner.handle(ctx, input.Type); err != nil {
+				return change, fmt.Errorf("%w: %w", ErrRunChange, err)
+			}
+			handled[input.Type] = true
+			if err := runner.sendEvent(ctx, ChangeEvent{Type: ChangeEventInputHandled, Status: status, Input: input.Type}); err != nil {
+				return change, fmt.Errorf("%w: %w", ErrRunChange, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return change, fmt.Errorf("%w: waiting for change, last status %s: %w", ErrRunChange, status.Status, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// changeRunner handles inputs of a single change
+type changeRunner struct {
+	client CPS
+	params RunChangeRequest
+}
+
+func (r changeRunner) handle(ctx context.Context, inputType string) error {
+	getReq := GetChangeRequest{EnrollmentID: r.params.EnrollmentID, ChangeID: r.params.ChangeID}
+	ackReq := func(ack bool) AcknowledgementRequest {
+		a := AcknowledgementDeny
+		if ack {
+			a = AcknowledgementAcknowledge
+		}
+		return AcknowledgementRequest{
+			Acknowledgement: Acknowledgement{Acknowledgement: a},
+			EnrollmentID:    r.params.EnrollmentID,
+			ChangeID:        r.params.ChangeID,
+		}
+	}
+	h := r.params.Handlers
+
+	switch inputType {
+	case ChangeInputLetsEncryptChallenges:
+		if h.LetsEncryptChallenges == nil {
+			break
+		}
+		challenges, err := r.client.GetChangeLetsEncryptChallenges(ctx, getReq)
+		if err != nil {
+			return err
+		}
+		if err := h.LetsEncryptChallenges(ctx, challenges); err != nil {
+			return fmt.Errorf("handling %s: %w", inputType, err)
+		}
+		return r.client.AcknowledgeDVChallenges(ctx, ackReq(true))
+	case ChangeInputPreVerificationWarnings:
+		if h.PreVerificationWarnings == nil {
+			break
+		}
+		warnings, err := r.client.GetChangePreVerificationWarnings(ctx, getReq)
+		if err != nil {
+			return err
+		}
+		ack, err := h.PreVerificationWarnings(ctx, warnings)
+		if err != nil {
+			return fmt.Errorf("handling %s: %w", inputType, err)
+		}
+		return r.client.AcknowledgePreVerificationWarnings(ctx, ackReq(ack))
+	case ChangeInputPostVerificationWarnings:
+		if h.PostVerificationWarnings == nil {
+			break
+		}
+		warnings, err := r.client.GetChangePostVerificationWarnings(ctx, getReq)
+		if err != nil {
+			return err
+		}
+		ack, err := h.PostVerificationWarnings(ctx, warnings)
+		if err != nil {
+			return fmt.Errorf("handling %s: %w", inputType, err)
+		}
+		return r.client.AcknowledgePostVerificationWarnings(ctx, ackReq(ack))
+	case ChangeInputChangeManagementInfo:
+		if h.ChangeManagement == nil {
+			break
+		}
+		info, err := r.client.GetChangeManagementInfo(ctx, getReq)
+		if err != nil {
+			return err
+		}
+		ack, err := h.ChangeManagement(ctx, info)
+		if err != nil {
+			return fmt.Errorf("handling %s: %w", inputType, err)
+		}
+		return r.client.AcknowledgeChangeManagement(ctx, ackReq(ack))
+	case ChangeInputThirdPartyCertificate:
+		if h.ThirdPartyCertificate == nil {
+			break
+		}
+		csr, err := r.client.GetChangeThirdPartyCSR(ctx, getReq)
+		if err != nil {
+			return err
+		}
+		certs, err := h.ThirdPartyCertificate(ctx, csr)
+		if err != nil {
+			return fmt.Errorf("handling %s: %w", inputType, err)
+		}
+		if certs == nil {
+			return fmt.Errorf("handling %s: no certificates returned", inputType)
+		}
+		return r.client.UploadThirdPartyCertAndTrustChain(ctx, UploadThirdPartyCertAndTrustChainRequest{
+			EnrollmentID: r.params.EnrollmentID,
+			ChangeID:     r.params.ChangeID,
+			Certificates: *certs,
+		})
+	}
+	return fmt.Errorf("%w: %s", ErrNoChangeHandler, inputType)
+}
+
+func (r changeRunner) sendEvent(ctx context.Context, event ChangeEvent) error {
+	if r.params.Progress == nil {
+		return nil
+	}
+	select {
+	case r.params.Progress <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
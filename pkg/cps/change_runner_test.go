@@ -0,0 +1,246 @@
+package cps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeServer serves change statuses in order, moving to the next one after an input is submitted
+// or, for statuses without inputs, after each status check
+func changeServer(t *testing.T, statuses []string, infos map[string]string) (*httptest.Server, *[]string) {
+	const changePath = "/cps/v2/enrollments/1/changes/2"
+	var mu sync.Mutex
+	var i int
+	var requests []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == changePath:
+			status := statuses[i]
+			if i < len(statuses)-1 && !json.Valid([]byte(status)) {
+				i++
+			}
+			if !json.Valid([]byte(status)) {
+				status = fmt.Sprintf(`{"statusInfo": {"status": %q, "state": "running"}, "allowedInput": []}`, status)
+			}
+			_, err := w.Write([]byte(status))
+			assert.NoError(t, err)
+		case r.Method == http.MethodGet:
+			requests = append(requests, "GET "+r.URL.Path)
+			info, ok := infos[r.URL.Path]
+			if !assert.True(t, ok, "unexpected request %s", r.URL) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, err := w.Write([]byte(info))
+			assert.NoError(t, err)
+		case r.Method == http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			requests = append(requests, fmt.Sprintf("POST %s %s", r.URL.Path, body))
+			i++
+			_, err = w.Write([]byte(`{"change": "/cps/v2/enrollments/1/changes/2"}`))
+			assert.NoError(t, err)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	return server, &requests
+}
+
+func awaitingInput(inputType string) string {
+	return fmt.Sprintf(`{"statusInfo": {"status": "wait-input", "state": "awaiting-input"},
+		"allowedInput": [{"type": %q, "requiredToProceed": true}, {"type": "change-management-info", "requiredToProceed": false}]}`, inputType)
+}
+
+func TestRunChange(t *testing.T) {
+	const inputPath = "/cps/v2/enrollments/1/changes/2/input/"
+	infos := map[string]string{
+		inputPath + "info/lets-encrypt-challenges":   `{"dv": [{"domain": "example.com", "challenges": [{"type": "dns-01", "token": "abc"}]}]}`,
+		inputPath + "info/pre-verification-warnings": `{"warnings": "pre warning"}`,
+		inputPath + "info/change-management-info":    `{"validationResultHash": "hash"}`,
+		inputPath + "info/third-party-csr":           `{"csrs": [{"csr": "CSR", "keyAlgorithm": "RSA"}]}`,
+	}
+	ackBody := func(ack string) string {
+		return fmt.Sprintf(`{"acknowledgement":%q}`, ack)
+	}
+
+	tests := map[string]struct {
+		statuses         []string
+		handlers         ChangeHandlers
+		expectedRequests []string
+		expectedStatus   string
+		withError        []error
+	}{
+		"DV change deployed": {
+			statuses: []string{
+				"verify-csr",
+				awaitingInput(ChangeInputLetsEncryptChallenges),
+				awaitingInput(ChangeInputPreVerificationWarnings),
+				awaitingInput(ChangeInputChangeManagementInfo),
+				"deploy-cert-to-production-network",
+				`{"statusInfo": {"status": "complete", "state": "completed"}}`,
+			},
+			handlers: ChangeHandlers{
+				LetsEncryptChallenges: func(_ context.Context, dv *DVArray) error {
+					assert.Equal(t, "abc", dv.DV[0].Challenges[0].Token)
+					return nil
+				},
+				PreVerificationWarnings: func(_ context.Context, w *PreVerificationWarnings) (bool, error) {
+					assert.Equal(t, "pre warning", w.Warnings)
+					return true, nil
+				},
+				ChangeManagement: func(_ context.Context, info *ChangeManagementInfoResponse) (bool, error) {
+					assert.Equal(t, "hash", info.ValidationResultHash)
+					return true, nil
+				},
+			},
+			expectedRequests: []string{
+				"GET " + inputPath + "info/lets-encrypt-challenges",
+				"POST " + inputPath + "update/lets-encrypt-challenges-completed " + ackBody("acknowledge"),
+				"GET " + inputPath + "info/pre-verification-warnings",
+				"POST " + inputPath + "update/pre-verification-warnings-ack " + ackBody("acknowledge"),
+				"GET " + inputPath + "info/change-management-info",
+				"POST " + inputPath + "update/change-management-ack " + ackBody("acknowledge"),
+			},
+			expectedStatus: ChangeStatusComplete,
+		},
+		"third-party certificate uploaded": {
+			statuses: []string{
+				awaitingInput(ChangeInputThirdPartyCertificate),
+				`{"statusInfo": {"status": "complete"}}`,
+			},
+			handlers: ChangeHandlers{
+				ThirdPartyCertificate: func(_ context.Context, csr *ThirdPartyCSRResponse) (*ThirdPartyCertificates, error) {
+					return &ThirdPartyCertificates{CertificatesAndTrustChains: []CertificateAndTrustChain{
+						{Certificate: "signed " + csr.CSRs[0].CSR, KeyAlgorithm: csr.CSRs[0].KeyAlgorithm},
+					}}, nil
+				},
+			},
+			expectedRequests: []string{
+				"GET " + inputPath + "info/third-party-csr",
+				"POST " + inputPath + `update/third-party-cert-and-trust-chain {"certificatesAndTrustChains":[{"certificate":"signed CSR","keyAlgorithm":"RSA"}]}`,
+			},
+			expectedStatus: ChangeStatusComplete,
+		},
+		"warnings denied, change cancelled": {
+			statuses: []string{
+				awaitingInput(ChangeInputPreVerificationWarnings),
+				`{"statusInfo": {"status": "cancelled", "state": "completed", "description": "Change cancelled"}}`,
+			},
+			handlers: ChangeHandlers{
+				PreVerificationWarnings: func(context.Context, *PreVerificationWarnings) (bool, error) { return false, nil },
+			},
+			expectedRequests: []string{
+				"GET " + inputPath + "info/pre-verification-warnings",
+				"POST " + inputPath + "update/pre-verification-warnings-ack " + ackBody("deny"),
+			},
+			expectedStatus: ChangeStatusCancelled,
+			withError:      []error{ErrRunChange, ErrChangeFailed},
+		},
+		"change error": {
+			statuses: []string{
+				`{"statusInfo": {"status": "verify-csr", "state": "error", "error": {"code": "E1", "description": "bad CSR"}}}`,
+			},
+			expectedStatus: "verify-csr",
+			withError:      []error{ErrRunChange, ErrChangeFailed},
+		},
+		"missing handler": {
+			statuses:       []string{awaitingInput(ChangeInputPostVerificationWarnings)},
+			expectedStatus: "wait-input",
+			withError:      []error{ErrRunChange, ErrNoChangeHandler},
+		},
+		"handler error": {
+			statuses: []string{awaitingInput(ChangeInputLetsEncryptChallenges)},
+			handlers: ChangeHandlers{
+				LetsEncryptChallenges: func(context.Context, *DVArray) error { return context.Canceled },
+			},
+			expectedRequests: []string{"GET " + inputPath + "info/lets-encrypt-challenges"},
+			expectedStatus:   "wait-input",
+			withError:        []error{ErrRunChange, context.Canceled},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer, requests := changeServer(t, test.statuses, infos)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			change, err := RunChange(context.Background(), client, RunChangeRequest{
+				EnrollmentID: 1,
+				ChangeID:     2,
+				Handlers:     test.handlers,
+				PollInterval: time.Millisecond,
+			})
+			for _, e := range test.withError {
+				assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+			}
+			if len(test.withError) == 0 {
+				require.NoError(t, err)
+			}
+			require.NotNil(t, change)
+			assert.Equal(t, test.expectedStatus, change.StatusInfo.Status)
+			assert.Equal(t, test.expectedRequests, *requests)
+		})
+	}
+}
+
+func TestRunChange_InputHandledOncePerStatus(t *testing.T) {
+	// CPS keeps the status until the challenges are validated
+	var statusChecks int
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `{"dv": []}`
+		switch {
+		case r.URL.Path == "/cps/v2/enrollments/1/changes/2" && statusChecks < 2:
+			statusChecks++
+			body = awaitingInput(ChangeInputLetsEncryptChallenges)
+		case r.URL.Path == "/cps/v2/enrollments/1/changes/2":
+			body = `{"statusInfo": {"status": "complete"}}`
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	var calls int
+	progress := make(chan ChangeEvent, 10)
+	_, err := RunChange(context.Background(), client, RunChangeRequest{
+		EnrollmentID: 1,
+		ChangeID:     2,
+		Handlers: ChangeHandlers{LetsEncryptChallenges: func(context.Context, *DVArray) error {
+			calls++
+			return nil
+		}},
+		PollInterval: time.Millisecond,
+		Progress:     progress,
+	})
+	require.NoError(t, err)
+	close(progress)
+	assert.Equal(t, 1, calls)
+
+	var events []ChangeEventType
+	for e := range progress {
+		events = append(events, e.Type)
+	}
+	assert.Equal(t, []ChangeEventType{ChangeEventStatus, ChangeEventInputHandled, ChangeEventStatus, ChangeEventStatus}, events)
+}
+
+func TestRunChange_Validation(t *testing.T) {
+	_, err := RunChange(context.Background(), nil, RunChangeRequest{EnrollmentID: 1})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "ChangeID: cannot be blank")
+}
@@ -0,0 +1,374 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/dns"
+)
+
+type (
+	// DNSChallengeOptions contains options of DNSChallengeSolver
+	DNSChallengeOptions struct {
+		// TTL of created TXT records. Defaults to DefaultDNSChallengeTTL
+		TTL int
+		// PollInterval is the interval between propagation checks. Defaults to DefaultDNSChallengePollInterval
+		PollInterval time.Duration
+		// PropagationTimeout limits the time of waiting for records to propagate. Defaults to DefaultDNSChallengePropagationTimeout
+		PropagationTimeout time.Duration
+		// LookupTXT returns TXT values of the name served by all authoritative name servers of the zone.
+		// Defaults to querying name servers from NS records of the zone directly.
+		LookupTXT func(ctx context.Context, zone, name string) ([][]string, error)
+	}
+
+	// DNSChallengeSolver fulfils dns-01 challenges of DV enrollments by publishing TXT records in Edge DNS zones.
+	// Records are published in the zone with the longest name matching the challenge record name.
+	// Existing TXT records are extended with challenge values and restored by CleanUp.
+	DNSChallengeSolver struct {
+		client    dns.DNS
+		opts      DNSChallengeOptions
+		zones     []string
+		published map[string]*publishedTXT
+	}
+
+	// publishedTXT is a TXT record modified by the solver
+	publishedTXT struct {
+		zone     string
+		name     string
+		original *dns.GetRecordResponse
+	}
+)
+
+const (
+	// DefaultDNSChallengeTTL is the default TTL of challenge TXT records
+	DefaultDNSChallengeTTL = 60
+	// DefaultDNSChallengePollInterval is the default interval between propagation checks
+	DefaultDNSChallengePollInterval = 10 * time.Second
+	// DefaultDNSChallengePropagationTimeout is the default time of waiting for records to propagate
+	DefaultDNSChallengePropagationTimeout = 10 * time.Minute
+
+	// ChallengeTypeDNS01 is the type of DV challenges fulfilled with TXT records
+	ChallengeTypeDNS01 = "dns-01"
+	// acmeChallengeLabel is the label prepended to domain names in names of challenge TXT records
+	acmeChallengeLabel = "_acme-challenge"
+	// zonesPageSize is the page size used when listing zones
+	zonesPageSize = 100
+)
+
+var (
+	// ErrDNSChallenge is returned when dns-01 challenges can't be fulfilled
+	ErrDNSChallenge = errors.New("fulfilling dns-01 challenges")
+	// ErrNoMatchingZone is returned when no Edge DNS zone matches the challenge record name
+	ErrNoMatchingZone = errors.New("no matching zone")
+)
+
+// NewDNSChallengeSolver returns a DNSChallengeSolver publishing records with the Edge DNS client
+func NewDNSChallengeSolver(client dns.DNS, opts DNSChallengeOptions) *DNSChallengeSolver {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultDNSChallengeTTL
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultDNSChallengePollInterval
+	}
+	if opts.PropagationTimeout <= 0 {
+		opts.PropagationTimeout = DefaultDNSChallengePropagationTimeout
+	}
+	if opts.LookupTXT == nil {
+		opts.LookupTXT = lookupAuthoritativeTXT
+	}
+	return &DNSChallengeSolver{client: client, opts: opts, published: make(map[string]*publishedTXT)}
+}
+
+// FulfillDNSChallenges fetches dns-01 challenges of the change, publishes and acknowledges them,
+// then waits until CPS no longer requires the challenges and removes the records.
+// Records are removed also when fulfilment fails.
+func FulfillDNSChallenges(ctx context.Context, client CPS, dnsClient dns.DNS, params GetChangeRequest, opts DNSChallengeOptions) (err error) {
+	challenges, err := client.GetChangeLetsEncryptChallenges(ctx, params)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSChallenge, err)
+	}
+
+	solver := NewDNSChallengeSolver(dnsClient, opts)
+	defer func() {
+		// records are removed even if the context is done
+		if cleanUpErr := solver.CleanUp(context.WithoutCancel(ctx)); cleanUpErr != nil {
+			err = errors.Join(err, cleanUpErr)
+		}
+	}()
+	if err := solver.Present(ctx, challenges); err != nil {
+		return err
+	}
+	err = client.AcknowledgeDVChallenges(ctx, AcknowledgementRequest{
+		Acknowledgement: Acknowledgement{Acknowledgement: AcknowledgementAcknowledge},
+		EnrollmentID:    params.EnrollmentID,
+		ChangeID:        params.ChangeID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSChallenge, err)
+	}
+
+	for {
+		change, err := client.GetChangeStatus(ctx, GetChangeStatusRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDNSChallenge, err)
+		}
+		if !awaitsInput(change, ChangeInputLetsEncryptChallenges) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: waiting for validation: %w", ErrDNSChallenge, ctx.Err())
+		case <-time.After(solver.opts.PollInterval):
+		}
+	}
+}
+
+// HandleChallenges publishes the challenges and waits for their propagation. It can be used as
+// ChangeHandlers.LetsEncryptChallenges of RunChange, with CleanUp called after RunChange returns.
+func (s *DNSChallengeSolver) HandleChallenges(ctx context.Context, challenges *DVArray) error {
+	return s.Present(ctx, challenges)
+}
+
+// Present publishes TXT records of dns-01 challenges and waits until they are served by authoritative name servers
+func (s *DNSChallengeSolver) Present(ctx context.Context, challenges *DVArray) error {
+	values := dnsChallengeValues(challenges)
+	if len(values) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := s.publish(ctx, name, values[name]); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrDNSChallenge, name, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.PropagationTimeout)
+	defer cancel()
+	for _, name := range names {
+		if err := s.waitForPropagation(ctx, s.published[name].zone, name, values[name]); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrDNSChallenge, name, err)
+		}
+	}
+	return nil
+}
+
+// CleanUp removes TXT records created by Present and restores records which existed before
+func (s *DNSChallengeSolver) CleanUp(ctx context.Context) error {
+	var errs []error
+	for name, p := range s.published {
+		var err error
+		if p.original == nil {
+			err = s.client.DeleteRecord(ctx, dns.DeleteRecordRequest{Zone: p.zone, Name: name, RecordType: "TXT"})
+		} else {
+			err = s.client.UpdateRecord(ctx, dns.UpdateRecordRequest{Zone: p.zone, Record: &dns.RecordBody{
+				Name:       name,
+				RecordType: "TXT",
+				TTL:        p.original.TTL,
+				Target:     p.original.Target,
+			}})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delete(s.published, name)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: cleaning up: %w", ErrDNSChallenge, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (s *DNSChallengeSolver) publish(ctx context.Context, name string, values []string) error {
+	zone, err := s.zoneFor(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	p, ok := s.published[name]
+	if !ok {
+		p = &publishedTXT{zone: zone, name: name}
+		existing, err := s.client.GetRecord(ctx, dns.GetRecordRequest{Zone: zone, Name: name, RecordType: "TXT"})
+		var apiErr *dns.Error
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		case err != nil:
+			return err
+		default:
+			p.original = existing
+		}
+	}
+
+	record := &dns.RecordBody{Name: name, RecordType: "TXT", TTL: s.opts.TTL}
+	present := make(map[string]bool)
+	if p.original != nil {
+		record.Target = append(record.Target, p.original.Target...)
+		for _, t := range p.original.Target {
+			present[unquoteTXT(t)] = true
+		}
+	}
+	for _, v := range values {
+		if !present[v] {
+			record.Target = append(record.Target, dns.SplitTXT(v))
+		}
+	}
+
+	if ok || p.original != nil {
+		err = s.client.UpdateRecord(ctx, dns.UpdateRecordRequest{Zone: zone, Record: record})
+	} else {
+		err = s.client.CreateRecord(ctx, dns.CreateRecordRequest{Zone: zone, Record: record})
+	}
+	if err != nil {
+		return err
+	}
+	s.published[name] = p
+	return nil
+}
+
+// zoneFor returns the accessible zone with the longest name matching the record name
+func (s *DNSChallengeSolver) zoneFor(ctx context.Context, name string) (string, error) {
+	if s.zones == nil {
+		zones, err := listAllZones(ctx, s.client)
+		if err != nil {
+			return "", err
+		}
+		s.zones = zones
+	}
+
+	var match string
+	for _, zone := range s.zones {
+		z := strings.ToLower(strings.TrimSuffix(zone, "."))
+		if (name == z || strings.HasSuffix(name, "."+z)) && len(z) > len(match) {
+			match = zone
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("%w for %s", ErrNoMatchingZone, name)
+	}
+	return match, nil
+}
+
+func (s *DNSChallengeSolver) waitForPropagation(ctx context.Context, zone, name string, values []string) error {
+	for {
+		servers, err := s.opts.LookupTXT(ctx, zone, name)
+		if err == nil && txtPropagated(servers, values) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("waiting for propagation: %w: %w", ctx.Err(), err)
+			}
+			return fmt.Errorf("waiting for propagation: %w", ctx.Err())
+		case <-time.After(s.opts.PollInterval):
+		}
+	}
+}
+
+// txtPropagated tells whether each name server serves all values
+func txtPropagated(servers [][]string, values []string) bool {
+	if len(servers) == 0 {
+		return false
+	}
+	for _, served := range servers {
+		present := make(map[string]bool, len(served))
+		for _, v := range served {
+			present[v] = true
+		}
+		for _, v := range values {
+			if !present[v] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func listAllZones(ctx context.Context, client dns.DNS) ([]string, error) {
+	var zones []string
+	for page := 1; ; page++ {
+		resp, err := client.ListZones(ctx, dns.ListZonesRequest{Page: page, PageSize: zonesPageSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, z := range resp.Zones {
+			zones = append(zones, z.Zone)
+		}
+		if len(resp.Zones) < zonesPageSize || resp.Metadata != nil && len(zones) >= resp.Metadata.TotalElements {
+			return zones, nil
+		}
+	}
+}
+
+// dnsChallengeValues returns values of dns-01 challenges grouped by lowercase record names without the trailing dot
+func dnsChallengeValues(challenges *DVArray) map[string][]string {
+	values := make(map[string][]string)
+	if challenges == nil {
+		return values
+	}
+	for _, dv := range challenges.DV {
+		for _, c := range dv.Challenges {
+			if c.Type != ChallengeTypeDNS01 {
+				continue
+			}
+			name := c.FullPath
+			if name == "" {
+				name = acmeChallengeLabel + "." + strings.TrimPrefix(dv.Domain, "*.")
+			}
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			values[name] = append(values[name], c.ResponseBody)
+		}
+	}
+	return values
+}
+
+// awaitsInput tells whether the change requires the input to proceed
+func awaitsInput(change *Change, inputType string) bool {
+	for _, input := range change.AllowedInput {
+		if input.Type == inputType && input.RequiredToProceed {
+			return true
+		}
+	}
+	return false
+}
+
+func unquoteTXT(rdata string) string {
+	return strings.ReplaceAll(strings.Trim(rdata, `"`), `" "`, "")
+}
+
+// lookupAuthoritativeTXT queries each name server of the zone for TXT values of the name
+func lookupAuthoritativeTXT(ctx context.Context, zone, name string) ([][]string, error) {
+	nameServers, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	var result [][]string
+	for _, ns := range nameServers {
+		server := net.JoinHostPort(strings.TrimSuffix(ns.Host, "."), "53")
+		resolver := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+		values, err := resolver.LookupTXT(ctx, name)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return nil, fmt.Errorf("querying %s: %w", ns.Host, err)
+		}
+		result = append(result, values)
+	}
+	return result, nil
+}
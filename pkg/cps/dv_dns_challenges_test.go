@@ -0,0 +1,204 @@
+package cps
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/dns"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegrid"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockDNSClient(t *testing.T, mockServer *httptest.Server) dns.DNS {
+	serverURL, err := url.Parse(mockServer.URL)
+	require.NoError(t, err)
+	certPool := x509.NewCertPool()
+	certPool.AddCert(mockServer.Certificate())
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certPool}}}
+	s, err := session.New(session.WithClient(httpClient), session.WithSigner(&edgegrid.Config{Host: serverURL.Host}))
+	require.NoError(t, err)
+	return dns.Client(s)
+}
+
+// dnsChallengeServer serves Edge DNS zones and records, and the CPS change of enrollment 1
+type dnsChallengeServer struct {
+	sync.Mutex
+	zones    []string
+	records  map[string]string
+	requests []string
+	// changeChecks is the number of change status checks after which challenges are no longer required
+	changeChecks int
+}
+
+func (s *dnsChallengeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == "/config-dns/v2/zones":
+		zones := ""
+		for i, z := range s.zones {
+			if i > 0 {
+				zones += ","
+			}
+			zones += fmt.Sprintf(`{"zone": %q}`, z)
+		}
+		_, _ = fmt.Fprintf(w, `{"metadata": {"totalElements": %d}, "zones": [%s]}`, len(s.zones), zones)
+		return
+	case r.Method == http.MethodGet && path == "/cps/v2/enrollments/1/changes/2/input/info/lets-encrypt-challenges":
+		_, _ = w.Write([]byte(`{"dv": [
+			{"domain": "www.example.com", "challenges": [
+				{"type": "http-01", "fullPath": "http://www.example.com/.well-known/acme-challenge/x", "responseBody": "http"},
+				{"type": "dns-01", "fullPath": "_acme-challenge.www.example.com.", "responseBody": "token-www"}]},
+			{"domain": "*.example.com", "challenges": [{"type": "dns-01", "responseBody": "token-wildcard"}]},
+			{"domain": "example.com", "challenges": [{"type": "dns-01", "responseBody": "token-apex"}]}
+		]}`))
+		return
+	case r.Method == http.MethodGet && path == "/cps/v2/enrollments/1/changes/2":
+		required := s.changeChecks > 0
+		s.changeChecks--
+		_, _ = fmt.Fprintf(w, `{"statusInfo": {"status": "wait-dv"}, "allowedInput": [{"type": "lets-encrypt-challenges", "requiredToProceed": %t}]}`, required)
+		return
+	case r.Method == http.MethodGet:
+		if rec, ok := s.records[path]; ok {
+			_, _ = w.Write([]byte(rec))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type": "not-found", "title": "Not Found"}`))
+		return
+	}
+	s.requests = append(s.requests, fmt.Sprintf("%s %s %s", r.Method, path, body))
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/cps"):
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestDNSChallengeSolver(t *testing.T) {
+	const recordsPath = "/config-dns/v2/zones/"
+	srv := &dnsChallengeServer{
+		zones: []string{"example.com", "sub.www.example.com", "other.com", "www.example.com"},
+		records: map[string]string{
+			recordsPath + "example.com/names/_acme-challenge.example.com/types/TXT": `{"name": "_acme-challenge.example.com", "type": "TXT", "ttl": 300, "rdata": ["\"keep\""]}`,
+		},
+	}
+	mockServer := httptest.NewTLSServer(srv)
+	defer mockServer.Close()
+
+	var lookups []string
+	solver := NewDNSChallengeSolver(mockDNSClient(t, mockServer), DNSChallengeOptions{
+		PollInterval: time.Millisecond,
+		LookupTXT: func(_ context.Context, zone, name string) ([][]string, error) {
+			lookups = append(lookups, zone+" "+name)
+			if len(lookups) == 1 {
+				// not yet propagated to the second name server
+				return [][]string{{"token-wildcard", "token-apex"}, {}}, nil
+			}
+			if name == "_acme-challenge.example.com" {
+				return [][]string{{"keep", "token-wildcard", "token-apex"}, {"keep", "token-wildcard", "token-apex"}}, nil
+			}
+			return [][]string{{"token-www"}}, nil
+		},
+	})
+
+	challenges := &DVArray{DV: []DV{
+		{Domain: "*.example.com", Challenges: []Challenge{{Type: ChallengeTypeDNS01, ResponseBody: "token-wildcard"}}},
+		{Domain: "example.com", Challenges: []Challenge{{Type: ChallengeTypeDNS01, ResponseBody: "token-apex"}}},
+		{Domain: "www.example.com", Challenges: []Challenge{
+			{Type: "http-01", ResponseBody: "http"},
+			{Type: ChallengeTypeDNS01, FullPath: "_acme-challenge.WWW.example.com.", ResponseBody: "token-www"},
+		}},
+	}}
+	require.NoError(t, solver.HandleChallenges(context.Background(), challenges))
+	assert.Equal(t, []string{
+		"PUT " + recordsPath + `example.com/names/_acme-challenge.example.com/types/TXT {"name":"_acme-challenge.example.com","type":"TXT","ttl":60,"rdata":["\"keep\"","\"token-wildcard\"","\"token-apex\""]}`,
+		"POST " + recordsPath + `www.example.com/names/_acme-challenge.www.example.com/types/TXT {"name":"_acme-challenge.www.example.com","type":"TXT","ttl":60,"rdata":["\"token-www\""]}`,
+	}, srv.requests)
+	assert.Equal(t, []string{
+		"example.com _acme-challenge.example.com",
+		"example.com _acme-challenge.example.com",
+		"www.example.com _acme-challenge.www.example.com",
+	}, lookups)
+
+	srv.requests = nil
+	require.NoError(t, solver.CleanUp(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"PUT " + recordsPath + `example.com/names/_acme-challenge.example.com/types/TXT {"name":"_acme-challenge.example.com","type":"TXT","ttl":300,"rdata":["\"keep\""]}`,
+		"DELETE " + recordsPath + "www.example.com/names/_acme-challenge.www.example.com/types/TXT ",
+	}, srv.requests)
+}
+
+func TestDNSChallengeSolver_Errors(t *testing.T) {
+	srv := &dnsChallengeServer{zones: []string{"example.net"}}
+	mockServer := httptest.NewTLSServer(srv)
+	defer mockServer.Close()
+
+	solver := NewDNSChallengeSolver(mockDNSClient(t, mockServer), DNSChallengeOptions{})
+	err := solver.Present(context.Background(), &DVArray{DV: []DV{
+		{Domain: "example.com", Challenges: []Challenge{{Type: ChallengeTypeDNS01, ResponseBody: "token"}}},
+	}})
+	assert.True(t, errors.Is(err, ErrDNSChallenge), "want: %s; got: %s", ErrDNSChallenge, err)
+	assert.True(t, errors.Is(err, ErrNoMatchingZone), "want: %s; got: %s", ErrNoMatchingZone, err)
+
+	srv.zones = []string{"example.com"}
+	solver = NewDNSChallengeSolver(mockDNSClient(t, mockServer), DNSChallengeOptions{
+		PollInterval:       time.Millisecond,
+		PropagationTimeout: 20 * time.Millisecond,
+		LookupTXT: func(context.Context, string, string) ([][]string, error) {
+			return nil, errors.New("SERVFAIL")
+		},
+	})
+	err = solver.Present(context.Background(), &DVArray{DV: []DV{
+		{Domain: "example.com", Challenges: []Challenge{{Type: ChallengeTypeDNS01, ResponseBody: "token"}}},
+	}})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "want: %s; got: %s", context.DeadlineExceeded, err)
+	assert.Contains(t, err.Error(), "SERVFAIL")
+}
+
+func TestFulfillDNSChallenges(t *testing.T) {
+	srv := &dnsChallengeServer{zones: []string{"example.com"}, changeChecks: 1}
+	mockServer := httptest.NewTLSServer(srv)
+	defer mockServer.Close()
+
+	err := FulfillDNSChallenges(context.Background(), mockAPIClient(t, mockServer), mockDNSClient(t, mockServer),
+		GetChangeRequest{EnrollmentID: 1, ChangeID: 2},
+		DNSChallengeOptions{
+			PollInterval: time.Millisecond,
+			LookupTXT: func(context.Context, string, string) ([][]string, error) {
+				return [][]string{{"token-www", "token-wildcard", "token-apex"}}, nil
+			},
+		})
+	require.NoError(t, err)
+
+	const recordsPath = "/config-dns/v2/zones/example.com/names/"
+	assert.Equal(t, []string{
+		"POST " + recordsPath + `_acme-challenge.example.com/types/TXT {"name":"_acme-challenge.example.com","type":"TXT","ttl":60,"rdata":["\"token-wildcard\"","\"token-apex\""]}`,
+		"POST " + recordsPath + `_acme-challenge.www.example.com/types/TXT {"name":"_acme-challenge.www.example.com","type":"TXT","ttl":60,"rdata":["\"token-www\""]}`,
+		`POST /cps/v2/enrollments/1/changes/2/input/update/lets-encrypt-challenges-completed {"acknowledgement":"acknowledge"}`,
+	}, srv.requests[:3])
+	assert.ElementsMatch(t, []string{
+		"DELETE " + recordsPath + "_acme-challenge.example.com/types/TXT ",
+		"DELETE " + recordsPath + "_acme-challenge.www.example.com/types/TXT ",
+	}, srv.requests[3:])
+}
@@ -0,0 +1,249 @@
+package cps
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// ThirdPartyValidationOptions contains options of third-party certificate validation
+	ThirdPartyValidationOptions struct {
+		// Now is the time at which validity is checked. Defaults to the current time
+		Now time.Time
+		// MinValidity is the minimum remaining validity of the certificate and its trust chain
+		MinValidity time.Duration
+		// Roots are trusted root certificates used to check that the trust chain is complete.
+		// Defaults to the system pool. The chain is also complete if it ends with a self-signed certificate.
+		Roots *x509.CertPool
+	}
+)
+
+var (
+	// ErrThirdPartyCertificate is returned when a third-party certificate is not valid for the enrollment
+	ErrThirdPartyCertificate = errors.New("third-party certificate validation")
+	// ErrInvalidPEM is returned when a CSR or certificate can't be parsed
+	ErrInvalidPEM = errors.New("invalid PEM")
+	// ErrPublicKeyMismatch is returned when the certificate public key does not match the CSR
+	ErrPublicKeyMismatch = errors.New("public key does not match the CSR")
+	// ErrKeyAlgorithmMismatch is returned when the certificate key type does not match the expected key algorithm
+	ErrKeyAlgorithmMismatch = errors.New("key algorithm mismatch")
+	// ErrSANMismatch is returned when certificate SANs do not match the enrollment
+	ErrSANMismatch = errors.New("SAN mismatch")
+	// ErrTrustChain is returned when the trust chain is out of order or incomplete
+	ErrTrustChain = errors.New("invalid trust chain")
+	// ErrCertificateValidity is returned when a certificate is expired, not yet valid or expires too soon
+	ErrCertificateValidity = errors.New("invalid certificate validity")
+)
+
+// ValidateThirdPartyCertificates validates certificates signed with CSRs returned by GetChangeThirdPartyCSR
+// before they are uploaded with UploadThirdPartyCertAndTrustChain. Certificates are matched with CSRs by key algorithm
+// and each pair is validated with ValidateThirdPartyCertificate.
+func ValidateThirdPartyCertificates(csrs *ThirdPartyCSRResponse, certs ThirdPartyCertificates, enrollmentCSR *CSR, opts ThirdPartyValidationOptions) error {
+	if csrs == nil {
+		return fmt.Errorf("%w: no CSRs", ErrThirdPartyCertificate)
+	}
+	var errs []error
+	for _, csr := range csrs.CSRs {
+		var found bool
+		for _, cert := range certs.CertificatesAndTrustChains {
+			if !strings.EqualFold(cert.KeyAlgorithm, csr.KeyAlgorithm) {
+				continue
+			}
+			found = true
+			if err := ValidateThirdPartyCertificate(csr, cert, enrollmentCSR, opts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%w: %w: no certificate for %s CSR", ErrThirdPartyCertificate, ErrKeyAlgorithmMismatch, csr.KeyAlgorithm))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateThirdPartyCertificate checks locally that the certificate can be uploaded for the CSR. It verifies that:
+//   - the certificate public key matches the CSR and its type matches the key algorithm
+//   - SANs of the certificate match the common name and SANs of the enrollment CSR, if given
+//   - each certificate of the trust chain is signed by the next one and the chain ends with a trusted or self-signed certificate
+//   - the certificate and the trust chain are valid at the time of check for at least MinValidity
+//
+// All problems found are returned joined, each wrapping ErrThirdPartyCertificate and an error specific to the check.
+func ValidateThirdPartyCertificate(csr CertSigningRequest, cert CertificateAndTrustChain, enrollmentCSR *CSR, opts ThirdPartyValidationOptions) error {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	request, err := parseCSR(csr.CSR)
+	if err != nil {
+		return fmt.Errorf("%w: CSR: %w", ErrThirdPartyCertificate, err)
+	}
+	chain, err := parseCertificates(cert.Certificate + "\n" + cert.TrustChain)
+	if err != nil {
+		return fmt.Errorf("%w: certificate: %w", ErrThirdPartyCertificate, err)
+	}
+	leaf := chain[0]
+
+	var errs []error
+	fail := func(err error, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s %w: %s", ErrThirdPartyCertificate, csr.KeyAlgorithm, err, fmt.Sprintf(format, args...)))
+	}
+
+	if key, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !key.Equal(request.PublicKey) {
+		fail(ErrPublicKeyMismatch, "certificate %q", leaf.Subject.CommonName)
+	}
+	for _, keyAlgorithm := range []string{csr.KeyAlgorithm, cert.KeyAlgorithm} {
+		if expected, ok := keyAlgorithms[strings.ToUpper(keyAlgorithm)]; ok && leaf.PublicKeyAlgorithm != expected {
+			fail(ErrKeyAlgorithmMismatch, "expected %s key, certificate has %s key", keyAlgorithm, leaf.PublicKeyAlgorithm)
+			break
+		}
+	}
+	if enrollmentCSR != nil {
+		if missing, unexpected := compareSANs(leaf.DNSNames, append([]string{enrollmentCSR.CN}, enrollmentCSR.SANS...)); len(missing) > 0 || len(unexpected) > 0 {
+			fail(ErrSANMismatch, "missing: [%s], unexpected: [%s]", strings.Join(missing, ", "), strings.Join(unexpected, ", "))
+		}
+	}
+	if err := verifyChain(chain, opts); err != nil {
+		fail(ErrTrustChain, "%s", err)
+	}
+	for _, c := range chain {
+		if err := checkValidity(c, opts); err != nil {
+			fail(ErrCertificateValidity, "%s", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var keyAlgorithms = map[string]x509.PublicKeyAlgorithm{
+	"RSA":   x509.RSA,
+	"ECDSA": x509.ECDSA,
+}
+
+func parseCSR(data string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || !strings.HasSuffix(block.Type, "CERTIFICATE REQUEST") {
+		return nil, fmt.Errorf("%w: no certificate request found", ErrInvalidPEM)
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPEM, err)
+	}
+	if err := request.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPEM, err)
+	}
+	return request, nil
+}
+
+// parseCertificates returns certificates of all CERTIFICATE blocks in order
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: certificate %d: %w", ErrInvalidPEM, len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no certificate found", ErrInvalidPEM)
+	}
+	return certs, nil
+}
+
+// compareSANs returns expected names missing in the certificate and names of the certificate which were not expected
+func compareSANs(names, expected []string) (missing, unexpected []string) {
+	normalize := func(names []string) map[string]bool {
+		set := make(map[string]bool, len(names))
+		for _, n := range names {
+			if n != "" {
+				set[strings.ToLower(strings.TrimSuffix(n, "."))] = true
+			}
+		}
+		return set
+	}
+	actualSet, expectedSet := normalize(names), normalize(expected)
+	for n := range expectedSet {
+		if !actualSet[n] {
+			missing = append(missing, n)
+		}
+	}
+	for n := range actualSet {
+		if !expectedSet[n] {
+			unexpected = append(unexpected, n)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+// verifyChain checks that each certificate is signed by the next one and that the last one is self-signed or trusted
+func verifyChain(chain []*x509.Certificate, opts ThirdPartyValidationOptions) error {
+	for i := 0; i < len(chain)-1; i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err == nil {
+			continue
+		}
+		for j, issuer := range chain {
+			if j != i && j != i+1 && chain[i].CheckSignatureFrom(issuer) == nil {
+				return fmt.Errorf("certificate %d %q is signed by certificate %d %q, which has to follow it",
+					i, chain[i].Subject.CommonName, j, issuer.Subject.CommonName)
+			}
+		}
+		return fmt.Errorf("certificate %d %q is not signed by certificate %d %q, issuer %q is missing",
+			i, chain[i].Subject.CommonName, i+1, chain[i+1].Subject.CommonName, chain[i].Issuer.CommonName)
+	}
+
+	last := chain[len(chain)-1]
+	if last.CheckSignatureFrom(last) == nil {
+		return nil
+	}
+	roots := opts.Roots
+	if roots == nil {
+		var err error
+		if roots, err = x509.SystemCertPool(); err != nil {
+			return fmt.Errorf("loading system roots: %w", err)
+		}
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   opts.Now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	var invalidErr x509.CertificateInvalidError
+	if err != nil && !(errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired) {
+		// validity is reported separately
+		return fmt.Errorf("chain is incomplete, issuer %q of certificate %d %q is not trusted: %w",
+			last.Issuer.CommonName, len(chain)-1, last.Subject.CommonName, err)
+	}
+	return nil
+}
+
+func checkValidity(cert *x509.Certificate, opts ThirdPartyValidationOptions) error {
+	switch {
+	case opts.Now.Before(cert.NotBefore):
+		return fmt.Errorf("certificate %q is not valid before %s", cert.Subject.CommonName, cert.NotBefore.Format(time.RFC3339))
+	case opts.Now.After(cert.NotAfter):
+		return fmt.Errorf("certificate %q expired at %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	case cert.NotAfter.Sub(opts.Now) < opts.MinValidity:
+		return fmt.Errorf("certificate %q expires at %s, in less than %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339), opts.MinValidity)
+	}
+	return nil
+}
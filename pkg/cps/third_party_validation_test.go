@@ -0,0 +1,202 @@
+package cps
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	pem  string
+}
+
+func newTestCert(t *testing.T, cn string, key crypto.Signer, issuer *testCert, notAfter time.Time, dnsNames ...string) *testCert {
+	if key == nil {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             testNow.Add(-24 * time.Hour),
+		NotAfter:              notAfter,
+		DNSNames:              dnsNames,
+		BasicConstraintsValid: true,
+		IsCA:                  issuer == nil || len(dnsNames) == 0,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+func newTestCSR(t *testing.T, key crypto.Signer) string {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}}, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestValidateThirdPartyCertificate(t *testing.T) {
+	validUntil := testNow.Add(365 * 24 * time.Hour)
+	root := newTestCert(t, "Root CA", nil, nil, validUntil)
+	intermediate := newTestCert(t, "Intermediate CA", nil, root, validUntil)
+	otherRoot := newTestCert(t, "Other CA", nil, nil, validUntil)
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecCSR := CertSigningRequest{CSR: newTestCSR(t, ecKey), KeyAlgorithm: "ECDSA"}
+	rsaCSR := CertSigningRequest{CSR: newTestCSR(t, rsaKey), KeyAlgorithm: "RSA"}
+	enrollmentCSR := &CSR{CN: "www.example.com", SANS: []string{"www.example.com", "example.com"}}
+
+	leaf := newTestCert(t, "www.example.com", ecKey, intermediate, testNow.Add(30*24*time.Hour), "www.example.com", "example.com")
+
+	tests := map[string]struct {
+		csr       CertSigningRequest
+		cert      CertificateAndTrustChain
+		opts      ThirdPartyValidationOptions
+		withError []error
+		contains  []string
+	}{
+		"valid": {
+			csr:  ecCSR,
+			cert: CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: intermediate.pem, KeyAlgorithm: "ECDSA"},
+		},
+		"valid, self-signed root in chain": {
+			csr:  ecCSR,
+			cert: CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: intermediate.pem + root.pem, KeyAlgorithm: "ECDSA"},
+			opts: ThirdPartyValidationOptions{Roots: x509.NewCertPool()},
+		},
+		"public key and key algorithm mismatch": {
+			csr:       rsaCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: intermediate.pem, KeyAlgorithm: "RSA"},
+			withError: []error{ErrPublicKeyMismatch, ErrKeyAlgorithmMismatch},
+			contains:  []string{"expected RSA key, certificate has ECDSA key"},
+		},
+		"SAN mismatch": {
+			csr: ecCSR,
+			cert: CertificateAndTrustChain{
+				Certificate: newTestCert(t, "www.example.com", ecKey, intermediate, validUntil, "WWW.example.com", "api.example.com").pem,
+				TrustChain:  intermediate.pem,
+			},
+			withError: []error{ErrSANMismatch},
+			contains:  []string{"missing: [example.com], unexpected: [api.example.com]"},
+		},
+		"chain out of order": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: root.pem + intermediate.pem},
+			withError: []error{ErrTrustChain},
+			contains:  []string{`certificate 0 "www.example.com" is signed by certificate 2 "Intermediate CA", which has to follow it`},
+		},
+		"chain incomplete": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem},
+			withError: []error{ErrTrustChain},
+			contains:  []string{`chain is incomplete, issuer "Intermediate CA" of certificate 0 "www.example.com" is not trusted`},
+		},
+		"wrong issuer in chain": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: otherRoot.pem},
+			withError: []error{ErrTrustChain},
+			contains:  []string{`issuer "Intermediate CA" is missing`},
+		},
+		"expires too soon": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: intermediate.pem},
+			opts:      ThirdPartyValidationOptions{MinValidity: 60 * 24 * time.Hour},
+			withError: []error{ErrCertificateValidity},
+			contains:  []string{`certificate "www.example.com" expires at 2024-07-01T00:00:00Z, in less than 1440h0m0s`},
+		},
+		"expired": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem, TrustChain: intermediate.pem},
+			opts:      ThirdPartyValidationOptions{Now: testNow.Add(60 * 24 * time.Hour)},
+			withError: []error{ErrCertificateValidity},
+			contains:  []string{`certificate "www.example.com" expired at 2024-07-01T00:00:00Z`},
+		},
+		"invalid certificate PEM": {
+			csr:       ecCSR,
+			cert:      CertificateAndTrustChain{Certificate: "not a certificate"},
+			withError: []error{ErrInvalidPEM},
+		},
+		"invalid CSR PEM": {
+			csr:       CertSigningRequest{CSR: leaf.pem},
+			cert:      CertificateAndTrustChain{Certificate: leaf.pem},
+			withError: []error{ErrInvalidPEM},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := test.opts
+			if opts.Now.IsZero() {
+				opts.Now = testNow
+			}
+			if opts.Roots == nil {
+				opts.Roots = roots
+			}
+			err := ValidateThirdPartyCertificate(test.csr, test.cert, enrollmentCSR, opts)
+			if len(test.withError) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, ErrThirdPartyCertificate), "want: %s; got: %s", ErrThirdPartyCertificate, err)
+			for _, e := range test.withError {
+				assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+			}
+			for _, s := range test.contains {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+}
+
+func TestValidateThirdPartyCertificates(t *testing.T) {
+	validUntil := testNow.Add(365 * 24 * time.Hour)
+	root := newTestCert(t, "Root CA", nil, nil, validUntil)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf := newTestCert(t, "www.example.com", ecKey, root, validUntil, "www.example.com")
+
+	csrs := &ThirdPartyCSRResponse{CSRs: []CertSigningRequest{
+		{CSR: newTestCSR(t, ecKey), KeyAlgorithm: "ECDSA"},
+		{CSR: newTestCSR(t, ecKey), KeyAlgorithm: "RSA"},
+	}}
+	certs := ThirdPartyCertificates{CertificatesAndTrustChains: []CertificateAndTrustChain{
+		{Certificate: leaf.pem, TrustChain: root.pem, KeyAlgorithm: "ecdsa"},
+	}}
+	opts := ThirdPartyValidationOptions{Now: testNow}
+
+	err = ValidateThirdPartyCertificates(csrs, certs, &CSR{CN: "www.example.com"}, opts)
+	assert.True(t, errors.Is(err, ErrKeyAlgorithmMismatch), "want: %s; got: %s", ErrKeyAlgorithmMismatch, err)
+	assert.Contains(t, err.Error(), "no certificate for RSA CSR")
+
+	csrs.CSRs = csrs.CSRs[:1]
+	assert.NoError(t, ValidateThirdPartyCertificates(csrs, certs, &CSR{CN: "www.example.com"}, opts))
+}
@@ -0,0 +1,137 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/session"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// ChangeListOp is an operation applied to a record set within a change list
+	ChangeListOp string
+
+	// ChangeListChange contains a single record set change added to a change list
+	ChangeListChange struct {
+		Name  string       `json:"name"`
+		Type  string       `json:"type"`
+		Op    ChangeListOp `json:"op"`
+		TTL   int          `json:"ttl,omitempty"`
+		Rdata []string     `json:"rdata,omitempty"`
+	}
+
+	// AddChangeListChangeRequest contains request parameters for AddChangeListChange
+	AddChangeListChangeRequest struct {
+		Zone   string
+		Change *ChangeListChange
+	}
+
+	// DeleteChangeListRequest contains request parameters for DeleteChangeList
+	DeleteChangeListRequest ZoneRequest
+)
+
+const (
+	// ChangeListOpAdd adds a new record set
+	ChangeListOpAdd ChangeListOp = "ADD"
+	// ChangeListOpEdit replaces an existing record set
+	ChangeListOpEdit ChangeListOp = "EDIT"
+	// ChangeListOpDelete removes an existing record set
+	ChangeListOpDelete ChangeListOp = "DELETE"
+)
+
+var (
+	// ErrAddChangeListChange is returned when AddChangeListChange fails
+	ErrAddChangeListChange = errors.New("add change list change")
+	// ErrDeleteChangeList is returned when DeleteChangeList fails
+	ErrDeleteChangeList = errors.New("delete change list")
+)
+
+// Validate validates AddChangeListChangeRequest
+func (r AddChangeListChangeRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Zone":   validation.Validate(r.Zone, validation.Required),
+		"Change": validation.Validate(r.Change, validation.Required),
+	})
+}
+
+// Validate validates ChangeListChange
+func (c ChangeListChange) Validate() error {
+	return validation.Errors{
+		"Name": validation.Validate(c.Name, validation.Required),
+		"Type": validation.Validate(c.Type, validation.Required),
+		"Op": validation.Validate(c.Op, validation.Required, validation.In(ChangeListOpAdd, ChangeListOpEdit, ChangeListOpDelete).
+			Error(fmt.Sprintf("value '%s' is invalid. Must be one of: '%s', '%s' or '%s'", c.Op, ChangeListOpAdd, ChangeListOpEdit, ChangeListOpDelete))),
+		"TTL":   validation.Validate(c.TTL, validation.When(c.Op != ChangeListOpDelete, validation.Required)),
+		"Rdata": validation.Validate(c.Rdata, validation.When(c.Op != ChangeListOpDelete, validation.Required)),
+	}.Filter()
+}
+
+// Validate validates DeleteChangeListRequest
+func (r DeleteChangeListRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Zone": validation.Validate(r.Zone, validation.Required),
+	})
+}
+
+func (d *dns) AddChangeListChange(ctx context.Context, params AddChangeListChangeRequest) error {
+	logger := d.Log(ctx)
+	logger.Debug("AddChangeListChange")
+
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %s", ErrAddChangeListChange, ErrStructValidation, err)
+	}
+
+	reqBody, err := convertStructToReqBody(params.Change)
+	if err != nil {
+		return fmt.Errorf("failed to generate request body: %w", err)
+	}
+
+	postURL := fmt.Sprintf("/config-dns/v2/changelists/%s/recordsets/add-change", params.Zone)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create AddChangeListChange request: %w", err)
+	}
+
+	resp, err := d.Exec(req, nil)
+	if err != nil {
+		return fmt.Errorf("AddChangeListChange request failed: %w", err)
+	}
+	defer session.CloseResponseBody(resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return d.Error(resp)
+	}
+
+	return nil
+}
+
+func (d *dns) DeleteChangeList(ctx context.Context, params DeleteChangeListRequest) error {
+	logger := d.Log(ctx)
+	logger.Debug("DeleteChangeList")
+
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %s", ErrDeleteChangeList, ErrStructValidation, err)
+	}
+
+	deleteURL := fmt.Sprintf("/config-dns/v2/changelists/%s", params.Zone)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create DeleteChangeList request: %w", err)
+	}
+
+	resp, err := d.Exec(req, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteChangeList request failed: %w", err)
+	}
+	defer session.CloseResponseBody(resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return d.Error(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNS_AddChangeListChange(t *testing.T) {
+	tests := map[string]struct {
+		params              AddChangeListChangeRequest
+		responseStatus      int
+		responseBody        string
+		expectedPath        string
+		expectedRequestBody string
+		withError           func(*testing.T, error)
+	}{
+		"204 No Content": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name:  "www.example.com",
+					Type:  "A",
+					Op:    ChangeListOpAdd,
+					TTL:   300,
+					Rdata: []string{"10.0.0.2"},
+				},
+			},
+			responseStatus:      http.StatusNoContent,
+			expectedPath:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			expectedRequestBody: `{"name":"www.example.com","type":"A","op":"ADD","ttl":300,"rdata":["10.0.0.2"]}`,
+		},
+		"204 No Content - delete": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name: "www.example.com",
+					Type: "A",
+					Op:   ChangeListOpDelete,
+				},
+			},
+			responseStatus:      http.StatusNoContent,
+			expectedPath:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			expectedRequestBody: `{"name":"www.example.com","type":"A","op":"DELETE"}`,
+		},
+		"validation error - invalid op": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name:  "www.example.com",
+					Type:  "A",
+					Op:    "REPLACE",
+					TTL:   300,
+					Rdata: []string{"10.0.0.2"},
+				},
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+				assert.Contains(t, err.Error(), "value 'REPLACE' is invalid")
+			},
+		},
+		"validation error - missing rdata": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name: "www.example.com",
+					Type: "A",
+					Op:   ChangeListOpEdit,
+					TTL:  300,
+				},
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+				assert.Contains(t, err.Error(), "Rdata: cannot be blank")
+			},
+		},
+		"500 internal server error": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name: "www.example.com",
+					Type: "A",
+					Op:   ChangeListOpDelete,
+				},
+			},
+			responseStatus: http.StatusInternalServerError,
+			responseBody: `
+{
+	"type": "internal_error",
+    "title": "Internal Server Error",
+    "detail": "Error adding change",
+    "status": 500
+}`,
+			expectedPath: "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			withError: func(t *testing.T, err error) {
+				want := &Error{
+					Type:       "internal_error",
+					Title:      "Internal Server Error",
+					Detail:     "Error adding change",
+					StatusCode: http.StatusInternalServerError,
+				}
+				assert.True(t, errors.Is(err, want), "want: %s; got: %s", want, err)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedPath, r.URL.String())
+				assert.Equal(t, http.MethodPost, r.Method)
+				if test.expectedRequestBody != "" {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.JSONEq(t, test.expectedRequestBody, string(body))
+				}
+				w.WriteHeader(test.responseStatus)
+				if len(test.responseBody) > 0 {
+					_, err := w.Write([]byte(test.responseBody))
+					assert.NoError(t, err)
+				}
+			}))
+			client := mockAPIClient(t, mockServer)
+			err := client.AddChangeListChange(context.Background(), test.params)
+			if test.withError != nil {
+				test.withError(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDNS_DeleteChangeList(t *testing.T) {
+	tests := map[string]struct {
+		params         DeleteChangeListRequest
+		responseStatus int
+		responseBody   string
+		expectedPath   string
+		withError      func(*testing.T, error)
+	}{
+		"204 No Content": {
+			params:         DeleteChangeListRequest{Zone: "example.com"},
+			responseStatus: http.StatusNoContent,
+			expectedPath:   "/config-dns/v2/changelists/example.com",
+		},
+		"validation error": {
+			params: DeleteChangeListRequest{},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+			},
+		},
+		"404 not found": {
+			params:         DeleteChangeListRequest{Zone: "example.com"},
+			responseStatus: http.StatusNotFound,
+			responseBody: `
+{
+	"type": "not_found",
+    "title": "Not Found",
+    "detail": "Change list for zone example.com does not exist",
+    "status": 404
+}`,
+			expectedPath: "/config-dns/v2/changelists/example.com",
+			withError: func(t *testing.T, err error) {
+				want := &Error{
+					Type:       "not_found",
+					Title:      "Not Found",
+					Detail:     "Change list for zone example.com does not exist",
+					StatusCode: http.StatusNotFound,
+				}
+				assert.True(t, errors.Is(err, want), "want: %s; got: %s", want, err)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedPath, r.URL.String())
+				assert.Equal(t, http.MethodDelete, r.Method)
+				w.WriteHeader(test.responseStatus)
+				if len(test.responseBody) > 0 {
+					_, err := w.Write([]byte(test.responseBody))
+					assert.NoError(t, err)
+				}
+			}))
+			client := mockAPIClient(t, mockServer)
+			err := client.DeleteChangeList(context.Background(), test.params)
+			if test.withError != nil {
+				test.withError(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
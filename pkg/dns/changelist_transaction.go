@@ -0,0 +1,283 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// ChangeTransaction groups record set changes for a single zone and applies them
+	// atomically through the zone's change list.
+	//
+	// Changes are only buffered locally until Commit is called, so no server side state
+	// exists for a transaction that is never committed. Commit creates the change list,
+	// adds all changes, verifies the zone was not modified concurrently and submits it.
+	// If any of those steps fails, or the context is cancelled, the change list is removed.
+	ChangeTransaction struct {
+		ctx     context.Context
+		client  DNS
+		zone    string
+		serial  uint32
+		changes []ChangeListChange
+		closed  bool
+		mu      sync.Mutex
+	}
+)
+
+var (
+	// ErrBeginChange is returned when BeginChange fails
+	ErrBeginChange = errors.New("begin change")
+	// ErrCommitChange is returned when ChangeTransaction.Commit fails
+	ErrCommitChange = errors.New("commit change")
+	// ErrTransactionClosed is returned when a committed or discarded transaction is used
+	ErrTransactionClosed = errors.New("transaction already committed or discarded")
+	// ErrConcurrentModification is returned when the zone's SOA serial changed while the transaction was open
+	ErrConcurrentModification = errors.New("zone was modified concurrently")
+	// ErrChangeListPending is returned when the zone already has a change list which is not stale
+	ErrChangeListPending = errors.New("zone has a pending change list")
+)
+
+// changeListCleanupTimeout bounds the time spent on removing a change list after a failed commit
+var changeListCleanupTimeout = 30 * time.Second
+
+// BeginChange starts a new change list transaction for the given zone.
+// It records the current SOA serial of the zone, which is later used to detect concurrent modifications.
+func BeginChange(ctx context.Context, client DNS, zone string) (*ChangeTransaction, error) {
+	if zone == "" {
+		return nil, fmt.Errorf("%s: %w: zone is required", ErrBeginChange, ErrStructValidation)
+	}
+
+	serial, err := zoneSerial(ctx, client, zone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBeginChange, err)
+	}
+
+	return &ChangeTransaction{
+		ctx:    ctx,
+		client: client,
+		zone:   zone,
+		serial: serial,
+	}, nil
+}
+
+// Zone returns the name of the zone modified by the transaction
+func (tx *ChangeTransaction) Zone() string {
+	return tx.zone
+}
+
+// Upsert adds or replaces the record set with the given name and type
+func (tx *ChangeTransaction) Upsert(rs RecordSet) error {
+	if err := ValidateRecordSet(tx.zone, rs); err != nil {
+		return fmt.Errorf("%w: %s", ErrStructValidation, err)
+	}
+
+	return tx.add(ChangeListChange{
+		Name:  rs.Name,
+		Type:  strings.ToUpper(rs.Type),
+		TTL:   rs.TTL,
+		Rdata: rs.Rdata,
+	})
+}
+
+// Delete removes the record set with the given name and type
+func (tx *ChangeTransaction) Delete(name, recordType string) error {
+	err := validation.Errors{
+		"Name": validation.Validate(name, validation.Required),
+		"Type": validation.Validate(recordType, validation.Required),
+	}.Filter()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStructValidation, err)
+	}
+
+	return tx.add(ChangeListChange{
+		Name: name,
+		Type: strings.ToUpper(recordType),
+		Op:   ChangeListOpDelete,
+	})
+}
+
+// add queues the change, replacing any earlier change of the same record set
+func (tx *ChangeTransaction) add(change ChangeListChange) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTransactionClosed
+	}
+	for i, c := range tx.changes {
+		if strings.EqualFold(c.Name, change.Name) && c.Type == change.Type {
+			tx.changes[i] = change
+			return nil
+		}
+	}
+	tx.changes = append(tx.changes, change)
+
+	return nil
+}
+
+// Changes returns a copy of the changes queued in the transaction
+func (tx *ChangeTransaction) Changes() []ChangeListChange {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	changes := make([]ChangeListChange, len(tx.changes))
+	copy(changes, tx.changes)
+	return changes
+}
+
+// Discard drops all queued changes and closes the transaction
+func (tx *ChangeTransaction) Discard() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTransactionClosed
+	}
+	tx.closed = true
+	tx.changes = nil
+
+	return nil
+}
+
+// Commit applies all queued changes to the zone and closes the transaction.
+//
+// A stale change list left over for the zone is removed before a new one is created.
+// ErrChangeListPending is returned if the zone already has an up-to-date change list,
+// and ErrConcurrentModification if the zone's SOA serial changed since BeginChange.
+func (tx *ChangeTransaction) Commit() (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTransactionClosed
+	}
+	tx.closed = true
+
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	ctx, zone := tx.ctx, tx.zone
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %s", ErrCommitChange, err)
+	}
+
+	if err := tx.checkSerial(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitChange, err)
+	}
+
+	if err := tx.removeStaleChangeList(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitChange, err)
+	}
+
+	if err := tx.client.SaveChangeList(ctx, SaveChangeListRequest{Zone: zone}); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitChange, err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// the request context may already be cancelled, cleanup must not depend on it
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), changeListCleanupTimeout)
+		defer cancel()
+		if cleanupErr := tx.client.DeleteChangeList(cleanupCtx, DeleteChangeListRequest{Zone: zone}); cleanupErr != nil {
+			err = errors.Join(err, fmt.Errorf("%w: %s", ErrDeleteChangeList, cleanupErr))
+		}
+	}()
+
+	for _, change := range tx.changes {
+		if change.Op == "" {
+			if change.Op, err = tx.upsertOp(ctx, change); err != nil {
+				return fmt.Errorf("%w: %w", ErrCommitChange, err)
+			}
+		}
+		if err = tx.client.AddChangeListChange(ctx, AddChangeListChangeRequest{Zone: zone, Change: &change}); err != nil {
+			return fmt.Errorf("%w: %s %s: %w", ErrCommitChange, change.Name, change.Type, err)
+		}
+	}
+
+	if err = tx.checkSerial(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitChange, err)
+	}
+
+	if err = tx.client.SubmitChangeList(ctx, SubmitChangeListRequest{Zone: zone}); err != nil {
+		return fmt.Errorf("%w: %w", ErrCommitChange, err)
+	}
+
+	return nil
+}
+
+// checkSerial verifies that the zone's SOA serial did not change since the transaction began
+func (tx *ChangeTransaction) checkSerial(ctx context.Context) error {
+	serial, err := zoneSerial(ctx, tx.client, tx.zone)
+	if err != nil {
+		return err
+	}
+	if serial != tx.serial {
+		return fmt.Errorf("%w: SOA serial changed from %d to %d", ErrConcurrentModification, tx.serial, serial)
+	}
+	return nil
+}
+
+// removeStaleChangeList deletes an existing change list if it no longer matches the current zone version
+func (tx *ChangeTransaction) removeStaleChangeList(ctx context.Context) error {
+	changeList, err := tx.client.GetChangeList(ctx, GetChangeListRequest{Zone: tx.zone})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !changeList.Stale {
+		return fmt.Errorf("%w: %s", ErrChangeListPending, tx.zone)
+	}
+
+	return tx.client.DeleteChangeList(ctx, DeleteChangeListRequest{Zone: tx.zone})
+}
+
+// upsertOp determines if the record set has to be added or edited
+func (tx *ChangeTransaction) upsertOp(ctx context.Context, change ChangeListChange) (ChangeListOp, error) {
+	_, err := tx.client.GetRecord(ctx, GetRecordRequest{Zone: tx.zone, Name: change.Name, RecordType: change.Type})
+	if err == nil {
+		return ChangeListOpEdit, nil
+	}
+	if isNotFound(err) {
+		return ChangeListOpAdd, nil
+	}
+	return "", err
+}
+
+// zoneSerial returns the serial number from the zone's SOA record
+func zoneSerial(ctx context.Context, client DNS, zone string) (uint32, error) {
+	soa, err := client.GetRecord(ctx, GetRecordRequest{Zone: zone, Name: zone, RecordType: "SOA"})
+	if err != nil {
+		return 0, err
+	}
+	if len(soa.Target) == 0 {
+		return 0, fmt.Errorf("SOA record of zone %s has no rdata", zone)
+	}
+	parts := strings.Fields(soa.Target[0])
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("invalid SOA rdata for zone %s: %q", zone, soa.Target[0])
+	}
+	serial, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SOA serial for zone %s: %s", zone, err)
+	}
+
+	return uint32(serial), nil
+}
+
+// isNotFound reports whether err is an API error with 404 status code
+func isNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
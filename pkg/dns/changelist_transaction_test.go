@@ -0,0 +1,334 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type expectedCall struct {
+	method       string
+	path         string
+	requestBody  string
+	status       int
+	responseBody string
+}
+
+// scriptedServer returns a server which expects given calls in order
+func scriptedServer(t *testing.T, calls []expectedCall) (*httptest.Server, func()) {
+	var mu sync.Mutex
+	var i int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !assert.Less(t, i, len(calls), "unexpected call %s %s", r.Method, r.URL) {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		call := calls[i]
+		i++
+		assert.Equal(t, call.method, r.Method)
+		assert.Equal(t, call.path, r.URL.String())
+		if call.requestBody != "" {
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, call.requestBody, string(body))
+		}
+		w.WriteHeader(call.status)
+		if call.responseBody != "" {
+			_, err := w.Write([]byte(call.responseBody))
+			assert.NoError(t, err)
+		}
+	}))
+	return server, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, len(calls), i, "not all expected calls were made")
+	}
+}
+
+func soaCall(serial string) expectedCall {
+	return expectedCall{
+		method:       http.MethodGet,
+		path:         "/config-dns/v2/zones/example.com/names/example.com/types/SOA",
+		status:       http.StatusOK,
+		responseBody: `{"name":"example.com","type":"SOA","ttl":86400,"rdata":["a1-1.akam.net. hostmaster.example.com. ` + serial + ` 3600 600 604800 300"]}`,
+	}
+}
+
+const notFoundBody = `{"type": "not_found", "title": "Not Found", "detail": "not found", "status": 404}`
+
+func TestChangeTransaction(t *testing.T) {
+	getChangeListNotFound := expectedCall{method: http.MethodGet, path: "/config-dns/v2/changelists/example.com", status: http.StatusNotFound, responseBody: notFoundBody}
+	saveChangeList := expectedCall{method: http.MethodPost, path: "/config-dns/v2/changelists?zone=example.com", status: http.StatusCreated}
+	deleteChangeList := expectedCall{method: http.MethodDelete, path: "/config-dns/v2/changelists/example.com", status: http.StatusNoContent}
+	submitChangeList := expectedCall{method: http.MethodPost, path: "/config-dns/v2/changelists/example.com/submit", status: http.StatusNoContent}
+
+	tests := map[string]struct {
+		calls     []expectedCall
+		changes   func(*testing.T, *ChangeTransaction)
+		withError func(*testing.T, error)
+	}{
+		"commit upsert and delete": {
+			calls: []expectedCall{
+				soaCall("2024010101"),
+				soaCall("2024010101"),
+				getChangeListNotFound,
+				saveChangeList,
+				{
+					method: http.MethodGet,
+					path:   "/config-dns/v2/zones/example.com/names/www.example.com/types/A",
+					status: http.StatusNotFound, responseBody: notFoundBody,
+				},
+				{
+					method:      http.MethodPost,
+					path:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					requestBody: `{"name":"www.example.com","type":"A","op":"ADD","ttl":300,"rdata":["10.0.0.1"]}`,
+					status:      http.StatusNoContent,
+				},
+				{
+					method:       http.MethodGet,
+					path:         "/config-dns/v2/zones/example.com/names/api.example.com/types/CNAME",
+					status:       http.StatusOK,
+					responseBody: `{"name":"api.example.com","type":"CNAME","ttl":300,"rdata":["old.example.net."]}`,
+				},
+				{
+					method:      http.MethodPost,
+					path:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					requestBody: `{"name":"api.example.com","type":"CNAME","op":"EDIT","ttl":600,"rdata":["new.example.net."]}`,
+					status:      http.StatusNoContent,
+				},
+				{
+					method:      http.MethodPost,
+					path:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					requestBody: `{"name":"old.example.com","type":"TXT","op":"DELETE"}`,
+					status:      http.StatusNoContent,
+				},
+				soaCall("2024010101"),
+				submitChangeList,
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Upsert(RecordSet{Name: "www.example.com", Type: "a", TTL: 300, Rdata: []string{"10.0.0.1"}}))
+				require.NoError(t, tx.Upsert(RecordSet{Name: "api.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"stale.example.net."}}))
+				require.NoError(t, tx.Delete("old.example.com", "TXT"))
+				// later change of the same record set replaces the earlier one
+				require.NoError(t, tx.Upsert(RecordSet{Name: "api.example.com", Type: "CNAME", TTL: 600, Rdata: []string{"new.example.net."}}))
+			},
+		},
+		"stale change list is removed": {
+			calls: []expectedCall{
+				soaCall("7"),
+				soaCall("7"),
+				{
+					method:       http.MethodGet,
+					path:         "/config-dns/v2/changelists/example.com",
+					status:       http.StatusOK,
+					responseBody: `{"zone":"example.com","stale":true}`,
+				},
+				deleteChangeList,
+				saveChangeList,
+				{
+					method:      http.MethodPost,
+					path:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					requestBody: `{"name":"www.example.com","type":"A","op":"DELETE"}`,
+					status:      http.StatusNoContent,
+				},
+				soaCall("7"),
+				submitChangeList,
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Delete("www.example.com", "A"))
+			},
+		},
+		"pending change list": {
+			calls: []expectedCall{
+				soaCall("7"),
+				soaCall("7"),
+				{
+					method:       http.MethodGet,
+					path:         "/config-dns/v2/changelists/example.com",
+					status:       http.StatusOK,
+					responseBody: `{"zone":"example.com","stale":false}`,
+				},
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Delete("www.example.com", "A"))
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrChangeListPending), "want: %s; got: %s", ErrChangeListPending, err)
+			},
+		},
+		"zone modified before commit": {
+			calls: []expectedCall{
+				soaCall("7"),
+				soaCall("8"),
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Delete("www.example.com", "A"))
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrConcurrentModification), "want: %s; got: %s", ErrConcurrentModification, err)
+			},
+		},
+		"zone modified during commit, change list is removed": {
+			calls: []expectedCall{
+				soaCall("7"),
+				soaCall("7"),
+				getChangeListNotFound,
+				saveChangeList,
+				{
+					method: http.MethodPost,
+					path:   "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					status: http.StatusNoContent,
+				},
+				soaCall("8"),
+				deleteChangeList,
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Delete("www.example.com", "A"))
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrCommitChange), "want: %s; got: %s", ErrCommitChange, err)
+				assert.True(t, errors.Is(err, ErrConcurrentModification), "want: %s; got: %s", ErrConcurrentModification, err)
+			},
+		},
+		"failed change, cleanup fails": {
+			calls: []expectedCall{
+				soaCall("7"),
+				soaCall("7"),
+				getChangeListNotFound,
+				saveChangeList,
+				{
+					method:       http.MethodPost,
+					path:         "/config-dns/v2/changelists/example.com/recordsets/add-change",
+					status:       http.StatusBadRequest,
+					responseBody: `{"type": "bad_request", "title": "Bad Request", "detail": "invalid change", "status": 400}`,
+				},
+				{
+					method:       http.MethodDelete,
+					path:         "/config-dns/v2/changelists/example.com",
+					status:       http.StatusInternalServerError,
+					responseBody: `{"type": "internal_error", "title": "Internal Server Error", "detail": "oops", "status": 500}`,
+				},
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {
+				require.NoError(t, tx.Delete("www.example.com", "A"))
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrCommitChange), "want: %s; got: %s", ErrCommitChange, err)
+				assert.True(t, errors.Is(err, ErrDeleteChangeList), "want: %s; got: %s", ErrDeleteChangeList, err)
+				assert.Contains(t, err.Error(), "invalid change")
+			},
+		},
+		"nothing to commit": {
+			calls: []expectedCall{
+				soaCall("7"),
+			},
+			changes: func(t *testing.T, tx *ChangeTransaction) {},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer, verify := scriptedServer(t, test.calls)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			tx, err := BeginChange(context.Background(), client, "example.com")
+			require.NoError(t, err)
+			test.changes(t, tx)
+			err = tx.Commit()
+			verify()
+			if test.withError != nil {
+				test.withError(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.ErrorIs(t, tx.Commit(), ErrTransactionClosed)
+			assert.ErrorIs(t, tx.Delete("www.example.com", "A"), ErrTransactionClosed)
+		})
+	}
+}
+
+func TestChangeTransaction_Discard(t *testing.T) {
+	mockServer, verify := scriptedServer(t, []expectedCall{soaCall("7")})
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	tx, err := BeginChange(context.Background(), client, "example.com")
+	require.NoError(t, err)
+	require.NoError(t, tx.Upsert(RecordSet{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}}))
+	assert.Len(t, tx.Changes(), 1)
+
+	require.NoError(t, tx.Discard())
+	assert.Empty(t, tx.Changes())
+	assert.ErrorIs(t, tx.Discard(), ErrTransactionClosed)
+	assert.ErrorIs(t, tx.Commit(), ErrTransactionClosed)
+	verify()
+}
+
+func TestChangeTransaction_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockServer, verify := scriptedServer(t, []expectedCall{
+		soaCall("7"),
+		soaCall("7"),
+		{method: http.MethodGet, path: "/config-dns/v2/changelists/example.com", status: http.StatusNotFound, responseBody: notFoundBody},
+		{method: http.MethodPost, path: "/config-dns/v2/changelists?zone=example.com", status: http.StatusCreated},
+		{method: http.MethodPost, path: "/config-dns/v2/changelists/example.com/recordsets/add-change", status: http.StatusNoContent},
+		// cleanup is performed even though the context is already cancelled
+		{method: http.MethodDelete, path: "/config-dns/v2/changelists/example.com", status: http.StatusNoContent},
+	})
+	defer mockServer.Close()
+	client := &cancellingClient{DNS: mockAPIClient(t, mockServer), cancel: cancel}
+
+	tx, err := BeginChange(ctx, client, "example.com")
+	require.NoError(t, err)
+	require.NoError(t, tx.Delete("www.example.com", "A"))
+	require.NoError(t, tx.Delete("api.example.com", "A"))
+
+	err = tx.Commit()
+	assert.ErrorIs(t, err, context.Canceled)
+	verify()
+}
+
+func TestBeginChange(t *testing.T) {
+	t.Run("missing zone", func(t *testing.T) {
+		_, err := BeginChange(context.Background(), nil, "")
+		assert.ErrorIs(t, err, ErrStructValidation)
+	})
+	t.Run("invalid SOA", func(t *testing.T) {
+		mockServer, verify := scriptedServer(t, []expectedCall{{
+			method:       http.MethodGet,
+			path:         "/config-dns/v2/zones/example.com/names/example.com/types/SOA",
+			status:       http.StatusOK,
+			responseBody: `{"name":"example.com","type":"SOA","ttl":86400,"rdata":["a1-1.akam.net. hostmaster.example.com. abc"]}`,
+		}})
+		defer mockServer.Close()
+		_, err := BeginChange(context.Background(), mockAPIClient(t, mockServer), "example.com")
+		assert.ErrorIs(t, err, ErrBeginChange)
+		assert.Contains(t, err.Error(), "invalid SOA serial")
+		verify()
+	})
+}
+
+// cancellingClient cancels the context after the first change is added to the change list
+type cancellingClient struct {
+	DNS
+	cancel context.CancelFunc
+}
+
+func (c *cancellingClient) AddChangeListChange(ctx context.Context, params AddChangeListChangeRequest) error {
+	err := c.DNS.AddChangeListChange(ctx, params)
+	c.cancel()
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,428 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// DSDigestType is a DS record digest algorithm as defined in the IANA "Delegation Signer (DS) Resource Record (RR) Type Digest Algorithms" registry
+	DSDigestType uint8
+
+	// DNSKey contains parsed DNSKEY record data
+	DNSKey struct {
+		Owner     string
+		TTL       int64
+		Flags     uint16
+		Protocol  uint8
+		Algorithm uint8
+		PublicKey []byte
+	}
+
+	// DSRecord contains DS record data
+	DSRecord struct {
+		Owner      string
+		TTL        int64
+		KeyTag     uint16
+		Algorithm  uint8
+		DigestType DSDigestType
+		Digest     string
+	}
+
+	// DNSSecReportOptions contains options for GetDNSSecReport
+	DNSSecReportOptions struct {
+		// DigestTypes lists digest types of the DS records computed for each key. Defaults to SHA-256 and SHA-384
+		DigestTypes []DSDigestType
+		// KeyMaxAge is the age after which a key is considered expired. Key expiry is not checked if zero
+		KeyMaxAge time.Duration
+		// ExpiryWarning is the period before key expiry in which the key is reported as expiring
+		ExpiryWarning time.Duration
+		// Now returns the current time. Defaults to time.Now
+		Now func() time.Time
+	}
+
+	// DNSSecReport contains DNSSEC details for a list of zones
+	DNSSecReport struct {
+		Zones []ZoneDNSSecReport
+	}
+
+	// ZoneDNSSecReport contains DNSSEC details for a single zone
+	ZoneDNSSecReport struct {
+		Zone   string
+		Alerts []string
+		// Current contains the key currently used for signing the zone
+		Current *DNSSecKeyReport
+		// New contains the key the zone is rolling over to, if any
+		New *DNSSecKeyReport
+		// RolloverPending is true when a new key was generated and its DS records have to be published at the parent zone
+		RolloverPending bool
+		// KeyExpiresAt is the time at which the current key reaches DNSSecReportOptions.KeyMaxAge
+		KeyExpiresAt time.Time
+		// KeyExpiring is true when the current key expires within DNSSecReportOptions.ExpiryWarning
+		KeyExpiring bool
+		// RegistrarDS lists DS records which should be present at the parent zone
+		RegistrarDS []DSRecord
+		// Error describes a problem with parsing the zone's key material
+		Error error
+	}
+
+	// DNSSecKeyReport contains details of a single DNSSEC key
+	DNSSecKeyReport struct {
+		DNSKey DNSKey
+		// DS contains DS records computed from the key for each requested digest type
+		DS []DSRecord
+		// PublishedDS contains the DS record returned by the API
+		PublishedDS *DSRecord
+		// DSMatches is true when PublishedDS equals one of the computed DS records
+		DSMatches        bool
+		ExpectedTTL      int64
+		LastModifiedDate time.Time
+	}
+)
+
+const (
+	// DSDigestSHA1 is the SHA-1 digest type
+	DSDigestSHA1 DSDigestType = 1
+	// DSDigestSHA256 is the SHA-256 digest type
+	DSDigestSHA256 DSDigestType = 2
+	// DSDigestSHA384 is the SHA-384 digest type
+	DSDigestSHA384 DSDigestType = 4
+
+	// dnsKeyFlagSEP is the Secure Entry Point flag, set for key signing keys
+	dnsKeyFlagSEP = 0x0001
+	// dnsKeyAlgorithmRSAMD5 uses a different key tag calculation
+	dnsKeyAlgorithmRSAMD5 = 1
+)
+
+var (
+	// ErrParseDNSKey is returned when DNSKEY record can't be parsed
+	ErrParseDNSKey = errors.New("parse DNSKEY record")
+	// ErrParseDS is returned when DS record can't be parsed
+	ErrParseDS = errors.New("parse DS record")
+	// ErrUnsupportedDigestType is returned when DS digest type is not supported
+	ErrUnsupportedDigestType = errors.New("unsupported DS digest type")
+	// ErrGetDNSSecReport is returned when GetDNSSecReport fails
+	ErrGetDNSSecReport = errors.New("get DNSSEC report")
+)
+
+// String returns the digest type mnemonic
+func (t DSDigestType) String() string {
+	switch t {
+	case DSDigestSHA1:
+		return "SHA-1"
+	case DSDigestSHA256:
+		return "SHA-256"
+	case DSDigestSHA384:
+		return "SHA-384"
+	}
+	return strconv.Itoa(int(t))
+}
+
+func (t DSDigestType) hash() (hash.Hash, error) {
+	switch t {
+	case DSDigestSHA256:
+		return sha256.New(), nil
+	case DSDigestSHA384:
+		return sha512.New384(), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDigestType, t)
+}
+
+// ParseDNSKeyRecord parses DNSKEY record in presentation format, as returned in SecRecords.DNSKeyRecord,
+// e.g. "example.com. 7200 IN DNSKEY 257 3 13 ( base64key )"
+func ParseDNSKeyRecord(record string) (*DNSKey, error) {
+	owner, ttl, rdata, err := splitRecord(record, "DNSKEY")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrParseDNSKey, err)
+	}
+	if len(rdata) < 4 {
+		return nil, fmt.Errorf("%w: expected flags, protocol, algorithm and public key: %q", ErrParseDNSKey, record)
+	}
+	flags, err := strconv.ParseUint(rdata[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid flags: %s", ErrParseDNSKey, err)
+	}
+	protocol, err := strconv.ParseUint(rdata[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid protocol: %s", ErrParseDNSKey, err)
+	}
+	algorithm, err := strconv.ParseUint(rdata[2], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid algorithm: %s", ErrParseDNSKey, err)
+	}
+	// public key can be split into several whitespace separated chunks
+	publicKey, err := base64.StdEncoding.DecodeString(strings.Join(rdata[3:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key: %s", ErrParseDNSKey, err)
+	}
+
+	return &DNSKey{
+		Owner:     owner,
+		TTL:       ttl,
+		Flags:     uint16(flags),
+		Protocol:  uint8(protocol),
+		Algorithm: uint8(algorithm),
+		PublicKey: publicKey,
+	}, nil
+}
+
+// ParseDSRecord parses DS record in presentation format, as returned in SecRecords.DSRecord,
+// e.g. "example.com. 86400 IN DS 42061 13 2 ( hexdigest )"
+func ParseDSRecord(record string) (*DSRecord, error) {
+	owner, ttl, rdata, err := splitRecord(record, "DS")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrParseDS, err)
+	}
+	if len(rdata) < 4 {
+		return nil, fmt.Errorf("%w: expected key tag, algorithm, digest type and digest: %q", ErrParseDS, record)
+	}
+	keyTag, err := strconv.ParseUint(rdata[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key tag: %s", ErrParseDS, err)
+	}
+	algorithm, err := strconv.ParseUint(rdata[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid algorithm: %s", ErrParseDS, err)
+	}
+	digestType, err := strconv.ParseUint(rdata[2], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid digest type: %s", ErrParseDS, err)
+	}
+	digest := strings.ToUpper(strings.Join(rdata[3:], ""))
+	if _, err := hex.DecodeString(digest); err != nil {
+		return nil, fmt.Errorf("%w: invalid digest: %s", ErrParseDS, err)
+	}
+
+	return &DSRecord{
+		Owner:      owner,
+		TTL:        ttl,
+		KeyTag:     uint16(keyTag),
+		Algorithm:  uint8(algorithm),
+		DigestType: DSDigestType(digestType),
+		Digest:     digest,
+	}, nil
+}
+
+// splitRecord splits record in presentation format into owner, TTL and rdata fields.
+// TTL and class are optional, parentheses used for multi-line rdata are removed.
+func splitRecord(record, recordType string) (string, int64, []string, error) {
+	record = strings.NewReplacer("(", " ", ")", " ").Replace(record)
+	fields := strings.Fields(record)
+	typeIdx := -1
+	for i, f := range fields {
+		if strings.EqualFold(f, recordType) {
+			typeIdx = i
+			break
+		}
+	}
+	if typeIdx < 1 {
+		return "", 0, nil, fmt.Errorf("no owner name or %s type found in %q", recordType, record)
+	}
+
+	var ttl int64
+	for _, f := range fields[1:typeIdx] {
+		if v, err := strconv.ParseInt(f, 10, 64); err == nil {
+			ttl = v
+		}
+	}
+
+	return fields[0], ttl, fields[typeIdx+1:], nil
+}
+
+// IsKSK reports whether the key has the Secure Entry Point flag set
+func (k DNSKey) IsKSK() bool {
+	return k.Flags&dnsKeyFlagSEP != 0
+}
+
+// rdata returns the key's wire format rdata
+func (k DNSKey) rdata() []byte {
+	rdata := make([]byte, 4, 4+len(k.PublicKey))
+	binary.BigEndian.PutUint16(rdata, k.Flags)
+	rdata[2] = k.Protocol
+	rdata[3] = k.Algorithm
+	return append(rdata, k.PublicKey...)
+}
+
+// KeyTag calculates the key tag as described in RFC 4034, Appendix B
+func (k DNSKey) KeyTag() uint16 {
+	if k.Algorithm == dnsKeyAlgorithmRSAMD5 {
+		if len(k.PublicKey) < 3 {
+			return 0
+		}
+		return binary.BigEndian.Uint16(k.PublicKey[len(k.PublicKey)-3:])
+	}
+
+	var ac uint32
+	for i, b := range k.rdata() {
+		if i&1 == 1 {
+			ac += uint32(b)
+		} else {
+			ac += uint32(b) << 8
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac & 0xFFFF)
+}
+
+// DS computes the DS record for the key as described in RFC 4034, section 5.1.4
+func (k DNSKey) DS(digestType DSDigestType) (*DSRecord, error) {
+	h, err := digestType.hash()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := canonicalName(k.Owner)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(owner)
+	h.Write(k.rdata())
+
+	return &DSRecord{
+		Owner:      k.Owner,
+		TTL:        k.TTL,
+		KeyTag:     k.KeyTag(),
+		Algorithm:  k.Algorithm,
+		DigestType: digestType,
+		Digest:     strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+	}, nil
+}
+
+// canonicalName returns the lowercase wire format of a domain name
+func canonicalName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	var wire []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("invalid label %q in domain name %q", label, name)
+			}
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+	wire = append(wire, 0)
+	if len(wire) > 255 {
+		return nil, fmt.Errorf("domain name %q is too long", name)
+	}
+	return wire, nil
+}
+
+// Equal reports whether both records describe the same key digest
+func (r DSRecord) Equal(other DSRecord) bool {
+	return strings.EqualFold(strings.TrimSuffix(r.Owner, "."), strings.TrimSuffix(other.Owner, ".")) &&
+		r.KeyTag == other.KeyTag &&
+		r.Algorithm == other.Algorithm &&
+		r.DigestType == other.DigestType &&
+		strings.EqualFold(r.Digest, other.Digest)
+}
+
+// String returns the record in presentation format
+func (r DSRecord) String() string {
+	owner := r.Owner
+	if !strings.HasSuffix(owner, ".") {
+		owner += "."
+	}
+	return fmt.Sprintf("%s %d IN DS %d %d %d %s", owner, r.TTL, r.KeyTag, r.Algorithm, r.DigestType, r.Digest)
+}
+
+// GetDNSSecReport retrieves DNSSEC status of the given zones and computes DS records, pending rollovers and
+// key expiry for each of them. Problems with the key material of a single zone are reported in ZoneDNSSecReport.Error.
+func GetDNSSecReport(ctx context.Context, client DNS, zones []string, opts DNSSecReportOptions) (*DNSSecReport, error) {
+	status, err := client.GetZonesDNSSecStatus(ctx, GetZonesDNSSecStatusRequest{Zones: zones})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGetDNSSecReport, err)
+	}
+
+	if len(opts.DigestTypes) == 0 {
+		opts.DigestTypes = []DSDigestType{DSDigestSHA256, DSDigestSHA384}
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	report := DNSSecReport{Zones: make([]ZoneDNSSecReport, 0, len(status.DNSSecStatuses))}
+	for _, s := range status.DNSSecStatuses {
+		report.Zones = append(report.Zones, newZoneDNSSecReport(s, opts))
+	}
+
+	return &report, nil
+}
+
+func newZoneDNSSecReport(status SecStatus, opts DNSSecReportOptions) ZoneDNSSecReport {
+	report := ZoneDNSSecReport{
+		Zone:   status.Zone,
+		Alerts: status.Alerts,
+	}
+
+	current, err := newDNSSecKeyReport(status.CurrentRecords, opts.DigestTypes)
+	if err != nil {
+		report.Error = fmt.Errorf("current records: %w", err)
+		return report
+	}
+	report.Current = current
+	report.RegistrarDS = append(report.RegistrarDS, current.DS...)
+
+	if opts.KeyMaxAge > 0 && !current.LastModifiedDate.IsZero() {
+		report.KeyExpiresAt = current.LastModifiedDate.Add(opts.KeyMaxAge)
+		report.KeyExpiring = !opts.Now().Add(opts.ExpiryWarning).Before(report.KeyExpiresAt)
+	}
+
+	if status.NewRecords != nil {
+		next, err := newDNSSecKeyReport(*status.NewRecords, opts.DigestTypes)
+		if err != nil {
+			report.Error = fmt.Errorf("new records: %w", err)
+			return report
+		}
+		report.New = next
+		report.RolloverPending = next.DNSKey.KeyTag() != current.DNSKey.KeyTag()
+		if report.RolloverPending {
+			// both keys have to be trusted by the parent zone until the rollover completes
+			report.RegistrarDS = append(report.RegistrarDS, next.DS...)
+		}
+	}
+
+	return report
+}
+
+func newDNSSecKeyReport(records SecRecords, digestTypes []DSDigestType) (*DNSSecKeyReport, error) {
+	key, err := ParseDNSKeyRecord(records.DNSKeyRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	report := DNSSecKeyReport{
+		DNSKey:           *key,
+		ExpectedTTL:      records.ExpectedTTL,
+		LastModifiedDate: records.LastModifiedDate,
+	}
+	for _, digestType := range digestTypes {
+		ds, err := key.DS(digestType)
+		if err != nil {
+			return nil, err
+		}
+		report.DS = append(report.DS, *ds)
+	}
+
+	if records.DSRecord != "" {
+		published, err := ParseDSRecord(records.DSRecord)
+		if err != nil {
+			return nil, err
+		}
+		report.PublishedDS = published
+		if ds, err := key.DS(published.DigestType); err == nil {
+			report.DSMatches = ds.Equal(*published)
+		}
+	}
+
+	return &report, nil
+}
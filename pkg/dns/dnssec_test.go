@@ -0,0 +1,304 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// key from RFC 4509, section 2.3
+const (
+	testDNSKeyZSK = "dskey.example.com. 86400 IN DNSKEY 256 3 5 ( AQOeiiR0GOMYkDshWoSKz9Xz fwJr1AYtsmx3TGkJaNXVbfi/ " +
+		"2pHm822aJ5iI9BMzNXxeYCmZ DRD99WYwYqUSdjMmmAphXdvx egXd/M5+X7OrzKBaMbCVdFLU Uh6DhweJBjEVv5f2wwjM9Xzc " +
+		"nOf+EPbtG9DMBmADjFDc2w/r ljwvFw== ) "
+	testDNSKeyKSK = "dskey.example.com. 86400 IN DNSKEY 257 3 5 (AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/" +
+		"2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9Xzc" +
+		"nOf+EPbtG9DMBmADjFDc2w/rljwvFw== ) "
+	testDSZSK = "dskey.example.com. 86400 IN DS 60485 5 2 ( D4B7D520E7BB5F0F67674A0C CEB1E3E0614B93C4F9E99B83 83F6A1E4469DA50A ) "
+	testDSKSK = "dskey.example.com. 86400 IN DS 60486 5 2 ( A0091ADB6848CA53BA2EE803C283F76C32E8A4ECFAFE9B50EF143E18B7E7539D ) "
+)
+
+func TestParseDNSKeyRecord(t *testing.T) {
+	tests := map[string]struct {
+		record    string
+		expected  *DNSKey
+		keyTag    uint16
+		withError string
+	}{
+		"multi-line key": {
+			record: testDNSKeyZSK,
+			expected: &DNSKey{
+				Owner:     "dskey.example.com.",
+				TTL:       86400,
+				Flags:     256,
+				Protocol:  3,
+				Algorithm: 5,
+			},
+			keyTag: 60485,
+		},
+		"key signing key": {
+			record: testDNSKeyKSK,
+			expected: &DNSKey{
+				Owner:     "dskey.example.com.",
+				TTL:       86400,
+				Flags:     257,
+				Protocol:  3,
+				Algorithm: 5,
+			},
+			keyTag: 60486,
+		},
+		"no TTL and class": {
+			record: "dskey.example.com. DNSKEY 256 3 5 AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZ" +
+				"DRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw==",
+			expected: &DNSKey{
+				Owner:     "dskey.example.com.",
+				Flags:     256,
+				Protocol:  3,
+				Algorithm: 5,
+			},
+			keyTag: 60485,
+		},
+		"not a DNSKEY record": {
+			record:    testDSZSK,
+			withError: "no owner name or DNSKEY type found",
+		},
+		"missing public key": {
+			record:    "example.com. 7200 IN DNSKEY 257 3 13",
+			withError: "expected flags, protocol, algorithm and public key",
+		},
+		"invalid flags": {
+			record:    "example.com. 7200 IN DNSKEY abc 3 13 ( AAAA )",
+			withError: "invalid flags",
+		},
+		"invalid public key": {
+			record:    "foo.test.net. 7200 IN DNSKEY 257 3 7 (DUMMY_HASH_2 ) ",
+			withError: "invalid public key",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := ParseDNSKeyRecord(test.record)
+			if test.withError != "" {
+				assert.True(t, errors.Is(err, ErrParseDNSKey), "want: %s; got: %s", ErrParseDNSKey, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.keyTag, key.KeyTag())
+			assert.Equal(t, test.expected.Flags == 257, key.IsKSK())
+			assert.Len(t, key.PublicKey, 130)
+			key.PublicKey = nil
+			assert.Equal(t, test.expected, key)
+		})
+	}
+}
+
+func TestParseDSRecord(t *testing.T) {
+	ds, err := ParseDSRecord(testDSZSK)
+	require.NoError(t, err)
+	assert.Equal(t, &DSRecord{
+		Owner:      "dskey.example.com.",
+		TTL:        86400,
+		KeyTag:     60485,
+		Algorithm:  5,
+		DigestType: DSDigestSHA256,
+		Digest:     "D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A",
+	}, ds)
+	assert.Equal(t, "dskey.example.com. 86400 IN DS 60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A", ds.String())
+
+	_, err = ParseDSRecord("foo.test.net. 86400 IN DS 42061 7 2 ( DUMMY_HASH_1 ) ")
+	assert.True(t, errors.Is(err, ErrParseDS), "want: %s; got: %s", ErrParseDS, err)
+	assert.Contains(t, err.Error(), "invalid digest")
+}
+
+func TestDNSKey_DS(t *testing.T) {
+	key, err := ParseDNSKeyRecord(testDNSKeyZSK)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		digestType DSDigestType
+		expected   string
+		withError  error
+	}{
+		"SHA-256": {
+			digestType: DSDigestSHA256,
+			expected:   "dskey.example.com. 86400 IN DS 60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A",
+		},
+		"SHA-384": {
+			digestType: DSDigestSHA384,
+			expected: "dskey.example.com. 86400 IN DS 60485 5 4 " +
+				"AB64DBEBE13C0B6BAE558B78CCAB93B836F8ADA4CBED2D4484A8715A819DE7B9E846315E70EA5D884B377394BDAF16A3",
+		},
+		"SHA-1 is not supported": {
+			digestType: DSDigestSHA1,
+			withError:  ErrUnsupportedDigestType,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ds, err := key.DS(test.digestType)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ds.String())
+		})
+	}
+
+	t.Run("owner name is case insensitive", func(t *testing.T) {
+		upper := *key
+		upper.Owner = "DSKEY.Example.COM"
+		ds, err := upper.DS(DSDigestSHA256)
+		require.NoError(t, err)
+		expected, err := ParseDSRecord(testDSZSK)
+		require.NoError(t, err)
+		assert.True(t, ds.Equal(*expected))
+	})
+}
+
+func TestGetDNSSecReport(t *testing.T) {
+	now := test.NewTimeFromString(t, "2024-06-01T00:00:00Z")
+	tests := map[string]struct {
+		zones        []string
+		opts         DNSSecReportOptions
+		responseBody string
+		expected     func(*testing.T, *DNSSecReport)
+		withError    error
+	}{
+		"current key only, expiring": {
+			zones: []string{"dskey.example.com"},
+			opts: DNSSecReportOptions{
+				DigestTypes:   []DSDigestType{DSDigestSHA256},
+				KeyMaxAge:     365 * 24 * time.Hour,
+				ExpiryWarning: 30 * 24 * time.Hour,
+				Now:           func() time.Time { return now },
+			},
+			responseBody: `
+			{
+				"dnsSecStatuses": [
+					{
+						"zone": "dskey.example.com",
+						"alerts": ["PARENT_DS_MISSING"],
+						"currentRecords": {
+							"dnskeyRecord": "` + testDNSKeyZSK + `",
+							"dsRecord": "` + testDSZSK + `",
+							"expectedTtl": 3600,
+							"lastModifiedDate": "2023-06-20T00:00:00Z"
+						}
+					}
+				]
+			}`,
+			expected: func(t *testing.T, report *DNSSecReport) {
+				require.Len(t, report.Zones, 1)
+				zone := report.Zones[0]
+				require.NoError(t, zone.Error)
+				assert.Equal(t, "dskey.example.com", zone.Zone)
+				assert.Equal(t, []string{"PARENT_DS_MISSING"}, zone.Alerts)
+				assert.True(t, zone.Current.DSMatches)
+				assert.Nil(t, zone.New)
+				assert.False(t, zone.RolloverPending)
+				assert.True(t, zone.KeyExpiring)
+				assert.Equal(t, test.NewTimeFromString(t, "2024-06-19T00:00:00Z"), zone.KeyExpiresAt)
+				require.Len(t, zone.RegistrarDS, 1)
+				assert.Equal(t, uint16(60485), zone.RegistrarDS[0].KeyTag)
+			},
+		},
+		"rollover pending": {
+			zones: []string{"dskey.example.com"},
+			opts:  DNSSecReportOptions{Now: func() time.Time { return now }},
+			responseBody: `
+			{
+				"dnsSecStatuses": [
+					{
+						"zone": "dskey.example.com",
+						"alerts": [],
+						"currentRecords": {
+							"dnskeyRecord": "` + testDNSKeyZSK + `",
+							"dsRecord": "` + testDSKSK + `",
+							"expectedTtl": 3600,
+							"lastModifiedDate": "2023-06-20T00:00:00Z"
+						},
+						"newRecords": {
+							"dnskeyRecord": "` + testDNSKeyKSK + `",
+							"dsRecord": "` + testDSKSK + `",
+							"expectedTtl": 3600,
+							"lastModifiedDate": "2024-05-20T00:00:00Z"
+						}
+					}
+				]
+			}`,
+			expected: func(t *testing.T, report *DNSSecReport) {
+				require.Len(t, report.Zones, 1)
+				zone := report.Zones[0]
+				require.NoError(t, zone.Error)
+				assert.False(t, zone.Current.DSMatches)
+				assert.True(t, zone.New.DSMatches)
+				assert.True(t, zone.RolloverPending)
+				assert.False(t, zone.KeyExpiring)
+				assert.True(t, zone.KeyExpiresAt.IsZero())
+				require.Len(t, zone.RegistrarDS, 4)
+				assert.Equal(t, DSDigestSHA256, zone.RegistrarDS[0].DigestType)
+				assert.Equal(t, DSDigestSHA384, zone.RegistrarDS[1].DigestType)
+				assert.Equal(t, uint16(60486), zone.RegistrarDS[2].KeyTag)
+			},
+		},
+		"invalid key material is reported per zone": {
+			zones: []string{"foo.test.net"},
+			responseBody: `
+			{
+				"dnsSecStatuses": [
+					{
+						"zone": "foo.test.net",
+						"alerts": [],
+						"currentRecords": {
+							"dnskeyRecord": "foo.test.net. 7200 IN DNSKEY 257 3 7 (DUMMY_HASH_2 ) ",
+							"dsRecord": "foo.test.net. 86400 IN DS 42061 7 2 ( DUMMY_HASH_1 ) ",
+							"expectedTtl": 0,
+							"lastModifiedDate": "2024-05-28T06:58:26Z"
+						}
+					}
+				]
+			}`,
+			expected: func(t *testing.T, report *DNSSecReport) {
+				require.Len(t, report.Zones, 1)
+				assert.True(t, errors.Is(report.Zones[0].Error, ErrParseDNSKey))
+				assert.Nil(t, report.Zones[0].Current)
+			},
+		},
+		"validation error": {
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/config-dns/v2/zones/dns-sec-status", r.URL.String())
+				assert.Equal(t, http.MethodPost, r.Method)
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			}))
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+			report, err := GetDNSSecReport(context.Background(), client, test.zones, test.opts)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.True(t, errors.Is(err, ErrGetDNSSecReport), "want: %s; got: %s", ErrGetDNSSecReport, err)
+				return
+			}
+			require.NoError(t, err)
+			test.expected(t, report)
+		})
+	}
+}
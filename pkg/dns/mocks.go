@@ -79,6 +79,18 @@ func (d *Mock) SubmitChangeList(ctx context.Context, req SubmitChangeListRequest
 	return args.Error(0)
 }
 
+func (d *Mock) AddChangeListChange(ctx context.Context, req AddChangeListChangeRequest) error {
+	args := d.Called(ctx, req)
+
+	return args.Error(0)
+}
+
+func (d *Mock) DeleteChangeList(ctx context.Context, req DeleteChangeListRequest) error {
+	args := d.Called(ctx, req)
+
+	return args.Error(0)
+}
+
 func (d *Mock) UpdateZone(ctx context.Context, req UpdateZoneRequest) error {
 	args := d.Called(ctx, req)
 
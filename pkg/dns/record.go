@@ -60,7 +60,7 @@ var (
 func (r CreateRecordRequest) Validate() error {
 	return edgegriderr.ParseValidationErrors(validation.Errors{
 		"Zone":   validation.Validate(r.Zone, validation.Required),
-		"Record": validation.Validate(r.Record, validation.Required),
+		"Record": validation.Validate(r.Record, validation.Required, validation.By(recordInZone(r.Zone))),
 	})
 }
 
@@ -68,7 +68,7 @@ func (r CreateRecordRequest) Validate() error {
 func (r UpdateRecordRequest) Validate() error {
 	return edgegriderr.ParseValidationErrors(validation.Errors{
 		"Zone":   validation.Validate(r.Zone, validation.Required),
-		"Record": validation.Validate(r.Record, validation.Required),
+		"Record": validation.Validate(r.Record, validation.Required, validation.By(recordInZone(r.Zone))),
 	})
 }
 
@@ -0,0 +1,370 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	// maxRecordTTL is the maximum TTL allowed by RFC 2181
+	maxRecordTTL = 2147483647
+	// maxTXTChunkLength is the maximum length of a single TXT character string
+	maxTXTChunkLength = 255
+	// maxDomainNameLength is the maximum length of a domain name in presentation format
+	maxDomainNameLength = 253
+	// maxLabelLength is the maximum length of a single domain name label
+	maxLabelLength = 63
+)
+
+var caaTagRegexp = regexp.MustCompile(`^[a-zA-Z0-9]{1,15}$`)
+
+// ValidateRecordSet validates the record set before submission: name has to be within the zone apex
+// (checked only if zone is not empty), TTL has to be within bounds and rdata has to be valid for the record type.
+func ValidateRecordSet(zone string, rs RecordSet) error {
+	return edgegriderr.ParseValidationErrors(recordSetValidationErrors(zone, rs))
+}
+
+// ValidateRecordSets validates each record set as ValidateRecordSet does, and additionally checks rules
+// spanning multiple record sets: record sets have to be unique and CNAME can't coexist with other types of the same name.
+// It can be used for validation of records imported from a zone file before they are submitted.
+func ValidateRecordSets(zone string, recordSets []RecordSet) error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"RecordSets": recordSetsValidationErrors(zone, recordSets).Filter(),
+	})
+}
+
+// ValidateForZone validates the record as ValidateRecordSet does
+func (rec *RecordBody) ValidateForZone(zone string) error {
+	return ValidateRecordSet(zone, rec.recordSet())
+}
+
+func (rec *RecordBody) recordSet() RecordSet {
+	return RecordSet{
+		Name:  rec.Name,
+		Type:  rec.RecordType,
+		TTL:   rec.TTL,
+		Rdata: rec.Target,
+	}
+}
+
+// recordInZone returns a rule validating *RecordBody for the given zone
+func recordInZone(zone string) validation.RuleFunc {
+	return func(value interface{}) error {
+		rec, ok := value.(*RecordBody)
+		if !ok || rec == nil {
+			return nil
+		}
+		return recordSetValidationErrors(zone, rec.recordSet()).Filter()
+	}
+}
+
+// recordSetsInZone returns a rule validating *RecordSets for the given zone
+func recordSetsInZone(zone string) validation.RuleFunc {
+	return func(value interface{}) error {
+		rs, ok := value.(*RecordSets)
+		if !ok || rs == nil {
+			return nil
+		}
+		return recordSetsValidationErrors(zone, rs.RecordSets).Filter()
+	}
+}
+
+func recordSetValidationErrors(zone string, rs RecordSet) validation.Errors {
+	return validation.Errors{
+		"Name":  validation.Validate(rs.Name, validation.Required, validation.By(nameInZone(zone))),
+		"Type":  validation.Validate(rs.Type, validation.Required),
+		"TTL":   validation.Validate(rs.TTL, validation.Required, validation.Min(1), validation.Max(maxRecordTTL)),
+		"Rdata": validation.Validate(rs.Rdata, validation.Required, validation.By(rdataRule(zone, rs))),
+	}
+}
+
+func recordSetsValidationErrors(zone string, recordSets []RecordSet) validation.Errors {
+	errs := validation.Errors{}
+	types := make(map[string][]int)
+	for i, rs := range recordSets {
+		if err := recordSetValidationErrors(zone, rs).Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+		name := normalizeName(rs.Name)
+		types[name] = append(types[name], i)
+	}
+
+	addError := func(i int, field string, err error) {
+		key := strconv.Itoa(i)
+		existing, _ := errs[key].(validation.Errors)
+		if existing == nil {
+			existing = validation.Errors{}
+			errs[key] = existing
+		}
+		if existing[field] == nil {
+			existing[field] = err
+		}
+	}
+	for name, indexes := range types {
+		seen := make(map[string]int)
+		for _, i := range indexes {
+			recordType := strings.ToUpper(recordSets[i].Type)
+			if first, ok := seen[recordType]; ok {
+				addError(i, "Type", fmt.Errorf("duplicate %s record set for name %s, already defined at index %d", recordType, name, first))
+				continue
+			}
+			seen[recordType] = i
+		}
+		if _, ok := seen["CNAME"]; ok && len(seen) > 1 {
+			for _, i := range indexes {
+				if strings.EqualFold(recordSets[i].Type, "CNAME") {
+					addError(i, "Type", fmt.Errorf("CNAME record can't coexist with other record types for name %s", name))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// nameInZone returns a rule checking that the name is the zone apex or its subdomain
+func nameInZone(zone string) validation.RuleFunc {
+	return func(value interface{}) error {
+		name, _ := value.(string)
+		if err := validateDomainName(name); err != nil {
+			return err
+		}
+		if zone == "" {
+			return nil
+		}
+		n, z := normalizeName(name), normalizeName(zone)
+		if n != z && !strings.HasSuffix(n, "."+z) {
+			return fmt.Errorf("name %s is outside of zone %s", name, zone)
+		}
+		return nil
+	}
+}
+
+// rdataRule returns a rule validating rdata according to the record type
+func rdataRule(zone string, rs RecordSet) validation.RuleFunc {
+	return func(value interface{}) error {
+		rdata, _ := value.([]string)
+		recordType := strings.ToUpper(rs.Type)
+
+		var validate func(string) error
+		switch recordType {
+		case "A":
+			validate = validateIPv4
+		case "AAAA":
+			validate = validateIPv6
+		case "CNAME":
+			if len(rdata) > 1 {
+				return errors.New("CNAME record set can contain only a single target")
+			}
+			if zone != "" && normalizeName(rs.Name) == normalizeName(zone) {
+				return errors.New("CNAME record can't be created at the zone apex")
+			}
+			validate = validateDomainName
+		case "MX":
+			validate = validateMX
+		case "SRV":
+			validate = validateSRV
+		case "CAA":
+			validate = validateCAA
+		case "TXT", "SPF":
+			validate = validateTXT
+		default:
+			return nil
+		}
+
+		for _, r := range rdata {
+			if err := validate(r); err != nil {
+				return fmt.Errorf("invalid %s rdata %q: %w", recordType, r, err)
+			}
+		}
+		return nil
+	}
+}
+
+func validateIPv4(value string) error {
+	if ip := net.ParseIP(value); ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+		return errors.New("not a valid IPv4 address")
+	}
+	return nil
+}
+
+func validateIPv6(value string) error {
+	if ip := net.ParseIP(value); ip == nil || !strings.Contains(value, ":") {
+		return errors.New("not a valid IPv6 address")
+	}
+	return nil
+}
+
+// validateDomainName checks label and name lengths, it allows wildcard and underscore labels
+func validateDomainName(name string) error {
+	if name == "." {
+		return nil
+	}
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return errors.New("empty domain name")
+	}
+	if len(name) > maxDomainNameLength {
+		return fmt.Errorf("domain name is longer than %d characters", maxDomainNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return errors.New("domain name contains an empty label")
+		}
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("label %s is longer than %d characters", label, maxLabelLength)
+		}
+		if strings.ContainsAny(label, " \t\"") {
+			return fmt.Errorf("label %q contains whitespace or quotes", label)
+		}
+	}
+	return nil
+}
+
+func validateUint16Field(name, value string) error {
+	if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+		return fmt.Errorf("%s must be a number between 0 and 65535", name)
+	}
+	return nil
+}
+
+func validateMX(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return errors.New("expected format is '<preference> <exchange>'")
+	}
+	if err := validateUint16Field("preference", fields[0]); err != nil {
+		return err
+	}
+	return validateDomainName(fields[1])
+}
+
+func validateSRV(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return errors.New("expected format is '<priority> <weight> <port> <target>'")
+	}
+	for i, name := range []string{"priority", "weight", "port"} {
+		if err := validateUint16Field(name, fields[i]); err != nil {
+			return err
+		}
+	}
+	return validateDomainName(fields[3])
+}
+
+func validateCAA(value string) error {
+	fields := strings.SplitN(strings.TrimSpace(value), " ", 3)
+	if len(fields) != 3 {
+		return errors.New("expected format is '<flags> <tag> <value>'")
+	}
+	if _, err := strconv.ParseUint(fields[0], 10, 8); err != nil {
+		return errors.New("flags must be a number between 0 and 255")
+	}
+	if !caaTagRegexp.MatchString(fields[1]) {
+		return fmt.Errorf("tag %q must consist of 1 to 15 letters and digits", fields[1])
+	}
+	return nil
+}
+
+// validateTXT checks that each quoted character string of TXT rdata does not exceed 255 bytes.
+// Rdata can be either a single unquoted string or a list of quoted strings. Unquoted strings are not checked,
+// as they were accepted by the API regardless of their length before validation was added.
+func validateTXT(value string) error {
+	if !strings.HasPrefix(strings.TrimSpace(value), `"`) {
+		return nil
+	}
+	chunks, err := parseCharacterStrings(value)
+	if err != nil {
+		return err
+	}
+	for i, c := range chunks {
+		if len(c) > maxTXTChunkLength {
+			return fmt.Errorf("character string %d is %d bytes long, strings longer than %d bytes have to be split into multiple quoted strings, see SplitTXT",
+				i, len(c), maxTXTChunkLength)
+		}
+	}
+	return nil
+}
+
+// parseCharacterStrings returns unescaped character strings of TXT rdata
+func parseCharacterStrings(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, `"`) {
+		return []string{value}, nil
+	}
+
+	var chunks []string
+	var current strings.Builder
+	inQuotes := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && inQuotes:
+			if i+3 < len(value) && isDigits(value[i+1:i+4]) {
+				b, err := strconv.ParseUint(value[i+1:i+4], 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("invalid escape sequence \\%s", value[i+1:i+4])
+				}
+				current.WriteByte(byte(b))
+				i += 3
+				continue
+			}
+			if i+1 == len(value) {
+				return nil, errors.New("unterminated escape sequence")
+			}
+			i++
+			current.WriteByte(value[i])
+		case c == '"':
+			if inQuotes {
+				chunks = append(chunks, current.String())
+				current.Reset()
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteByte(c)
+		case c != ' ' && c != '\t':
+			return nil, errors.New("text outside of quoted strings")
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quoted string")
+	}
+	return chunks, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitTXT splits the text into quoted character strings of at most 255 bytes each, escaping quotes and backslashes,
+// so it can be used as TXT record rdata
+func SplitTXT(text string) string {
+	var chunks []string
+	for len(text) > maxTXTChunkLength {
+		chunks = append(chunks, text[:maxTXTChunkLength])
+		text = text[maxTXTChunkLength:]
+	}
+	chunks = append(chunks, text)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, c := range chunks {
+		chunks[i] = `"` + escaper.Replace(c) + `"`
+	}
+	return strings.Join(chunks, " ")
+}
@@ -0,0 +1,237 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRecordSet(t *testing.T) {
+	tests := map[string]struct {
+		zone      string
+		rs        RecordSet
+		withError string
+	}{
+		"valid A": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1", "10.0.0.2"}},
+		},
+		"invalid A": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1", "::1"}},
+			withError: `Rdata: invalid A rdata "::1": not a valid IPv4 address`,
+		},
+		"valid AAAA": {
+			zone: "example.com.",
+			rs:   RecordSet{Name: "WWW.Example.com.", Type: "aaaa", TTL: 300, Rdata: []string{"2001:db8::1"}},
+		},
+		"IPv4 as AAAA": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "www.example.com", Type: "AAAA", TTL: 300, Rdata: []string{"10.0.0.1"}},
+			withError: `Rdata: invalid AAAA rdata "10.0.0.1": not a valid IPv6 address`,
+		},
+		"name outside of zone": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "www.badexample.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+			withError: "Name: name www.badexample.com is outside of zone example.com",
+		},
+		"zone is not checked when empty": {
+			rs: RecordSet{Name: "www.badexample.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+		},
+		"wildcard name": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "*.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+		},
+		"label too long": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: strings.Repeat("a", 64) + ".example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+			withError: "is longer than 63 characters",
+		},
+		"TTL out of bounds": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "www.example.com", Type: "A", TTL: -1, Rdata: []string{"10.0.0.1"}},
+			withError: "TTL: must be no less than 1",
+		},
+		"missing fields": {
+			rs:        RecordSet{},
+			withError: "Name: cannot be blank\nRdata: cannot be blank\nTTL: cannot be blank\nType: cannot be blank",
+		},
+		"CNAME at apex": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "CNAME", TTL: 300, Rdata: []string{"target.example.net."}},
+			withError: "Rdata: CNAME record can't be created at the zone apex",
+		},
+		"CNAME with multiple targets": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"a.example.net.", "b.example.net."}},
+			withError: "Rdata: CNAME record set can contain only a single target",
+		},
+		"valid MX": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "example.com", Type: "MX", TTL: 300, Rdata: []string{"10 mail.example.com.", "0 ."}},
+		},
+		"MX preference out of range": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "MX", TTL: 300, Rdata: []string{"65536 mail.example.com."}},
+			withError: `Rdata: invalid MX rdata "65536 mail.example.com.": preference must be a number between 0 and 65535`,
+		},
+		"MX missing exchange": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "MX", TTL: 300, Rdata: []string{"10"}},
+			withError: "expected format is '<preference> <exchange>'",
+		},
+		"valid SRV": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "_sip._tcp.example.com", Type: "SRV", TTL: 300, Rdata: []string{"10 60 5060 sip.example.com."}},
+		},
+		"SRV weight out of range": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "_sip._tcp.example.com", Type: "SRV", TTL: 300, Rdata: []string{"10 -1 5060 sip.example.com."}},
+			withError: "weight must be a number between 0 and 65535",
+		},
+		"valid CAA": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "example.com", Type: "CAA", TTL: 300, Rdata: []string{`0 issue "letsencrypt.org"`, `128 iodef "mailto:security@example.com"`}},
+		},
+		"invalid CAA tag": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "CAA", TTL: 300, Rdata: []string{`0 issue-wild "letsencrypt.org"`}},
+			withError: `tag "issue-wild" must consist of 1 to 15 letters and digits`,
+		},
+		"invalid CAA flags": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "CAA", TTL: 300, Rdata: []string{`256 issue "letsencrypt.org"`}},
+			withError: "flags must be a number between 0 and 255",
+		},
+		"valid TXT": {
+			zone: "example.com",
+			rs: RecordSet{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{
+				"v=spf1 -all",
+				`"first \"quoted\" chunk" "second\032chunk"`,
+				SplitTXT(strings.Repeat("x", 600)),
+			}},
+		},
+		"TXT chunk too long": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{`"short" "` + strings.Repeat("x", 256) + `"`}},
+			withError: "character string 1 is 256 bytes long",
+		},
+		"unquoted long TXT": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{strings.Repeat("x", 256)}},
+		},
+		"unterminated TXT string": {
+			zone:      "example.com",
+			rs:        RecordSet{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{`"abc" "def`}},
+			withError: "unterminated quoted string",
+		},
+		"other types are not checked": {
+			zone: "example.com",
+			rs:   RecordSet{Name: "example.com", Type: "AKAMAICDN", TTL: 20, Rdata: []string{"www.example.com.edgekey.net"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateRecordSet(test.zone, test.rs)
+			if test.withError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateRecordSets(t *testing.T) {
+	err := ValidateRecordSets("example.com", []RecordSet{
+		{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+		{Name: "www.example.com.", Type: "CNAME", TTL: 300, Rdata: []string{"target.example.net."}},
+		{Name: "mail.example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.2"}},
+		{Name: "mail.example.com", Type: "a", TTL: 300, Rdata: []string{"10.0.0.3"}},
+		{Name: "foo.example.net", Type: "TXT", TTL: 0, Rdata: []string{"text"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, `RecordSets[1]: {
+	Type: CNAME record can't coexist with other record types for name www.example.com
+}
+RecordSets[3]: {
+	Type: duplicate A record set for name mail.example.com, already defined at index 2
+}
+RecordSets[4]: {
+	Name: name foo.example.net is outside of zone example.com
+	TTL: cannot be blank
+}`, err.Error())
+
+	assert.NoError(t, ValidateRecordSets("example.com", []RecordSet{
+		{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"target.example.net."}},
+		{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"10.0.0.1"}},
+		{Name: "example.com", Type: "AAAA", TTL: 300, Rdata: []string{"2001:db8::1"}},
+	}))
+}
+
+func TestRecordBody_ValidateForZone(t *testing.T) {
+	rec := RecordBody{Name: "www.example.com", RecordType: "A", TTL: 300, Target: []string{"10.0.0.300"}}
+	err := rec.ValidateForZone("example.com")
+	require.Error(t, err)
+	assert.Equal(t, `Rdata: invalid A rdata "10.0.0.300": not a valid IPv4 address`, err.Error())
+}
+
+func TestSplitTXT(t *testing.T) {
+	assert.Equal(t, `"v=spf1 include:\"x\" \\ -all"`, SplitTXT(`v=spf1 include:"x" \ -all`))
+
+	split := SplitTXT(strings.Repeat("a", 255) + strings.Repeat("b", 10))
+	assert.Equal(t, `"`+strings.Repeat("a", 255)+`" "`+strings.Repeat("b", 10)+`"`, split)
+
+	chunks, err := parseCharacterStrings(SplitTXT(`a"b\c`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`a"b\c`}, chunks)
+}
+
+func TestDNS_RecordValidationBeforeSubmission(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Fail(t, "request should not be sent", "%s %s", r.Method, r.URL)
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	err := client.CreateRecord(context.Background(), CreateRecordRequest{
+		Zone:   "example.com",
+		Record: &RecordBody{Name: "www.example.org", RecordType: "A", TTL: 300, Target: []string{"10.0.0.1"}},
+	})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Equal(t, "create record: struct validation: Name: name www.example.org is outside of zone example.com", err.Error())
+
+	err = client.UpdateRecord(context.Background(), UpdateRecordRequest{
+		Zone:   "example.com",
+		Record: &RecordBody{Name: "www.example.com", RecordType: "MX", TTL: 300, Target: []string{"mail.example.com."}},
+	})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "expected format is '<preference> <exchange>'")
+
+	err = client.CreateRecordSets(context.Background(), CreateRecordSetsRequest{
+		Zone: "example.com",
+		RecordSets: &RecordSets{RecordSets: []RecordSet{
+			{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"target.example.net."}},
+			{Name: "www.example.com", Type: "TXT", TTL: 300, Rdata: []string{"text"}},
+		}},
+	})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "RecordSets[0]: {\n\tType: CNAME record can't coexist with other record types for name www.example.com\n}")
+
+	err = client.UpdateRecordSets(context.Background(), UpdateRecordSetsRequest{
+		Zone: "example.com",
+		RecordSets: &RecordSets{RecordSets: []RecordSet{
+			{Name: "www.example.com", Type: "SRV", TTL: 300, Rdata: []string{"1 2 70000 target.example.com."}},
+		}},
+	})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "port must be a number between 0 and 65535")
+}
@@ -96,7 +96,7 @@ func (r GetRecordSetsRequest) Validate() error {
 func (r CreateRecordSetsRequest) Validate() error {
 	return edgegriderr.ParseValidationErrors(validation.Errors{
 		"Zone":       validation.Validate(r.Zone, validation.Required),
-		"RecordSets": validation.Validate(r.RecordSets, validation.Required),
+		"RecordSets": validation.Validate(r.RecordSets, validation.Required, validation.By(recordSetsInZone(r.Zone))),
 	})
 }
 
@@ -104,7 +104,7 @@ func (r CreateRecordSetsRequest) Validate() error {
 func (r UpdateRecordSetsRequest) Validate() error {
 	return edgegriderr.ParseValidationErrors(validation.Errors{
 		"Zone":       validation.Validate(r.Zone, validation.Required),
-		"RecordSets": validation.Validate(r.RecordSets, validation.Required),
+		"RecordSets": validation.Validate(r.RecordSets, validation.Required, validation.By(recordSetsInZone(r.Zone))),
 	})
 }
 
@@ -0,0 +1,190 @@
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// TSIGRotationOptions contains options for RotateTSIGKey
+	TSIGRotationOptions struct {
+		// Name is the name of the new key. Defaults to the name of the current key
+		Name string
+		// Algorithm is the algorithm of the new key. Defaults to the algorithm of the current key
+		Algorithm string
+		// Rand is the source of randomness used for secret generation. Defaults to crypto/rand.Reader
+		Rand io.Reader
+	}
+
+	// TSIGRotationResult contains the outcome of TSIG key rotation
+	TSIGRotationResult struct {
+		OldKey TSIGKey
+		NewKey TSIGKey
+		// Zones lists zones updated with the new key
+		Zones []string
+		// Aliases lists aliases of the updated zones, sorted
+		Aliases []string
+		// Failed lists zones which do not use the new key after the update, mapped to the reason
+		Failed map[string]string
+	}
+)
+
+var (
+	// ErrRotateTSIGKey is returned when RotateTSIGKey fails
+	ErrRotateTSIGKey = errors.New("rotate tsig key")
+	// ErrGenerateTSIGKey is returned when GenerateTSIGKey fails
+	ErrGenerateTSIGKey = errors.New("generate tsig key")
+	// ErrTSIGKeyVerification is returned when some zones do not use the new key after rotation
+	ErrTSIGKeyVerification = errors.New("tsig key verification")
+
+	// tsigSecretSizes maps supported TSIG algorithms to secret sizes matching their digest length
+	tsigSecretSizes = map[string]int{
+		"hmac-md5.sig-alg.reg.int": 16,
+		"hmac-sha1":                20,
+		"hmac-sha224":              28,
+		"hmac-sha256":              32,
+		"hmac-sha384":              48,
+		"hmac-sha512":              64,
+	}
+)
+
+// GenerateTSIGKey generates a TSIG key with a random secret of the algorithm's digest length.
+// If random is nil, crypto/rand.Reader is used.
+func GenerateTSIGKey(name, algorithm string, random io.Reader) (*TSIGKey, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrGenerateTSIGKey)
+	}
+	size, ok := tsigSecretSizes[strings.ToLower(algorithm)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrGenerateTSIGKey, algorithm)
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+
+	secret := make([]byte, size)
+	if _, err := io.ReadFull(random, secret); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGenerateTSIGKey, err)
+	}
+
+	return &TSIGKey{
+		Name:      name,
+		Algorithm: strings.ToLower(algorithm),
+		Secret:    base64.StdEncoding.EncodeToString(secret),
+	}, nil
+}
+
+// BINDConfig returns the key as a BIND key statement, to be included in named.conf of primary servers
+func (key TSIGKey) BINDConfig() string {
+	return fmt.Sprintf("key \"%s\" {\n\talgorithm %s;\n\tsecret \"%s\";\n};\n", key.Name, key.Algorithm, key.Secret)
+}
+
+// RotateTSIGKey replaces the TSIG key used by the given zone with a newly generated one.
+// The new key is applied to every zone sharing the current key using a single bulk update,
+// after which each zone is verified to use the new key. Aliases of each zone are looked up with GetTSIGKeyAliases
+// before the update, as they share the key of their zone.
+//
+// ErrTSIGKeyVerification is returned together with the result if some zones were not updated, see TSIGRotationResult.Failed.
+func RotateTSIGKey(ctx context.Context, client DNS, zone string, opts TSIGRotationOptions) (*TSIGRotationResult, error) {
+	current, err := client.GetTSIGKey(ctx, GetTSIGKeyRequest{Zone: zone})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+
+	name, algorithm := opts.Name, opts.Algorithm
+	if name == "" {
+		name = current.Name
+	}
+	if algorithm == "" {
+		algorithm = current.Algorithm
+	}
+	newKey, err := GenerateTSIGKey(name, algorithm, opts.Rand)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+
+	oldKey := current.TSIGKey
+	used, err := client.GetTSIGKeyZones(ctx, GetTSIGKeyZonesRequest{TsigKey: &oldKey})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+	zones := used.Zones
+	if len(zones) == 0 {
+		zones = []string{zone}
+	}
+
+	aliases, err := tsigKeyAliases(ctx, client, zones)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+
+	err = client.UpdateTSIGKeyBulk(ctx, UpdateTSIGKeyBulkRequest{
+		TSIGKeyBulk: &TSIGKeyBulkPost{Key: newKey, Zones: zones},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+
+	result := TSIGRotationResult{
+		OldKey:  oldKey,
+		NewKey:  *newKey,
+		Zones:   zones,
+		Aliases: aliases,
+	}
+	for _, z := range zones {
+		if reason := verifyTSIGKey(ctx, client, z, *newKey); reason != "" {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[z] = reason
+		}
+	}
+	if len(result.Failed) > 0 {
+		return &result, fmt.Errorf("%w: %d of %d zones do not use the new key", ErrTSIGKeyVerification, len(result.Failed), len(zones))
+	}
+
+	return &result, nil
+}
+
+// tsigKeyAliases returns sorted aliases of all zones
+func tsigKeyAliases(ctx context.Context, client DNS, zones []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var aliases []string
+	for _, zone := range zones {
+		resp, err := client.GetTSIGKeyAliases(ctx, GetTSIGKeyAliasesRequest{Zone: zone})
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone, err)
+		}
+		for _, alias := range resp.Aliases {
+			if !seen[alias] {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	sort.Strings(aliases)
+	return aliases, nil
+}
+
+// verifyTSIGKey returns the reason why the zone does not use the expected key, or empty string if it does
+func verifyTSIGKey(ctx context.Context, client DNS, zone string, expected TSIGKey) string {
+	actual, err := client.GetTSIGKey(ctx, GetTSIGKeyRequest{Zone: zone})
+	if err != nil {
+		return err.Error()
+	}
+	switch {
+	case actual.Name != expected.Name:
+		return fmt.Sprintf("key name is %q", actual.Name)
+	case !strings.EqualFold(actual.Algorithm, expected.Algorithm):
+		return fmt.Sprintf("key algorithm is %q", actual.Algorithm)
+	case actual.Secret != "" && actual.Secret != expected.Secret:
+		return "key secret differs"
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTSIGKey(t *testing.T) {
+	tests := map[string]struct {
+		name      string
+		algorithm string
+		random    []byte
+		expected  *TSIGKey
+		withError string
+	}{
+		"hmac-sha256": {
+			name:      "example.com.akamai.com.",
+			algorithm: "HMAC-SHA256",
+			random:    bytes.Repeat([]byte{0xAB}, 64),
+			expected: &TSIGKey{
+				Name:      "example.com.akamai.com.",
+				Algorithm: "hmac-sha256",
+				Secret:    "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=",
+			},
+		},
+		"hmac-md5": {
+			name:      "key",
+			algorithm: "hmac-md5.sig-alg.reg.int",
+			random:    bytes.Repeat([]byte{0x01}, 64),
+			expected: &TSIGKey{
+				Name:      "key",
+				Algorithm: "hmac-md5.sig-alg.reg.int",
+				Secret:    "AQEBAQEBAQEBAQEBAQEBAQ==",
+			},
+		},
+		"unsupported algorithm": {
+			name:      "key",
+			algorithm: "hmac-sha3",
+			withError: `unsupported algorithm "hmac-sha3"`,
+		},
+		"missing name": {
+			algorithm: "hmac-sha256",
+			withError: "name is required",
+		},
+		"not enough randomness": {
+			name:      "key",
+			algorithm: "hmac-sha512",
+			random:    []byte{0x01},
+			withError: "unexpected EOF",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := GenerateTSIGKey(test.name, test.algorithm, bytes.NewReader(test.random))
+			if test.withError != "" {
+				assert.True(t, errors.Is(err, ErrGenerateTSIGKey), "want: %s; got: %s", ErrGenerateTSIGKey, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, key)
+		})
+	}
+
+	t.Run("default random source", func(t *testing.T) {
+		first, err := GenerateTSIGKey("key", "hmac-sha512", nil)
+		require.NoError(t, err)
+		second, err := GenerateTSIGKey("key", "hmac-sha512", nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Secret, second.Secret)
+		assert.Len(t, first.Secret, 88)
+	})
+}
+
+func TestTSIGKey_BINDConfig(t *testing.T) {
+	key := TSIGKey{Name: "example.com.akamai.com.", Algorithm: "hmac-sha256", Secret: "c2VjcmV0"}
+	assert.Equal(t, `key "example.com.akamai.com." {
+	algorithm hmac-sha256;
+	secret "c2VjcmV0";
+};
+`, key.BINDConfig())
+}
+
+// fakeTSIGServer keeps TSIG keys and aliases of zones, zones listed in ignored are not changed by bulk updates
+type fakeTSIGServer struct {
+	t       *testing.T
+	mu      sync.Mutex
+	keys    map[string]TSIGKey
+	aliases map[string][]string
+	ignored map[string]bool
+}
+
+func (f *fakeTSIGServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/key/used-by"):
+		zone := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/config-dns/v2/zones/"), "/key/used-by")
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(f.t, json.NewEncoder(w).Encode(GetTSIGKeyAliasesResponse{Zones: []string{zone}, Aliases: f.aliases[zone]}))
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/key"):
+		zone := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/config-dns/v2/zones/"), "/key")
+		key, ok := f.keys[zone]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(notFoundBody))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(f.t, json.NewEncoder(w).Encode(GetTSIGKeyResponse{TSIGKey: key, ZoneCount: 1}))
+	case r.Method == http.MethodPost && r.URL.Path == "/config-dns/v2/keys/used-by":
+		var key TSIGKey
+		assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&key))
+		var resp GetTSIGKeyZonesResponse
+		for _, zone := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+			if f.keys[zone] == key {
+				resp.Zones = append(resp.Zones, zone)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(f.t, json.NewEncoder(w).Encode(resp))
+	case r.Method == http.MethodPost && r.URL.Path == "/config-dns/v2/keys/bulk-update":
+		var bulk TSIGKeyBulkPost
+		assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&bulk))
+		for _, zone := range bulk.Zones {
+			if !f.ignored[zone] {
+				f.keys[zone] = *bulk.Key
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		assert.Fail(f.t, "unexpected request", "%s %s", r.Method, r.URL)
+	}
+}
+
+func TestRotateTSIGKey(t *testing.T) {
+	oldKey := TSIGKey{Name: "shared.", Algorithm: "hmac-sha256", Secret: "b2xkIHNlY3JldA=="}
+	otherKey := TSIGKey{Name: "other.", Algorithm: "hmac-sha256", Secret: "b3RoZXI="}
+
+	tests := map[string]struct {
+		opts           TSIGRotationOptions
+		ignored        map[string]bool
+		expectedKey    TSIGKey
+		expectedFailed map[string]string
+		withError      error
+	}{
+		"rotate shared key": {
+			opts: TSIGRotationOptions{Rand: bytes.NewReader(bytes.Repeat([]byte{0xAB}, 32))},
+			expectedKey: TSIGKey{
+				Name:      "shared.",
+				Algorithm: "hmac-sha256",
+				Secret:    "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=",
+			},
+		},
+		"rotate with new name and algorithm": {
+			opts: TSIGRotationOptions{
+				Name:      "rotated.",
+				Algorithm: "hmac-sha1",
+				Rand:      bytes.NewReader(bytes.Repeat([]byte{0x01}, 20)),
+			},
+			expectedKey: TSIGKey{
+				Name:      "rotated.",
+				Algorithm: "hmac-sha1",
+				Secret:    "AQEBAQEBAQEBAQEBAQEBAQEBAQE=",
+			},
+		},
+		"zone not updated": {
+			opts:    TSIGRotationOptions{Rand: bytes.NewReader(bytes.Repeat([]byte{0xAB}, 32))},
+			ignored: map[string]bool{"b.example.com": true},
+			expectedKey: TSIGKey{
+				Name:      "shared.",
+				Algorithm: "hmac-sha256",
+				Secret:    "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=",
+			},
+			expectedFailed: map[string]string{"b.example.com": "key secret differs"},
+			withError:      ErrTSIGKeyVerification,
+		},
+		"unsupported algorithm": {
+			opts:      TSIGRotationOptions{Algorithm: "hmac-foo"},
+			withError: ErrGenerateTSIGKey,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeTSIGServer{
+				t: t,
+				keys: map[string]TSIGKey{
+					"a.example.com": oldKey,
+					"b.example.com": oldKey,
+					"c.example.com": otherKey,
+				},
+				aliases: map[string][]string{
+					"a.example.com": {"alias-a.example.com", "alias.example.com"},
+					"b.example.com": {"alias.example.com", "alias-b.example.com"},
+					"c.example.com": {"alias-c.example.com"},
+				},
+				ignored: test.ignored,
+			}
+			mockServer := httptest.NewTLSServer(fake)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			result, err := RotateTSIGKey(context.Background(), client, "a.example.com", test.opts)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				if test.expectedFailed == nil {
+					return
+				}
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, oldKey, result.OldKey)
+			assert.Equal(t, test.expectedKey, result.NewKey)
+			assert.Equal(t, []string{"a.example.com", "b.example.com"}, result.Zones)
+			assert.Equal(t, []string{"alias-a.example.com", "alias-b.example.com", "alias.example.com"}, result.Aliases)
+			assert.Equal(t, test.expectedFailed, result.Failed)
+			assert.Equal(t, test.expectedKey, fake.keys["a.example.com"])
+			assert.Equal(t, otherKey, fake.keys["c.example.com"])
+		})
+	}
+}
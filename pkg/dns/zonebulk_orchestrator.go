@@ -0,0 +1,328 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// BulkOptions contains options of bulk zone operations run by CreateZonesInBulk and DeleteZonesInBulk
+	BulkOptions struct {
+		// BatchSize is the maximum number of zones submitted in a single request. Defaults to DefaultBulkBatchSize
+		BatchSize int
+		// PollInterval is the time between subsequent request status checks. Defaults to DefaultBulkPollInterval
+		PollInterval time.Duration
+		// Retry defines how zones which failed are retried
+		Retry BulkRetryPolicy
+		// Progress receives progress events, if set. Events are sent synchronously, so the channel has to be drained
+		Progress chan<- BulkProgressEvent
+	}
+
+	// BulkRetryPolicy defines retries of zones which failed in a bulk request
+	BulkRetryPolicy struct {
+		// MaxAttempts is the maximum number of attempts for each zone, including the first one. Defaults to 1, e.g. no retries
+		MaxAttempts int
+		// Backoff is the time to wait before retrying failed zones. It is doubled after each attempt
+		Backoff time.Duration
+		// Retryable decides if the failed zone should be retried. All failures are retried if not set
+		Retryable func(BulkFailedZone) bool
+	}
+
+	// BulkEventType is a type of BulkProgressEvent
+	BulkEventType string
+
+	// BulkProgressEvent describes progress of a bulk zone operation
+	BulkProgressEvent struct {
+		Type BulkEventType
+		// Attempt is the current attempt, starting from 1
+		Attempt int
+		// Batch is the index of the current batch within the attempt, starting from 1
+		Batch int
+		// Batches is the number of batches in the current attempt
+		Batches   int
+		RequestID string
+		// Zones is the number of zones in the current batch, or in the whole operation for BulkEventComplete
+		Zones     int
+		Succeeded int
+		Failed    int
+	}
+
+	// BulkResult contains aggregated results of a bulk zone operation
+	BulkResult struct {
+		// Succeeded lists zones processed successfully in any attempt
+		Succeeded []string
+		// Failed lists zones which failed in their last attempt
+		Failed []BulkFailedZone
+		// Attempts is the number of attempts made
+		Attempts int
+	}
+
+	// bulkOperation abstracts bulk create and delete endpoints
+	bulkOperation interface {
+		submit(context.Context, []string) (string, error)
+		status(context.Context, string) (*BulkStatusResponse, error)
+		result(context.Context, string) ([]string, []BulkFailedZone, error)
+	}
+
+	bulkCreate struct {
+		client DNS
+		zones  map[string]ZoneCreate
+		query  ZoneQueryString
+	}
+
+	bulkDelete struct {
+		client             DNS
+		bypassSafetyChecks *bool
+	}
+)
+
+const (
+	// BulkEventSubmitted is sent after a batch was submitted
+	BulkEventSubmitted BulkEventType = "SUBMITTED"
+	// BulkEventStatus is sent after each status check of a submitted batch
+	BulkEventStatus BulkEventType = "STATUS"
+	// BulkEventBatchComplete is sent after results of a batch were retrieved
+	BulkEventBatchComplete BulkEventType = "BATCH_COMPLETE"
+	// BulkEventRetry is sent before failed zones are retried
+	BulkEventRetry BulkEventType = "RETRY"
+	// BulkEventComplete is sent when the whole operation is finished
+	BulkEventComplete BulkEventType = "COMPLETE"
+
+	// DefaultBulkBatchSize is the default number of zones submitted in a single bulk request
+	DefaultBulkBatchSize = 1000
+	// DefaultBulkPollInterval is the default interval of bulk request status checks
+	DefaultBulkPollInterval = 10 * time.Second
+)
+
+var (
+	// ErrBulkZonesOperation is returned when CreateZonesInBulk or DeleteZonesInBulk fails
+	ErrBulkZonesOperation = errors.New("bulk zones operation")
+)
+
+// CreateZonesInBulk creates zones using bulk create requests. Zones are split into batches of BulkOptions.BatchSize,
+// each batch is submitted and polled until complete. Zones which failed are retried according to BulkOptions.Retry.
+//
+// The returned result contains zones processed so far also when an error is returned.
+func CreateZonesInBulk(ctx context.Context, client DNS, zones []ZoneCreate, query ZoneQueryString, opts BulkOptions) (*BulkResult, error) {
+	op := bulkCreate{
+		client: client,
+		zones:  make(map[string]ZoneCreate, len(zones)),
+		query:  query,
+	}
+	names := make([]string, 0, len(zones))
+	for _, z := range zones {
+		if _, ok := op.zones[z.Zone]; ok {
+			return nil, fmt.Errorf("%w: duplicate zone %q", ErrBulkZonesOperation, z.Zone)
+		}
+		op.zones[z.Zone] = z
+		names = append(names, z.Zone)
+	}
+
+	return runBulkOperation(ctx, op, names, opts)
+}
+
+// DeleteZonesInBulk deletes zones using bulk delete requests. Zones are split into batches of BulkOptions.BatchSize,
+// each batch is submitted and polled until complete. Zones which failed are retried according to BulkOptions.Retry.
+//
+// The returned result contains zones processed so far also when an error is returned.
+func DeleteZonesInBulk(ctx context.Context, client DNS, zones []string, bypassSafetyChecks *bool, opts BulkOptions) (*BulkResult, error) {
+	return runBulkOperation(ctx, bulkDelete{client: client, bypassSafetyChecks: bypassSafetyChecks}, zones, opts)
+}
+
+func runBulkOperation(ctx context.Context, op bulkOperation, zones []string, opts BulkOptions) (*BulkResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBulkBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultBulkPollInterval
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = 1
+	}
+
+	result := BulkResult{}
+	pending := zones
+	backoff := opts.Retry.Backoff
+	var final []BulkFailedZone
+	for len(pending) > 0 {
+		result.Attempts++
+		if result.Attempts > 1 {
+			if err := notifyBulkProgress(ctx, opts.Progress, BulkProgressEvent{Type: BulkEventRetry, Attempt: result.Attempts, Zones: len(pending)}); err != nil {
+				return &result, fmt.Errorf("%w: %w", ErrBulkZonesOperation, err)
+			}
+			if err := waitBulkInterval(ctx, backoff); err != nil {
+				return &result, fmt.Errorf("%w: %w", ErrBulkZonesOperation, err)
+			}
+			backoff *= 2
+		}
+
+		failed, err := runBulkAttempt(ctx, op, pending, result.Attempts, opts, &result)
+		if err != nil {
+			return &result, fmt.Errorf("%w: %w", ErrBulkZonesOperation, err)
+		}
+
+		pending = nil
+		var retried []BulkFailedZone
+		for _, f := range failed {
+			if result.Attempts < opts.Retry.MaxAttempts && (opts.Retry.Retryable == nil || opts.Retry.Retryable(f)) {
+				pending = append(pending, f.Zone)
+				retried = append(retried, f)
+				continue
+			}
+			final = append(final, f)
+		}
+		// zones which won't be retried stay failed, retried ones are failed until they succeed in a later attempt
+		result.Failed = append(append([]BulkFailedZone(nil), final...), retried...)
+	}
+
+	err := notifyBulkProgress(ctx, opts.Progress, BulkProgressEvent{
+		Type:      BulkEventComplete,
+		Attempt:   result.Attempts,
+		Zones:     len(zones),
+		Succeeded: len(result.Succeeded),
+		Failed:    len(result.Failed),
+	})
+	if err != nil {
+		return &result, fmt.Errorf("%w: %w", ErrBulkZonesOperation, err)
+	}
+
+	return &result, nil
+}
+
+// runBulkAttempt submits all zones in batches and returns zones which failed
+func runBulkAttempt(ctx context.Context, op bulkOperation, zones []string, attempt int, opts BulkOptions, result *BulkResult) ([]BulkFailedZone, error) {
+	batches := (len(zones) + opts.BatchSize - 1) / opts.BatchSize
+	var failed []BulkFailedZone
+	for i := 0; i < batches; i++ {
+		batch := zones[i*opts.BatchSize : min((i+1)*opts.BatchSize, len(zones))]
+		event := BulkProgressEvent{Attempt: attempt, Batch: i + 1, Batches: batches, Zones: len(batch)}
+
+		requestID, err := op.submit(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		event.Type, event.RequestID = BulkEventSubmitted, requestID
+		if err := notifyBulkProgress(ctx, opts.Progress, event); err != nil {
+			return nil, err
+		}
+
+		for {
+			if err := waitBulkInterval(ctx, opts.PollInterval); err != nil {
+				return nil, err
+			}
+			status, err := op.status(ctx, requestID)
+			if err != nil {
+				return nil, err
+			}
+			event.Type, event.Succeeded, event.Failed = BulkEventStatus, status.SuccessCount, status.FailureCount
+			if err := notifyBulkProgress(ctx, opts.Progress, event); err != nil {
+				return nil, err
+			}
+			if status.IsComplete {
+				break
+			}
+		}
+
+		succeeded, batchFailed, err := op.result(ctx, requestID)
+		if err != nil {
+			return nil, err
+		}
+		result.Succeeded = append(result.Succeeded, succeeded...)
+		failed = append(failed, batchFailed...)
+
+		event.Type, event.Succeeded, event.Failed = BulkEventBatchComplete, len(succeeded), len(batchFailed)
+		if err := notifyBulkProgress(ctx, opts.Progress, event); err != nil {
+			return nil, err
+		}
+	}
+
+	return failed, nil
+}
+
+// notifyBulkProgress sends the event to the progress channel unless the context is done
+func notifyBulkProgress(ctx context.Context, progress chan<- BulkProgressEvent, event BulkProgressEvent) error {
+	if progress == nil {
+		return nil
+	}
+	select {
+	case progress <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitBulkInterval waits for the given duration unless the context is done
+func waitBulkInterval(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b bulkCreate) submit(ctx context.Context, zones []string) (string, error) {
+	req := CreateBulkZonesRequest{
+		BulkZones:       &BulkZonesCreate{Zones: make([]ZoneCreate, 0, len(zones))},
+		ZoneQueryString: b.query,
+	}
+	for _, z := range zones {
+		req.BulkZones.Zones = append(req.BulkZones.Zones, b.zones[z])
+	}
+	resp, err := b.client.CreateBulkZones(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.RequestID, nil
+}
+
+func (b bulkCreate) status(ctx context.Context, requestID string) (*BulkStatusResponse, error) {
+	resp, err := b.client.GetBulkZoneCreateStatus(ctx, GetBulkZoneCreateStatusRequest{RequestID: requestID})
+	if err != nil {
+		return nil, err
+	}
+	return (*BulkStatusResponse)(resp), nil
+}
+
+func (b bulkCreate) result(ctx context.Context, requestID string) ([]string, []BulkFailedZone, error) {
+	resp, err := b.client.GetBulkZoneCreateResult(ctx, GetBulkZoneCreateResultRequest{RequestID: requestID})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.SuccessfullyCreatedZones, resp.FailedZones, nil
+}
+
+func (b bulkDelete) submit(ctx context.Context, zones []string) (string, error) {
+	resp, err := b.client.DeleteBulkZones(ctx, DeleteBulkZonesRequest{
+		ZonesList:          &ZoneNameListResponse{Zones: zones},
+		BypassSafetyChecks: b.bypassSafetyChecks,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.RequestID, nil
+}
+
+func (b bulkDelete) status(ctx context.Context, requestID string) (*BulkStatusResponse, error) {
+	resp, err := b.client.GetBulkZoneDeleteStatus(ctx, GetBulkZoneDeleteStatusRequest{RequestID: requestID})
+	if err != nil {
+		return nil, err
+	}
+	return (*BulkStatusResponse)(resp), nil
+}
+
+func (b bulkDelete) result(ctx context.Context, requestID string) ([]string, []BulkFailedZone, error) {
+	resp, err := b.client.GetBulkZoneDeleteResult(ctx, GetBulkZoneDeleteResultRequest{RequestID: requestID})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.SuccessfullyDeletedZones, resp.FailedZones, nil
+}
@@ -0,0 +1,269 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBulkServer processes bulk requests, failing zones listed in failures the given number of times
+type fakeBulkServer struct {
+	t          *testing.T
+	mu         sync.Mutex
+	failures   map[string]int
+	reason     string
+	reasons    map[string]string
+	requests   map[string][]string
+	polls      map[string]int
+	batchSizes []int
+}
+
+func newFakeBulkServer(t *testing.T, failures map[string]int) *fakeBulkServer {
+	return &fakeBulkServer{
+		t:        t,
+		failures: failures,
+		reason:   "ZONE_ALREADY_EXISTS",
+		requests: map[string][]string{},
+		polls:    map[string]int{},
+	}
+}
+
+func (f *fakeBulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/config-dns/v2/zones/")
+	kind, rest, _ := strings.Cut(path, "/")
+	assert.Contains(f.t, []string{"create-requests", "delete-requests"}, kind)
+
+	switch {
+	case r.Method == http.MethodPost:
+		var body struct {
+			Zones []json.RawMessage `json:"zones"`
+		}
+		assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		var zones []string
+		for _, raw := range body.Zones {
+			var zone string
+			if kind == "create-requests" {
+				var z ZoneCreate
+				assert.NoError(f.t, json.Unmarshal(raw, &z))
+				assert.Equal(f.t, "PRIMARY", z.Type)
+				zone = z.Zone
+			} else {
+				assert.NoError(f.t, json.Unmarshal(raw, &zone))
+			}
+			zones = append(zones, zone)
+		}
+		id := fmt.Sprintf("req-%d", len(f.requests)+1)
+		f.requests[id] = zones
+		f.batchSizes = append(f.batchSizes, len(zones))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"requestId": %q, "expirationDate": "2020-10-28T17:10:04.515792Z"}`, id)
+	case strings.HasSuffix(rest, "/result"):
+		id := strings.TrimSuffix(rest, "/result")
+		var succeeded []string
+		var failed []BulkFailedZone
+		for _, z := range f.requests[id] {
+			if f.failures[z] > 0 {
+				f.failures[z]--
+				reason := f.reason
+				if r, ok := f.reasons[z]; ok {
+					reason = r
+				}
+				failed = append(failed, BulkFailedZone{Zone: z, FailureReason: reason})
+				continue
+			}
+			succeeded = append(succeeded, z)
+		}
+		key := "successfullyCreatedZones"
+		if kind == "delete-requests" {
+			key = "successfullyDeletedZones"
+		}
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(f.t, json.NewEncoder(w).Encode(map[string]interface{}{"requestId": id, key: succeeded, "failedZones": failed}))
+	default:
+		f.polls[rest]++
+		complete := f.polls[rest] > 1
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, `{"requestId": %q, "zonesSubmitted": %d, "successCount": 0, "failureCount": 0, "isComplete": %t}`,
+			rest, len(f.requests[rest]), complete)
+	}
+}
+
+func TestCreateZonesInBulk(t *testing.T) {
+	zones := make([]ZoneCreate, 5)
+	for i := range zones {
+		zones[i] = ZoneCreate{Zone: fmt.Sprintf("zone%d.example.com", i), Type: "PRIMARY"}
+	}
+
+	tests := map[string]struct {
+		zones              []ZoneCreate
+		failures           map[string]int
+		reasons            map[string]string
+		opts               BulkOptions
+		expectedSucceeded  []string
+		expectedFailed     []BulkFailedZone
+		expectedAttempts   int
+		expectedBatchSizes []int
+		withError          string
+	}{
+		"all created in batches": {
+			zones:              zones,
+			opts:               BulkOptions{BatchSize: 2, PollInterval: time.Millisecond},
+			expectedSucceeded:  []string{"zone0.example.com", "zone1.example.com", "zone2.example.com", "zone3.example.com", "zone4.example.com"},
+			expectedAttempts:   1,
+			expectedBatchSizes: []int{2, 2, 1},
+		},
+		"failed zones retried": {
+			zones:    zones,
+			failures: map[string]int{"zone1.example.com": 1, "zone3.example.com": 5},
+			opts: BulkOptions{
+				BatchSize:    3,
+				PollInterval: time.Millisecond,
+				Retry:        BulkRetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			},
+			expectedSucceeded: []string{"zone0.example.com", "zone2.example.com", "zone4.example.com", "zone1.example.com"},
+			expectedFailed: []BulkFailedZone{
+				{Zone: "zone3.example.com", FailureReason: "ZONE_ALREADY_EXISTS"},
+			},
+			expectedAttempts:   3,
+			expectedBatchSizes: []int{3, 2, 2, 1},
+		},
+		"non retryable failures": {
+			zones:    zones[:2],
+			failures: map[string]int{"zone1.example.com": 1},
+			opts: BulkOptions{
+				PollInterval: time.Millisecond,
+				Retry: BulkRetryPolicy{
+					MaxAttempts: 3,
+					Retryable:   func(f BulkFailedZone) bool { return f.FailureReason != "ZONE_ALREADY_EXISTS" },
+				},
+			},
+			expectedSucceeded: []string{"zone0.example.com"},
+			expectedFailed: []BulkFailedZone{
+				{Zone: "zone1.example.com", FailureReason: "ZONE_ALREADY_EXISTS"},
+			},
+			expectedAttempts:   1,
+			expectedBatchSizes: []int{2},
+		},
+		"non retryable failures kept after retries": {
+			zones:    zones[:4],
+			failures: map[string]int{"zone0.example.com": 1, "zone1.example.com": 1, "zone2.example.com": 1, "zone3.example.com": 5},
+			reasons:  map[string]string{"zone0.example.com": "TIMEOUT", "zone3.example.com": "TIMEOUT"},
+			opts: BulkOptions{
+				PollInterval: time.Millisecond,
+				Retry: BulkRetryPolicy{
+					MaxAttempts: 3,
+					Retryable:   func(f BulkFailedZone) bool { return f.FailureReason == "TIMEOUT" },
+				},
+			},
+			expectedSucceeded: []string{"zone0.example.com"},
+			expectedFailed: []BulkFailedZone{
+				{Zone: "zone1.example.com", FailureReason: "ZONE_ALREADY_EXISTS"},
+				{Zone: "zone2.example.com", FailureReason: "ZONE_ALREADY_EXISTS"},
+				{Zone: "zone3.example.com", FailureReason: "TIMEOUT"},
+			},
+			expectedAttempts:   3,
+			expectedBatchSizes: []int{4, 2, 1},
+		},
+		"duplicate zone": {
+			zones:     []ZoneCreate{zones[0], zones[0]},
+			withError: `duplicate zone "zone0.example.com"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeBulkServer(t, test.failures)
+			fake.reasons = test.reasons
+			mockServer := httptest.NewTLSServer(fake)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			result, err := CreateZonesInBulk(context.Background(), client, test.zones, ZoneQueryString{Contract: "1-2ABCDE"}, test.opts)
+			if test.withError != "" {
+				assert.True(t, errors.Is(err, ErrBulkZonesOperation), "want: %s; got: %s", ErrBulkZonesOperation, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSucceeded, result.Succeeded)
+			assert.Equal(t, test.expectedFailed, result.Failed)
+			assert.Equal(t, test.expectedAttempts, result.Attempts)
+			assert.Equal(t, test.expectedBatchSizes, fake.batchSizes)
+		})
+	}
+}
+
+func TestDeleteZonesInBulk(t *testing.T) {
+	fake := newFakeBulkServer(t, map[string]int{"b.example.com": 1})
+	mockServer := httptest.NewTLSServer(fake)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	progress := make(chan BulkProgressEvent)
+	var events []BulkProgressEvent
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range progress {
+			events = append(events, e)
+		}
+	}()
+
+	result, err := DeleteZonesInBulk(context.Background(), client, []string{"a.example.com", "b.example.com"}, ptr.To(true), BulkOptions{
+		PollInterval: time.Millisecond,
+		Retry:        BulkRetryPolicy{MaxAttempts: 2},
+		Progress:     progress,
+	})
+	close(progress)
+	<-done
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, result.Succeeded)
+	assert.Empty(t, result.Failed)
+	assert.Equal(t, 2, result.Attempts)
+
+	var types []BulkEventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []BulkEventType{
+		BulkEventSubmitted, BulkEventStatus, BulkEventStatus, BulkEventBatchComplete,
+		BulkEventRetry,
+		BulkEventSubmitted, BulkEventStatus, BulkEventStatus, BulkEventBatchComplete,
+		BulkEventComplete,
+	}, types)
+	assert.Equal(t, BulkProgressEvent{Type: BulkEventBatchComplete, Attempt: 1, Batch: 1, Batches: 1, RequestID: "req-1", Zones: 2, Succeeded: 1, Failed: 1}, events[3])
+	assert.Equal(t, BulkProgressEvent{Type: BulkEventComplete, Attempt: 2, Zones: 2, Succeeded: 2}, events[9])
+}
+
+func TestBulkZonesOperation_ContextCancelled(t *testing.T) {
+	fake := newFakeBulkServer(t, nil)
+	mockServer := httptest.NewTLSServer(fake)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := make(chan BulkProgressEvent)
+	go func() {
+		<-progress
+		cancel()
+	}()
+
+	result, err := DeleteZonesInBulk(ctx, client, []string{"a.example.com"}, nil, BulkOptions{PollInterval: time.Hour, Progress: progress})
+	assert.True(t, errors.Is(err, context.Canceled), "want: %s; got: %s", context.Canceled, err)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Empty(t, result.Succeeded)
+}
@@ -0,0 +1,323 @@
+package edgeworkers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// BundleManifest represents bundle.json of an EdgeWorker code bundle
+	BundleManifest struct {
+		EdgeWorkerVersion string                 `json:"edgeworker-version"`
+		Description       string                 `json:"description,omitempty"`
+		APIVersion        string                 `json:"api-version,omitempty"`
+		Misc              map[string]interface{} `json:"misc,omitempty"`
+	}
+
+	// BundleLimits contains limits of code bundles enforced locally. Zero values are replaced with defaults
+	BundleLimits struct {
+		// MaxSize is the maximum size of the compressed bundle in bytes
+		MaxSize int
+		// MaxUncompressedSize is the maximum total size of bundle files in bytes
+		MaxUncompressedSize int
+		// MaxFiles is the maximum number of files in the bundle
+		MaxFiles int
+	}
+
+	// BundleOptions contains options of BuildBundle
+	BundleOptions struct {
+		// Manifest is written as bundle.json, replacing the one in the source directory, if set
+		Manifest *BundleManifest
+		// Version overrides edgeworker-version of the manifest, if set
+		Version string
+		Limits  BundleLimits
+	}
+
+	// BuiltBundle is a code bundle built by BuildBundle
+	BuiltBundle struct {
+		// Data is the gzip compressed tar archive
+		Data     []byte
+		Manifest BundleManifest
+		// Files are paths of files in the bundle, sorted
+		Files []string
+		// Checksum is the hex encoded SHA-256 checksum of Data
+		Checksum string
+	}
+)
+
+const (
+	// BundleManifestFile is the name of the bundle manifest
+	BundleManifestFile = "bundle.json"
+	// BundleMainFile is the name of the EdgeWorker main module
+	BundleMainFile = "main.js"
+
+	// DefaultMaxBundleSize is the default maximum size of a compressed bundle
+	DefaultMaxBundleSize = 1 << 20
+	// DefaultMaxBundleUncompressedSize is the default maximum total size of bundle files
+	DefaultMaxBundleUncompressedSize = 5 << 20
+	// DefaultMaxBundleFiles is the default maximum number of files in a bundle
+	DefaultMaxBundleFiles = 100
+)
+
+var (
+	// ErrBuildBundle is returned when a bundle can't be built
+	ErrBuildBundle = errors.New("build a bundle")
+	// ErrBundleLimit is returned when the bundle exceeds size or file count limits
+	ErrBundleLimit = errors.New("bundle limit exceeded")
+	// ErrBundleManifest is returned when bundle.json is missing or invalid
+	ErrBundleManifest = errors.New("invalid bundle manifest")
+	// ErrBundleMainModule is returned when main.js is missing or does not export any event handler
+	ErrBundleMainModule = errors.New("invalid main module")
+
+	edgeWorkerVersionRegexp = regexp.MustCompile(`^[A-Za-z0-9.\-_]{1,32}$`)
+	apiVersionRegexp        = regexp.MustCompile(`^\d+\.\d+$`)
+
+	eventHandlers = []string{"onClientRequest", "onOriginRequest", "onOriginResponse", "onClientResponse", "responseProvider"}
+
+	exportedHandlerRegexp = regexp.MustCompile(`export\s+(?:async\s+)?(?:function\s*\*?\s*|const\s+|let\s+|var\s+)(` +
+		strings.Join(eventHandlers, "|") + `)\b`)
+	exportListRegexp = regexp.MustCompile(`export\s*\{([^}]*)\}`)
+)
+
+// EventHandlers returns names of functions main.js can export to handle EdgeWorker events
+func EventHandlers() []string {
+	return append([]string(nil), eventHandlers...)
+}
+
+// Validate validates BundleManifest
+func (m BundleManifest) Validate() error {
+	return validation.Errors{
+		"EdgeWorkerVersion": validation.Validate(m.EdgeWorkerVersion, validation.Required,
+			validation.Match(edgeWorkerVersionRegexp).Error("must contain 1-32 letters, digits, dots, dashes or underscores")),
+		"Description": validation.Validate(m.Description, validation.Length(0, 1024)),
+		"APIVersion":  validation.Validate(m.APIVersion, validation.Match(apiVersionRegexp).Error("must be in the MAJOR.MINOR format")),
+	}.Filter()
+}
+
+// BuildBundleFromDir builds a code bundle from files of the directory, see BuildBundle
+func BuildBundleFromDir(dir string, opts BundleOptions) (*BuiltBundle, error) {
+	return BuildBundle(os.DirFS(dir), opts)
+}
+
+// BuildBundle packs files of fsys into a gzip compressed tar archive accepted by CreateEdgeWorkerVersion and ValidateBundle.
+// Hidden files and directories, i.e. with names starting with a dot, are skipped.
+//
+// bundle.json is taken from fsys or generated from BundleOptions.Manifest, and validated. main.js has to exist
+// and export at least one of EventHandlers. When edgeworker-version of bundle.json from fsys is overridden, keys
+// unknown to BundleManifest are kept in the rewritten file. Archives built from the same files are identical, so their checksums
+// can be compared.
+func BuildBundle(fsys fs.FS, opts BundleOptions) (*BuiltBundle, error) {
+	limits := opts.Limits.withDefaults()
+
+	files := make(map[string][]byte)
+	var uncompressed int
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s: not a regular file", p)
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[p] = data
+		uncompressed += len(data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildBundle, err)
+	}
+
+	manifest, err := bundleManifest(files, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildBundle, err)
+	}
+	if err := checkMainModule(files[BundleMainFile]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildBundle, err)
+	}
+
+	if len(files) > limits.MaxFiles {
+		return nil, fmt.Errorf("%w: %w: %d files, at most %d allowed", ErrBuildBundle, ErrBundleLimit, len(files), limits.MaxFiles)
+	}
+	if uncompressed > limits.MaxUncompressedSize {
+		return nil, fmt.Errorf("%w: %w: files have %d bytes, at most %d allowed", ErrBuildBundle, ErrBundleLimit, uncompressed, limits.MaxUncompressedSize)
+	}
+
+	data, names, err := packBundle(files)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildBundle, err)
+	}
+	if len(data) > limits.MaxSize {
+		return nil, fmt.Errorf("%w: %w: bundle has %d bytes, at most %d allowed", ErrBuildBundle, ErrBundleLimit, len(data), limits.MaxSize)
+	}
+
+	checksum := sha256.Sum256(data)
+	return &BuiltBundle{
+		Data:     data,
+		Manifest: *manifest,
+		Files:    names,
+		Checksum: hex.EncodeToString(checksum[:]),
+	}, nil
+}
+
+// Bundle returns a reader of the bundle which can be passed to CreateEdgeWorkerVersion or ValidateBundle
+func (b *BuiltBundle) Bundle() Bundle {
+	return Bundle{bytes.NewReader(b.Data)}
+}
+
+func (l BundleLimits) withDefaults() BundleLimits {
+	if l.MaxSize <= 0 {
+		l.MaxSize = DefaultMaxBundleSize
+	}
+	if l.MaxUncompressedSize <= 0 {
+		l.MaxUncompressedSize = DefaultMaxBundleUncompressedSize
+	}
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = DefaultMaxBundleFiles
+	}
+	return l
+}
+
+// bundleManifest resolves and validates the manifest, and stores its final form in files
+func bundleManifest(files map[string][]byte, opts BundleOptions) (*BundleManifest, error) {
+	var manifest BundleManifest
+	switch data, ok := files[BundleManifestFile]; {
+	case opts.Manifest != nil:
+		manifest = *opts.Manifest
+	case ok:
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrBundleManifest, BundleManifestFile, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s not found", ErrBundleManifest, BundleManifestFile)
+	}
+	if opts.Version != "" {
+		manifest.EdgeWorkerVersion = opts.Version
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBundleManifest, err)
+	}
+
+	if opts.Manifest != nil || opts.Version != "" {
+		var keys map[string]json.RawMessage
+		if opts.Manifest == nil {
+			if err := json.Unmarshal(files[BundleManifestFile], &keys); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrBundleManifest, BundleManifestFile, err)
+			}
+		}
+		data, err := marshalManifest(manifest, keys)
+		if err != nil {
+			return nil, err
+		}
+		files[BundleManifestFile] = append(data, '\n')
+	}
+	return &manifest, nil
+}
+
+// marshalManifest marshals the manifest merged into keys, so that keys unknown to BundleManifest are not lost
+func marshalManifest(manifest BundleManifest, keys map[string]json.RawMessage) ([]byte, error) {
+	if keys == nil {
+		return json.MarshalIndent(manifest, "", "  ")
+	}
+	known, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &fields); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"edgeworker-version", "description", "api-version", "misc"} {
+		delete(keys, key)
+	}
+	for key, value := range fields {
+		keys[key] = value
+	}
+	return json.MarshalIndent(keys, "", "  ")
+}
+
+// checkMainModule checks that main.js exports at least one event handler
+func checkMainModule(source []byte) error {
+	if source == nil {
+		return fmt.Errorf("%w: %s not found", ErrBundleMainModule, BundleMainFile)
+	}
+	if exportedHandlerRegexp.Match(source) {
+		return nil
+	}
+	for _, list := range exportListRegexp.FindAllSubmatch(source, -1) {
+		for _, item := range strings.Split(string(list[1]), ",") {
+			// the exported name is the last word, e.g. in 'handle as onClientRequest'
+			fields := strings.Fields(item)
+			if len(fields) == 0 {
+				continue
+			}
+			for _, h := range eventHandlers {
+				if fields[len(fields)-1] == h {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s does not export any of event handlers: %s", ErrBundleMainModule, BundleMainFile, strings.Join(eventHandlers, ", "))
+}
+
+// packBundle writes files into a gzip compressed tar archive. Files are sorted, and modification times and owners
+// are not stored, so the archive depends only on names and contents of files.
+func packBundle(files map[string][]byte) ([]byte, []string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		header := &tar.Header{
+			Name:     path.Clean(name),
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+			Typeflag: tar.TypeReg,
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, nil, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), names, nil
+}
@@ -0,0 +1,205 @@
+package edgeworkers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `{"edgeworker-version": "1.0.0", "description": "test", "api-version": "0.3"}`
+
+func untarBundle(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestBuildBundle(t *testing.T) {
+	mainJS := &fstest.MapFile{Data: []byte(`export function onClientRequest(request) {}`)}
+
+	tests := map[string]struct {
+		files              fstest.MapFS
+		opts               BundleOptions
+		expectedFiles      []string
+		expectedManifest   BundleManifest
+		expectedBundleJSON string
+		withError          []error
+		withErrorContains  string
+	}{
+		"bundle with manifest and nested files": {
+			files: fstest.MapFS{
+				"bundle.json":     {Data: []byte(testManifest)},
+				"main.js":         mainJS,
+				"lib/utils.js":    {Data: []byte(`export const x = 1;`)},
+				".git/config":     {Data: []byte(`ignored`)},
+				"lib/.eslintrc":   {Data: []byte(`ignored`)},
+				"static/data.txt": {Data: []byte(`data`)},
+			},
+			expectedFiles:    []string{"bundle.json", "lib/utils.js", "main.js", "static/data.txt"},
+			expectedManifest: BundleManifest{EdgeWorkerVersion: "1.0.0", Description: "test", APIVersion: "0.3"},
+		},
+		"generated manifest": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(`not json`)},
+				"main.js":     {Data: []byte("async function handle(r) {}\nexport { handle as responseProvider };")},
+			},
+			opts:             BundleOptions{Manifest: &BundleManifest{EdgeWorkerVersion: "2.0", Description: "generated"}},
+			expectedFiles:    []string{"bundle.json", "main.js"},
+			expectedManifest: BundleManifest{EdgeWorkerVersion: "2.0", Description: "generated"},
+		},
+		"version override": {
+			files:            fstest.MapFS{"bundle.json": {Data: []byte(testManifest)}, "main.js": mainJS},
+			opts:             BundleOptions{Version: "1.0.1"},
+			expectedFiles:    []string{"bundle.json", "main.js"},
+			expectedManifest: BundleManifest{EdgeWorkerVersion: "1.0.1", Description: "test", APIVersion: "0.3"},
+		},
+		"version override keeps unknown keys": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(`{"edgeworker-version": "1.0.0", "description": "test", "config": {"debug": true}}`)},
+				"main.js":     mainJS,
+			},
+			opts:             BundleOptions{Version: "1.0.1"},
+			expectedFiles:    []string{"bundle.json", "main.js"},
+			expectedManifest: BundleManifest{EdgeWorkerVersion: "1.0.1", Description: "test"},
+			expectedBundleJSON: `{
+  "config": {
+    "debug": true
+  },
+  "description": "test",
+  "edgeworker-version": "1.0.1"
+}
+`,
+		},
+		"missing manifest": {
+			files:             fstest.MapFS{"main.js": mainJS},
+			withError:         []error{ErrBundleManifest},
+			withErrorContains: "bundle.json not found",
+		},
+		"invalid manifest": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(`{"edgeworker-version": "1.0 beta", "api-version": "latest"}`)},
+				"main.js":     mainJS,
+			},
+			withError:         []error{ErrBundleManifest},
+			withErrorContains: "APIVersion: must be in the MAJOR.MINOR format; EdgeWorkerVersion: must contain",
+		},
+		"missing main.js": {
+			files:             fstest.MapFS{"bundle.json": {Data: []byte(testManifest)}, "lib/main.js": mainJS},
+			withError:         []error{ErrBundleMainModule},
+			withErrorContains: "main.js not found",
+		},
+		"main.js without event handlers": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(testManifest)},
+				"main.js":     {Data: []byte("function onClientRequest(r) {}\nexport { helper };")},
+			},
+			withError:         []error{ErrBundleMainModule},
+			withErrorContains: "does not export any of event handlers",
+		},
+		"too many files": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(testManifest)},
+				"main.js":     mainJS,
+				"a.js":        {Data: []byte(`a`)},
+			},
+			opts:              BundleOptions{Limits: BundleLimits{MaxFiles: 2}},
+			withError:         []error{ErrBundleLimit},
+			withErrorContains: "3 files, at most 2 allowed",
+		},
+		"uncompressed size exceeded": {
+			files: fstest.MapFS{
+				"bundle.json": {Data: []byte(testManifest)},
+				"main.js":     mainJS,
+				"data.txt":    {Data: bytes.Repeat([]byte("a"), 1000)},
+			},
+			opts:              BundleOptions{Limits: BundleLimits{MaxUncompressedSize: 1000}},
+			withError:         []error{ErrBundleLimit},
+			withErrorContains: "at most 1000 allowed",
+		},
+		"compressed size exceeded": {
+			files:             fstest.MapFS{"bundle.json": {Data: []byte(testManifest)}, "main.js": mainJS},
+			opts:              BundleOptions{Limits: BundleLimits{MaxSize: 10}},
+			withError:         []error{ErrBundleLimit},
+			withErrorContains: "at most 10 allowed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := BuildBundle(test.files, test.opts)
+			if len(test.withError) > 0 {
+				assert.True(t, errors.Is(err, ErrBuildBundle), "want: %s; got: %s", ErrBuildBundle, err)
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				assert.Contains(t, err.Error(), test.withErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedFiles, result.Files)
+			assert.Equal(t, test.expectedManifest, result.Manifest)
+			assert.Len(t, result.Checksum, 64)
+
+			files := untarBundle(t, result.Data)
+			assert.Len(t, files, len(test.expectedFiles))
+			assert.Contains(t, files["bundle.json"], `"edgeworker-version": "`+test.expectedManifest.EdgeWorkerVersion+`"`)
+			if test.expectedBundleJSON != "" {
+				assert.Equal(t, test.expectedBundleJSON, files["bundle.json"])
+			}
+		})
+	}
+}
+
+func TestBuildBundleIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.json"), []byte(testManifest), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.js"), []byte(`export async function onOriginRequest(r) {}`), 0600))
+
+	first, err := BuildBundleFromDir(dir, BundleOptions{})
+	require.NoError(t, err)
+	second, err := BuildBundle(fstest.MapFS{
+		"bundle.json": {Data: []byte(testManifest), Mode: 0755},
+		"main.js":     {Data: []byte(`export async function onOriginRequest(r) {}`)},
+	}, BundleOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, first.Checksum, second.Checksum)
+
+	content, err := io.ReadAll(first.Bundle())
+	require.NoError(t, err)
+	assert.Equal(t, first.Data, content)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.js"), []byte(`export async function onOriginRequest(req) {}`), 0600))
+	third, err := BuildBundleFromDir(dir, BundleOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Checksum, third.Checksum)
+	assert.True(t, strings.HasPrefix(untarBundle(t, third.Data)["main.js"], "export async"))
+}
+
+func TestEventHandlers(t *testing.T) {
+	handlers := EventHandlers()
+	require.Contains(t, handlers, "onClientRequest")
+	handlers[0] = "modified"
+	assert.NotContains(t, EventHandlers(), "modified")
+}
@@ -0,0 +1,310 @@
+package edgeworkers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type (
+	// BundleContent is an unpacked code bundle
+	BundleContent struct {
+		// Files are sorted by name
+		Files []BundleFile
+		// Manifest is parsed from bundle.json, nil if it's missing or can't be parsed
+		Manifest *BundleManifest
+	}
+
+	// BundleFile is a file of an unpacked code bundle
+	BundleFile struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+		// SHA256 is the hex encoded SHA-256 checksum of the content
+		SHA256  string `json:"sha256"`
+		Content []byte `json:"-"`
+	}
+
+	// BundleDiffOptions contains options of DiffBundles
+	BundleDiffOptions struct {
+		// Context is the number of context lines of unified diffs. Defaults to 3
+		Context int
+		// OldLabel and NewLabel prefix file names in unified diff headers. Default to 'a' and 'b'
+		OldLabel string
+		NewLabel string
+	}
+
+	// BundleDiff contains differences between two code bundles
+	BundleDiff struct {
+		// Changes are sorted by file name, unchanged files are not included
+		Changes []BundleFileChange `json:"changes"`
+	}
+
+	// BundleFileChange describes a file which differs between two code bundles
+	BundleFileChange struct {
+		Name      string               `json:"name"`
+		Type      BundleFileChangeType `json:"type"`
+		OldSize   int64                `json:"oldSize,omitempty"`
+		NewSize   int64                `json:"newSize,omitempty"`
+		OldSHA256 string               `json:"oldSha256,omitempty"`
+		NewSHA256 string               `json:"newSha256,omitempty"`
+		// Binary is set if either version of the file is not UTF-8 text
+		Binary bool `json:"binary,omitempty"`
+		// Diff is the unified diff of text files, empty for binary files. For added or removed empty files,
+		// it contains only the file header
+		Diff string `json:"diff,omitempty"`
+	}
+
+	// BundleFileChangeType is the type of BundleFileChange
+	BundleFileChangeType string
+)
+
+const (
+	// BundleFileAdded means that the file exists only in the new bundle
+	BundleFileAdded BundleFileChangeType = "added"
+	// BundleFileRemoved means that the file exists only in the old bundle
+	BundleFileRemoved BundleFileChangeType = "removed"
+	// BundleFileModified means that the file content differs
+	BundleFileModified BundleFileChangeType = "modified"
+
+	// maxBundleReadSize limits the total size of files unpacked by ReadBundle
+	maxBundleReadSize = 64 << 20
+)
+
+var (
+	// ErrReadBundle is returned when a bundle can't be unpacked
+	ErrReadBundle = errors.New("read a bundle")
+	// ErrDiffEdgeWorkerVersions is returned when DiffEdgeWorkerVersions fails
+	ErrDiffEdgeWorkerVersions = errors.New("diff EdgeWorker versions")
+)
+
+// ReadBundle unpacks a gzip compressed tar code bundle in memory, e.g. one returned by GetEdgeWorkerVersionContent.
+// Directory entries are skipped and leading './' is removed from file names.
+func ReadBundle(bundle Bundle) (*BundleContent, error) {
+	if bundle.Reader == nil {
+		return nil, fmt.Errorf("%w: empty bundle", ErrReadBundle)
+	}
+	gz, err := gzip.NewReader(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadBundle, err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	var content BundleContent
+	var total int64
+	seen := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadBundle, err)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("%w: %s: unsupported entry type %q", ErrReadBundle, header.Name, header.Typeflag)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s: duplicate file", ErrReadBundle, name)
+		}
+		seen[name] = true
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleReadSize-total+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrReadBundle, name, err)
+		}
+		if total += int64(len(data)); total > maxBundleReadSize {
+			return nil, fmt.Errorf("%w: files exceed %d bytes", ErrReadBundle, maxBundleReadSize)
+		}
+		checksum := sha256.Sum256(data)
+		content.Files = append(content.Files, BundleFile{
+			Name:    name,
+			Size:    int64(len(data)),
+			SHA256:  hex.EncodeToString(checksum[:]),
+			Content: data,
+		})
+	}
+	sort.Slice(content.Files, func(i, j int) bool { return content.Files[i].Name < content.Files[j].Name })
+
+	if f := content.File(BundleManifestFile); f != nil {
+		var manifest BundleManifest
+		if err := json.Unmarshal(f.Content, &manifest); err == nil {
+			content.Manifest = &manifest
+		}
+	}
+	return &content, nil
+}
+
+// File returns the file with the given name, or nil if the bundle does not contain it
+func (c *BundleContent) File(name string) *BundleFile {
+	i := sort.Search(len(c.Files), func(i int) bool { return c.Files[i].Name >= name })
+	if i < len(c.Files) && c.Files[i].Name == name {
+		return &c.Files[i]
+	}
+	return nil
+}
+
+// DiffBundles compares files of two bundles by checksum. Changes of text files contain a unified diff.
+// A bundle built from a local directory can be compared with a deployed version by reading BuiltBundle.Bundle with ReadBundle.
+func DiffBundles(oldBundle, newBundle *BundleContent, opts BundleDiffOptions) *BundleDiff {
+	if opts.Context <= 0 {
+		opts.Context = 3
+	}
+	if opts.OldLabel == "" {
+		opts.OldLabel = "a"
+	}
+	if opts.NewLabel == "" {
+		opts.NewLabel = "b"
+	}
+
+	names := make(map[string]bool)
+	for _, f := range oldBundle.Files {
+		names[f.Name] = true
+	}
+	for _, f := range newBundle.Files {
+		names[f.Name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	diff := &BundleDiff{Changes: []BundleFileChange{}}
+	for _, name := range sorted {
+		oldFile, newFile := oldBundle.File(name), newBundle.File(name)
+		change := BundleFileChange{Name: name}
+		var oldContent, newContent []byte
+		switch {
+		case oldFile == nil:
+			change.Type = BundleFileAdded
+		case newFile == nil:
+			change.Type = BundleFileRemoved
+		case oldFile.SHA256 == newFile.SHA256:
+			continue
+		default:
+			change.Type = BundleFileModified
+		}
+		if oldFile != nil {
+			change.OldSize, change.OldSHA256, oldContent = oldFile.Size, oldFile.SHA256, oldFile.Content
+		}
+		if newFile != nil {
+			change.NewSize, change.NewSHA256, newContent = newFile.Size, newFile.SHA256, newFile.Content
+		}
+		change.Binary = !isText(oldContent) || !isText(newContent)
+		if !change.Binary {
+			change.Diff = unifiedDiff(name, change.Type, oldContent, newContent, opts)
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+	return diff
+}
+
+// Empty tells whether bundles have the same files
+func (d *BundleDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Unified returns unified diffs of all changed text files, and a note for each changed binary file
+func (d *BundleDiff) Unified() string {
+	var b strings.Builder
+	for _, c := range d.Changes {
+		if !c.Binary {
+			b.WriteString(c.Diff)
+			continue
+		}
+		fmt.Fprintf(&b, "Binary file %s %s\n", c.Name, c.Type)
+	}
+	return b.String()
+}
+
+// DiffEdgeWorkerVersions fetches content bundles of two versions of an EdgeWorker and compares them with DiffBundles
+func DiffEdgeWorkerVersions(ctx context.Context, client Edgeworkers, edgeWorkerID int, oldVersion, newVersion string, opts BundleDiffOptions) (*BundleDiff, error) {
+	contents := make([]*BundleContent, 0, 2)
+	for _, version := range []string{oldVersion, newVersion} {
+		bundle, err := client.GetEdgeWorkerVersionContent(ctx, GetEdgeWorkerVersionContentRequest{
+			EdgeWorkerID: edgeWorkerID,
+			Version:      version,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: version %s: %w", ErrDiffEdgeWorkerVersions, version, err)
+		}
+		content, err := ReadBundle(*bundle)
+		if err != nil {
+			return nil, fmt.Errorf("%w: version %s: %w", ErrDiffEdgeWorkerVersions, version, err)
+		}
+		contents = append(contents, content)
+	}
+	if opts.OldLabel == "" {
+		opts.OldLabel = oldVersion
+	}
+	if opts.NewLabel == "" {
+		opts.NewLabel = newVersion
+	}
+	return DiffBundles(contents[0], contents[1], opts), nil
+}
+
+// isText tells whether the content is UTF-8 text without NUL bytes
+func isText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+func unifiedDiff(name string, changeType BundleFileChangeType, oldContent, newContent []byte, opts BundleDiffOptions) string {
+	fromFile, toFile := opts.OldLabel+"/"+name, opts.NewLabel+"/"+name
+	switch changeType {
+	case BundleFileAdded:
+		fromFile = "/dev/null"
+	case BundleFileRemoved:
+		toFile = "/dev/null"
+	}
+	result, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldContent),
+		B:        splitLines(newContent),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  opts.Context,
+	})
+	if err != nil {
+		return ""
+	}
+	if result == "" {
+		// difflib returns nothing when there are no lines to compare, e.g. for an added empty file
+		result = fmt.Sprintf("--- %s\n+++ %s\n", fromFile, toFile)
+	}
+	return result
+}
+
+// splitLines splits content into lines keeping line endings. A final line without a newline is followed by
+// the '\ No newline at end of file' marker, so that adding or removing the final newline shows in the diff.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	return lines
+}
@@ -0,0 +1,154 @@
+package edgeworkers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle(t *testing.T, main string, extra fstest.MapFS) *BuiltBundle {
+	files := fstest.MapFS{
+		"bundle.json": {Data: []byte(testManifest)},
+		"main.js":     {Data: []byte(main)},
+	}
+	for name, f := range extra {
+		files[name] = f
+	}
+	bundle, err := BuildBundle(files, BundleOptions{})
+	require.NoError(t, err)
+	return bundle
+}
+
+func TestReadBundle(t *testing.T) {
+	built := testBundle(t, "export function onClientRequest(r) {}\n", fstest.MapFS{"lib/a.js": {Data: []byte("a")}})
+	content, err := ReadBundle(built.Bundle())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"bundle.json", "lib/a.js", "main.js"}, []string{content.Files[0].Name, content.Files[1].Name, content.Files[2].Name})
+	assert.Equal(t, &built.Manifest, content.Manifest)
+	a := content.File("lib/a.js")
+	require.NotNil(t, a)
+	assert.Equal(t, BundleFile{
+		Name:    "lib/a.js",
+		Size:    1,
+		SHA256:  "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb",
+		Content: []byte("a"),
+	}, *a)
+	assert.Nil(t, content.File("missing.js"))
+
+	// bundles packed with 'tar czf bundle.tgz .' contain directory entries and './' prefixes
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./main.js", Typeflag: tar.TypeReg, Mode: 0644, Size: 2}))
+	_, err = tw.Write([]byte("js"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	content, err = ReadBundle(Bundle{bytes.NewReader(buf.Bytes())})
+	require.NoError(t, err)
+	require.Len(t, content.Files, 1)
+	assert.Equal(t, "main.js", content.Files[0].Name)
+	assert.Nil(t, content.Manifest)
+
+	_, err = ReadBundle(Bundle{bytes.NewReader([]byte("not gzip"))})
+	assert.True(t, errors.Is(err, ErrReadBundle), "want: %s; got: %s", ErrReadBundle, err)
+}
+
+func TestDiffBundles(t *testing.T) {
+	oldBundle, err := ReadBundle(testBundle(t, "export function onClientRequest(r) {\n  r.respondWith(200, {}, 'old');\n}\n", fstest.MapFS{
+		"lib/removed.js":       {Data: []byte("export const x = 1;\n")},
+		"lib/empty-removed.js": {Data: []byte{}},
+		"logo.png":             {Data: []byte{0x89, 'P', 'N', 'G', 0, 1}},
+	}).Bundle())
+	require.NoError(t, err)
+	newBundle, err := ReadBundle(testBundle(t, "export function onClientRequest(r) {\n  r.respondWith(200, {}, 'new');\n}\n", fstest.MapFS{
+		"lib/added.js":       {Data: []byte("export const y = 2;")},
+		"lib/empty-added.js": {Data: []byte{}},
+		"logo.png":           {Data: []byte{0x89, 'P', 'N', 'G', 0, 2}},
+	}).Bundle())
+	require.NoError(t, err)
+
+	diff := DiffBundles(oldBundle, newBundle, BundleDiffOptions{})
+	require.Len(t, diff.Changes, 6)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, "--- /dev/null\n+++ b/lib/empty-added.js\n", diff.Changes[1].Diff)
+	assert.Equal(t, "--- a/lib/empty-removed.js\n+++ /dev/null\n", diff.Changes[2].Diff)
+	assert.False(t, diff.Changes[1].Binary || diff.Changes[2].Binary)
+	assert.NotContains(t, diff.Unified(), "Binary file lib/empty")
+	diff.Changes = append(diff.Changes[:1], diff.Changes[3:]...)
+
+	assert.Equal(t, BundleFileChange{
+		Name:      "lib/added.js",
+		Type:      BundleFileAdded,
+		NewSize:   19,
+		NewSHA256: newBundle.File("lib/added.js").SHA256,
+		Diff:      "--- /dev/null\n+++ b/lib/added.js\n@@ -0,0 +1 @@\n+export const y = 2;\n\\ No newline at end of file\n",
+	}, diff.Changes[0])
+	assert.Equal(t, "lib/removed.js", diff.Changes[1].Name)
+	assert.Equal(t, BundleFileRemoved, diff.Changes[1].Type)
+	assert.Equal(t, "--- a/lib/removed.js\n+++ /dev/null\n@@ -1 +0,0 @@\n-export const x = 1;\n", diff.Changes[1].Diff)
+	assert.Equal(t, "logo.png", diff.Changes[2].Name)
+	assert.Equal(t, BundleFileModified, diff.Changes[2].Type)
+	assert.True(t, diff.Changes[2].Binary)
+	assert.Empty(t, diff.Changes[2].Diff)
+	assert.Equal(t, "main.js", diff.Changes[3].Name)
+	assert.Equal(t, "--- a/main.js\n+++ b/main.js\n@@ -1,3 +1,3 @@\n export function onClientRequest(r) {\n-  r.respondWith(200, {}, 'old');\n+  r.respondWith(200, {}, 'new');\n }\n", diff.Changes[3].Diff)
+	assert.Contains(t, diff.Unified(), "Binary file logo.png modified\n--- a/main.js")
+
+	assert.True(t, DiffBundles(oldBundle, oldBundle, BundleDiffOptions{}).Empty())
+}
+
+func TestDiffBundles_FinalNewline(t *testing.T) {
+	source := "export function onClientRequest(r) {}\n"
+	withNewline, err := ReadBundle(testBundle(t, source, fstest.MapFS{"lib/a.js": {Data: []byte("a\nb\n")}}).Bundle())
+	require.NoError(t, err)
+	withoutNewline, err := ReadBundle(testBundle(t, source, fstest.MapFS{"lib/a.js": {Data: []byte("a\nb")}}).Bundle())
+	require.NoError(t, err)
+
+	diff := DiffBundles(withNewline, withoutNewline, BundleDiffOptions{})
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "--- a/lib/a.js\n+++ b/lib/a.js\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n", diff.Changes[0].Diff)
+
+	diff = DiffBundles(withoutNewline, withNewline, BundleDiffOptions{})
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "--- a/lib/a.js\n+++ b/lib/a.js\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n", diff.Changes[0].Diff)
+}
+
+func TestDiffEdgeWorkerVersions(t *testing.T) {
+	bundles := map[string][]byte{
+		"/edgeworkers/v1/ids/42/versions/1.0/content": testBundle(t, "export function onClientRequest(r) {}\n", nil).Data,
+		"/edgeworkers/v1/ids/42/versions/1.1/content": testBundle(t, "export function onClientResponse(r) {}\n", nil).Data,
+	}
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := bundles[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type": "not-found", "title": "Not Found", "status": 404}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		_, _ = w.Write(data)
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	diff, err := DiffEdgeWorkerVersions(context.Background(), client, 42, "1.0", "1.1", BundleDiffOptions{})
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "--- 1.0/main.js\n+++ 1.1/main.js\n@@ -1 +1 @@\n-export function onClientRequest(r) {}\n+export function onClientResponse(r) {}\n", diff.Changes[0].Diff)
+
+	_, err = DiffEdgeWorkerVersions(context.Background(), client, 42, "1.0", "2.0", BundleDiffOptions{})
+	assert.True(t, errors.Is(err, ErrDiffEdgeWorkerVersions), "want: %s; got: %s", ErrDiffEdgeWorkerVersions, err)
+	assert.Contains(t, err.Error(), "version 2.0")
+}
@@ -0,0 +1,389 @@
+package edgeworkers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// DeployRequest contains parameters of Deploy
+	DeployRequest struct {
+		EdgeWorkerID int
+		// Source contains files of the code bundle, see BuildBundle
+		Source fs.FS
+		// BundleOptions are passed to BuildBundle. BundleOptions.Version is set by Deploy when the version is bumped
+		BundleOptions BundleOptions
+		// Networks are activated in order. Defaults to staging, then production
+		Networks []ActivationNetwork
+		// Note is the note of activations
+		Note string
+		// BumpVersion increments edgeworker-version of bundle.json until it does not match any existing version.
+		// Otherwise Deploy fails if the version exists.
+		BumpVersion bool
+		// Verify is called after the activation on each network completes, e.g. to run smoke tests.
+		// An error fails the deployment as if the activation failed.
+		Verify func(context.Context, ActivationNetwork) error
+		// Rollback activates the previously active version on the network when the activation or verification fails
+		Rollback bool
+		// RollbackTimeout bounds the time spent on the rollback activation. Defaults to DefaultRollbackTimeout
+		RollbackTimeout time.Duration
+		// PollInterval is the interval between activation status checks. Defaults to DefaultActivationPollInterval
+		PollInterval time.Duration
+		// Progress receives events about deployment steps, if set. Events are sent synchronously,
+		// so the channel has to be drained until Deploy returns
+		Progress chan<- DeployEvent
+	}
+
+	// DeployResult describes the deployment
+	DeployResult struct {
+		Version string
+		Bundle  *BuiltBundle
+		// Warnings are returned by ValidateBundle
+		Warnings []ValidationIssue
+		// Activations are the last fetched states of activations, including rollbacks
+		Activations []Activation
+		// RolledBack is the version activated again after a failure, if any
+		RolledBack string
+	}
+
+	// DeployEvent describes progress of Deploy
+	DeployEvent struct {
+		Type    DeployEventType
+		Version string
+		Network ActivationNetwork
+		// Activation is set for activation events
+		Activation *Activation
+		// Warnings are set for DeployEventValidated events
+		Warnings []ValidationIssue
+	}
+
+	// DeployEventType is the type of DeployEvent
+	DeployEventType string
+)
+
+const (
+	// DeployEventBundleBuilt is sent after the bundle is built and its version is chosen
+	DeployEventBundleBuilt DeployEventType = "bundle-built"
+	// DeployEventValidated is sent after the bundle passes ValidateBundle
+	DeployEventValidated DeployEventType = "validated"
+	// DeployEventVersionCreated is sent after the version is created
+	DeployEventVersionCreated DeployEventType = "version-created"
+	// DeployEventActivationStatus is sent after each activation status check
+	DeployEventActivationStatus DeployEventType = "activation-status"
+	// DeployEventVerified is sent after Verify succeeds on a network
+	DeployEventVerified DeployEventType = "verified"
+	// DeployEventActivationCancelled is sent after a pending activation is cancelled
+	DeployEventActivationCancelled DeployEventType = "activation-cancelled"
+	// DeployEventRollback is sent before the previous version is activated again
+	DeployEventRollback DeployEventType = "rollback"
+)
+
+const (
+	// ActivationStatusComplete is the status of a completed activation
+	ActivationStatusComplete = "COMPLETE"
+	// ActivationStatusAborted is the status of an aborted activation
+	ActivationStatusAborted = "ABORTED"
+	// ActivationStatusCanceled is the status of a cancelled activation
+	ActivationStatusCanceled = "CANCELED"
+)
+
+const (
+	// DefaultActivationPollInterval is the default interval between activation status checks of Deploy
+	DefaultActivationPollInterval = 30 * time.Second
+	// DefaultRollbackTimeout is the default time limit of the rollback activation of Deploy
+	DefaultRollbackTimeout = 30 * time.Minute
+)
+
+// activationCancelTimeout bounds the time spent on cancelling a pending activation
+var activationCancelTimeout = 30 * time.Second
+
+var (
+	// ErrDeploy is returned when Deploy fails
+	ErrDeploy = errors.New("deploy")
+	// ErrBundleInvalid is returned when ValidateBundle reports errors
+	ErrBundleInvalid = errors.New("bundle is invalid")
+	// ErrVersionExists is returned when the bundle version exists and it's not bumped
+	ErrVersionExists = errors.New("version already exists")
+	// ErrActivationFailed is returned when an activation is aborted or cancelled, or its verification fails
+	ErrActivationFailed = errors.New("activation failed")
+	// ErrRollback is returned when the rollback after a failure fails
+	ErrRollback = errors.New("rollback failed")
+
+	versionNumberRegexp = regexp.MustCompile(`^(.*?)(\d+)(\D*)$`)
+)
+
+// Validate validates DeployRequest
+func (r DeployRequest) Validate() error {
+	return validation.Errors{
+		"EdgeWorkerID": validation.Validate(r.EdgeWorkerID, validation.Required),
+		"Source":       validation.Validate(r.Source, validation.Required),
+		"Networks": validation.Validate(r.Networks, validation.Each(validation.In(ActivationNetworkStaging, ActivationNetworkProduction).Error(
+			fmt.Sprintf("must be one of: '%s' or '%s'", ActivationNetworkStaging, ActivationNetworkProduction)))),
+	}.Filter()
+}
+
+// Deploy builds a bundle from DeployRequest.Source, validates it with ValidateBundle, creates a new version
+// and activates it on each network in turn, waiting until every activation completes.
+//
+// When an activation fails, or the context is done while it's pending, the pending activation is cancelled.
+// With DeployRequest.Rollback, the version which was active on the network before is then activated again.
+// Networks following the failed one are not activated.
+//
+// The result describes steps done so far also when an error is returned.
+func Deploy(ctx context.Context, client Edgeworkers, params DeployRequest) (*DeployResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrDeploy, ErrStructValidation, err)
+	}
+	if len(params.Networks) == 0 {
+		params.Networks = []ActivationNetwork{ActivationNetworkStaging, ActivationNetworkProduction}
+	}
+	if params.PollInterval <= 0 {
+		params.PollInterval = DefaultActivationPollInterval
+	}
+	if params.RollbackTimeout <= 0 {
+		params.RollbackTimeout = DefaultRollbackTimeout
+	}
+	d := deployer{client: client, params: params, result: &DeployResult{}}
+
+	if err := d.build(ctx); err != nil {
+		return d.result, fmt.Errorf("%w: %w", ErrDeploy, err)
+	}
+	if err := d.validate(ctx); err != nil {
+		return d.result, fmt.Errorf("%w: %w", ErrDeploy, err)
+	}
+	if _, err := client.CreateEdgeWorkerVersion(ctx, CreateEdgeWorkerVersionRequest{
+		EdgeWorkerID:  params.EdgeWorkerID,
+		ContentBundle: d.result.Bundle.Bundle(),
+	}); err != nil {
+		return d.result, fmt.Errorf("%w: %w", ErrDeploy, err)
+	}
+	if err := d.sendEvent(ctx, DeployEvent{Type: DeployEventVersionCreated, Version: d.result.Version}); err != nil {
+		return d.result, fmt.Errorf("%w: %w", ErrDeploy, err)
+	}
+
+	for _, network := range params.Networks {
+		if err := d.deployTo(ctx, network); err != nil {
+			return d.result, fmt.Errorf("%w: %s: %w", ErrDeploy, network, err)
+		}
+	}
+	return d.result, nil
+}
+
+// NextVersion increments the last number in the version until the result is not one of existing versions,
+// e.g. 1.0.9 becomes 1.0.10, and 1.2-beta becomes 1.3-beta. It returns the version unchanged if it does not exist.
+func NextVersion(version string, existing []string) (string, error) {
+	exists := make(map[string]bool, len(existing))
+	for _, v := range existing {
+		exists[v] = true
+	}
+	parts := versionNumberRegexp.FindStringSubmatch(version)
+	if parts == nil {
+		return "", fmt.Errorf("version %q does not contain a number", version)
+	}
+	number, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("version %q: %w", version, err)
+	}
+	for exists[version] {
+		number++
+		version = parts[1] + strconv.Itoa(number) + parts[3]
+	}
+	return version, nil
+}
+
+// deployer holds the state of a single Deploy
+type deployer struct {
+	client Edgeworkers
+	params DeployRequest
+	result *DeployResult
+}
+
+func (d *deployer) build(ctx context.Context) error {
+	bundle, err := BuildBundle(d.params.Source, d.params.BundleOptions)
+	if err != nil {
+		return err
+	}
+	versions, err := d.client.ListEdgeWorkerVersions(ctx, ListEdgeWorkerVersionsRequest{EdgeWorkerID: d.params.EdgeWorkerID})
+	if err != nil {
+		return err
+	}
+	existing := make([]string, 0, len(versions.EdgeWorkerVersions))
+	for _, v := range versions.EdgeWorkerVersions {
+		existing = append(existing, v.Version)
+	}
+
+	version := bundle.Manifest.EdgeWorkerVersion
+	if d.params.BumpVersion {
+		if version, err = NextVersion(version, existing); err != nil {
+			return err
+		}
+	}
+	for _, v := range existing {
+		if v == version {
+			return fmt.Errorf("%w: %s", ErrVersionExists, version)
+		}
+	}
+	if version != bundle.Manifest.EdgeWorkerVersion {
+		opts := d.params.BundleOptions
+		opts.Version = version
+		if bundle, err = BuildBundle(d.params.Source, opts); err != nil {
+			return err
+		}
+	}
+
+	d.result.Version, d.result.Bundle = version, bundle
+	return d.sendEvent(ctx, DeployEvent{Type: DeployEventBundleBuilt, Version: version})
+}
+
+func (d *deployer) validate(ctx context.Context) error {
+	response, err := d.client.ValidateBundle(ctx, ValidateBundleRequest{Bundle: d.result.Bundle.Bundle()})
+	if err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Type, e.Message))
+		}
+		return fmt.Errorf("%w: %s", ErrBundleInvalid, strings.Join(messages, "; "))
+	}
+	d.result.Warnings = response.Warnings
+	return d.sendEvent(ctx, DeployEvent{Type: DeployEventValidated, Version: d.result.Version, Warnings: response.Warnings})
+}
+
+// deployTo activates the new version on the network and verifies it, rolling back on failure if requested
+func (d *deployer) deployTo(ctx context.Context, network ActivationNetwork) error {
+	var previous string
+	if d.params.Rollback {
+		var err error
+		if previous, err = d.activeVersion(ctx, network); err != nil {
+			return err
+		}
+	}
+
+	err := d.activate(ctx, network, d.result.Version)
+	if err == nil && d.params.Verify != nil {
+		if err = d.params.Verify(ctx, network); err != nil {
+			err = fmt.Errorf("%w: verification: %w", ErrActivationFailed, err)
+		} else {
+			_ = d.sendEvent(ctx, DeployEvent{Type: DeployEventVerified, Version: d.result.Version, Network: network})
+		}
+	}
+	if err == nil || !d.params.Rollback || previous == "" || previous == d.result.Version {
+		return err
+	}
+
+	// the rollback is done also if the context is done, as the network could be left with a broken version
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.params.RollbackTimeout)
+	defer cancel()
+	_ = d.sendEvent(rollbackCtx, DeployEvent{Type: DeployEventRollback, Version: previous, Network: network})
+	if rollbackErr := d.activate(rollbackCtx, network, previous); rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("%w: version %s: %w", ErrRollback, previous, rollbackErr))
+	}
+	d.result.RolledBack = previous
+	return err
+}
+
+// activeVersion returns the version of the latest completed activation on the network, or an empty string
+func (d *deployer) activeVersion(ctx context.Context, network ActivationNetwork) (string, error) {
+	activations, err := d.client.ListActivations(ctx, ListActivationsRequest{EdgeWorkerID: d.params.EdgeWorkerID})
+	if err != nil {
+		return "", err
+	}
+	var latest *Activation
+	for i, a := range activations.Activations {
+		if a.Network != string(network) || a.Status != ActivationStatusComplete {
+			continue
+		}
+		if latest == nil || a.ActivationID > latest.ActivationID {
+			latest = &activations.Activations[i]
+		}
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.Version, nil
+}
+
+// activate activates the version on the network and waits until the activation completes.
+// A pending activation is cancelled when it can't be awaited. Errors of sending events are ignored,
+// as they happen only when the context is done, which is detected while waiting.
+func (d *deployer) activate(ctx context.Context, network ActivationNetwork, version string) error {
+	activation, err := d.client.ActivateVersion(ctx, ActivateVersionRequest{
+		EdgeWorkerID: d.params.EdgeWorkerID,
+		ActivateVersion: ActivateVersion{
+			Network: network,
+			Version: version,
+			Note:    d.params.Note,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	d.result.Activations = append(d.result.Activations, *activation)
+	last := &d.result.Activations[len(d.result.Activations)-1]
+
+	for {
+		*last = *activation
+		_ = d.sendEvent(ctx, DeployEvent{Type: DeployEventActivationStatus, Version: version, Network: network, Activation: activation})
+		switch activation.Status {
+		case ActivationStatusComplete:
+			return nil
+		case ActivationStatusAborted, ActivationStatusCanceled:
+			return fmt.Errorf("%w: activation %d of version %s is %s", ErrActivationFailed, activation.ActivationID, version, activation.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return d.cancel(ctx, activation, fmt.Errorf("waiting for activation %d, last status %s: %w", activation.ActivationID, activation.Status, ctx.Err()))
+		case <-time.After(d.params.PollInterval):
+		}
+		if activation, err = d.client.GetActivation(ctx, GetActivationRequest{
+			EdgeWorkerID: d.params.EdgeWorkerID,
+			ActivationID: activation.ActivationID,
+		}); err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("waiting for activation %d, last status %s: %w", last.ActivationID, last.Status, ctx.Err())
+			}
+			return d.cancel(ctx, last, err)
+		}
+	}
+}
+
+// cancel cancels the pending activation and returns the cause joined with a cancellation error, if any
+func (d *deployer) cancel(ctx context.Context, activation *Activation, cause error) error {
+	// the context may already be done, cancelling must not depend on it
+	cancelCtx, cancelTimeout := context.WithTimeout(context.WithoutCancel(ctx), activationCancelTimeout)
+	defer cancelTimeout()
+	cancelled, err := d.client.CancelPendingActivation(cancelCtx, CancelActivationRequest{
+		EdgeWorkerID: d.params.EdgeWorkerID,
+		ActivationID: activation.ActivationID,
+	})
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	d.result.Activations[len(d.result.Activations)-1] = *cancelled
+	_ = d.sendEvent(ctx, DeployEvent{Type: DeployEventActivationCancelled, Version: activation.Version, Network: ActivationNetwork(activation.Network), Activation: cancelled})
+	return cause
+}
+
+// sendEvent sends the event to DeployRequest.Progress unless the context is done
+func (d *deployer) sendEvent(ctx context.Context, event DeployEvent) error {
+	if d.params.Progress == nil {
+		return nil
+	}
+	select {
+	case d.params.Progress <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,312 @@
+package edgeworkers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployServer emulates versions, validations and activations of EdgeWorker 42.
+// Activations of a version go through statuses scripted for the version and network, ending with COMPLETE by default.
+type deployServer struct {
+	t                *testing.T
+	mu               sync.Mutex
+	versions         []string
+	activations      []Activation
+	statuses         map[string][]string
+	validationErrors []ValidationIssue
+	polls            map[int]int
+	uploadedVersion  string
+}
+
+func newDeployServer(t *testing.T, versions ...string) *deployServer {
+	return &deployServer{
+		t:        t,
+		versions: versions,
+		activations: []Activation{
+			{ActivationID: 1, EdgeWorkerID: 42, Network: "STAGING", Version: "0.9", Status: ActivationStatusComplete},
+			{ActivationID: 2, EdgeWorkerID: 42, Network: "PRODUCTION", Version: "0.8", Status: ActivationStatusComplete},
+			{ActivationID: 3, EdgeWorkerID: 42, Network: "PRODUCTION", Version: "0.9", Status: ActivationStatusComplete},
+			{ActivationID: 4, EdgeWorkerID: 42, Network: "PRODUCTION", Version: "0.95", Status: ActivationStatusAborted},
+		},
+		statuses: make(map[string][]string),
+		polls:    make(map[int]int),
+	}
+}
+
+func (s *deployServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	write := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		assert.NoError(s.t, json.NewEncoder(w).Encode(body))
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/edgeworkers/v1/ids/42/versions":
+		var response ListEdgeWorkerVersionsResponse
+		for _, v := range s.versions {
+			response.EdgeWorkerVersions = append(response.EdgeWorkerVersions, EdgeWorkerVersion{EdgeWorkerID: 42, Version: v})
+		}
+		write(http.StatusOK, response)
+	case r.Method == http.MethodPost && r.URL.Path == "/edgeworkers/v1/validations":
+		write(http.StatusOK, ValidateBundleResponse{
+			Errors:   s.validationErrors,
+			Warnings: []ValidationIssue{{Type: "ACCESS_TOKEN_EXPIRING_SOON", Message: "token expires soon"}},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/edgeworkers/v1/ids/42/versions":
+		content, err := ReadBundle(Bundle{r.Body})
+		require.NoError(s.t, err)
+		s.uploadedVersion = content.Manifest.EdgeWorkerVersion
+		s.versions = append(s.versions, s.uploadedVersion)
+		write(http.StatusCreated, EdgeWorkerVersion{EdgeWorkerID: 42, Version: s.uploadedVersion})
+	case r.Method == http.MethodGet && r.URL.Path == "/edgeworkers/v1/ids/42/activations":
+		write(http.StatusOK, ListActivationsResponse{Activations: s.activations})
+	case r.Method == http.MethodPost && r.URL.Path == "/edgeworkers/v1/ids/42/activations":
+		var body ActivateVersion
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		activation := Activation{
+			ActivationID: len(s.activations) + 1,
+			EdgeWorkerID: 42,
+			Network:      string(body.Network),
+			Version:      body.Version,
+			Note:         body.Note,
+			Status:       "PRESUBMIT",
+		}
+		s.activations = append(s.activations, activation)
+		write(http.StatusCreated, activation)
+	case strings.HasPrefix(r.URL.Path, "/edgeworkers/v1/ids/42/activations/"):
+		var id int
+		_, err := fmt.Sscanf(r.URL.Path, "/edgeworkers/v1/ids/42/activations/%d", &id)
+		require.NoError(s.t, err)
+		activation := &s.activations[id-1]
+		if r.Method == http.MethodDelete {
+			activation.Status = ActivationStatusCanceled
+		} else {
+			statuses := append(s.statuses[activation.Version+"/"+activation.Network], ActivationStatusComplete)
+			activation.Status = statuses[min(s.polls[id], len(statuses)-1)]
+			s.polls[id]++
+		}
+		write(http.StatusOK, activation)
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func pendingStatuses(n int) []string {
+	statuses := make([]string, n)
+	for i := range statuses {
+		statuses[i] = "PENDING"
+	}
+	return statuses
+}
+
+func TestDeploy(t *testing.T) {
+	source := fstest.MapFS{
+		"bundle.json": {Data: []byte(`{"edgeworker-version": "1.0", "description": "test"}`)},
+		"main.js":     {Data: []byte(`export function onClientRequest(r) {}`)},
+	}
+
+	tests := map[string]struct {
+		versions          []string
+		params            DeployRequest
+		statuses          map[string][]string
+		validationErrors  []ValidationIssue
+		timeout           time.Duration
+		expectedVersion   string
+		notUploaded       bool
+		expectedRollback  string
+		expectedStatuses  []string
+		withError         []error
+		withErrorContains string
+	}{
+		"staging and production": {
+			versions:         []string{"0.9"},
+			params:           DeployRequest{Note: "release"},
+			statuses:         map[string][]string{"1.0/STAGING": {"PENDING", "IN_PROGRESS"}},
+			expectedVersion:  "1.0",
+			expectedStatuses: []string{"STAGING 1.0 COMPLETE", "PRODUCTION 1.0 COMPLETE"},
+		},
+		"bumped version": {
+			versions:         []string{"0.9", "1.0", "1.1"},
+			params:           DeployRequest{BumpVersion: true, Networks: []ActivationNetwork{ActivationNetworkStaging}},
+			expectedVersion:  "1.2",
+			expectedStatuses: []string{"STAGING 1.2 COMPLETE"},
+		},
+		"version exists": {
+			versions:          []string{"1.0"},
+			withError:         []error{ErrVersionExists},
+			withErrorContains: "version already exists: 1.0",
+		},
+		"invalid bundle": {
+			validationErrors:  []ValidationIssue{{Type: "STATIC_VALIDATION_FAILED", Message: "syntax error"}},
+			expectedVersion:   "1.0",
+			notUploaded:       true,
+			withError:         []error{ErrBundleInvalid},
+			withErrorContains: "STATIC_VALIDATION_FAILED: syntax error",
+		},
+		"production aborted, rolled back": {
+			params:            DeployRequest{Rollback: true},
+			statuses:          map[string][]string{"1.0/PRODUCTION": {"PENDING", ActivationStatusAborted}},
+			expectedVersion:   "1.0",
+			expectedRollback:  "0.9",
+			expectedStatuses:  []string{"STAGING 1.0 COMPLETE", "PRODUCTION 1.0 ABORTED", "PRODUCTION 0.9 COMPLETE"},
+			withError:         []error{ErrActivationFailed},
+			withErrorContains: "PRODUCTION: activation failed: activation 6 of version 1.0 is ABORTED",
+		},
+		"verification failed, rolled back": {
+			params: DeployRequest{Rollback: true, Networks: []ActivationNetwork{ActivationNetworkStaging}, Verify: func(_ context.Context, network ActivationNetwork) error {
+				return errors.New("smoke test failed")
+			}},
+			expectedVersion:   "1.0",
+			expectedRollback:  "0.9",
+			expectedStatuses:  []string{"STAGING 1.0 COMPLETE", "STAGING 0.9 COMPLETE"},
+			withError:         []error{ErrActivationFailed},
+			withErrorContains: "verification: smoke test failed",
+		},
+		"rollback timeout, rollback cancelled": {
+			params: DeployRequest{Rollback: true, RollbackTimeout: 50 * time.Millisecond, Networks: []ActivationNetwork{ActivationNetworkStaging}, Verify: func(_ context.Context, network ActivationNetwork) error {
+				return errors.New("smoke test failed")
+			}},
+			statuses:          map[string][]string{"0.9/STAGING": pendingStatuses(1000)},
+			expectedVersion:   "1.0",
+			expectedStatuses:  []string{"STAGING 1.0 COMPLETE", "STAGING 0.9 CANCELED"},
+			withError:         []error{ErrActivationFailed, ErrRollback, context.DeadlineExceeded},
+			withErrorContains: "rollback failed: version 0.9: waiting for activation 6",
+		},
+		"timeout, activation cancelled": {
+			params:            DeployRequest{Networks: []ActivationNetwork{ActivationNetworkStaging}, PollInterval: time.Hour},
+			timeout:           50 * time.Millisecond,
+			expectedVersion:   "1.0",
+			expectedStatuses:  []string{"STAGING 1.0 CANCELED"},
+			withError:         []error{context.DeadlineExceeded},
+			withErrorContains: "waiting for activation 5, last status PRESUBMIT",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newDeployServer(t, test.versions...)
+			server.statuses = test.statuses
+			server.validationErrors = test.validationErrors
+			mockServer := httptest.NewTLSServer(server)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			ctx := context.Background()
+			if test.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.timeout)
+				defer cancel()
+			}
+			params := test.params
+			params.EdgeWorkerID = 42
+			params.Source = source
+			if params.PollInterval == 0 {
+				params.PollInterval = 5 * time.Millisecond
+			}
+			result, err := Deploy(ctx, client, params)
+			if len(test.withError) > 0 {
+				assert.True(t, errors.Is(err, ErrDeploy), "want: %s; got: %s", ErrDeploy, err)
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				assert.Contains(t, err.Error(), test.withErrorContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, []ValidationIssue{{Type: "ACCESS_TOKEN_EXPIRING_SOON", Message: "token expires soon"}}, result.Warnings)
+			}
+			require.NotNil(t, result)
+			assert.Equal(t, test.expectedVersion, result.Version)
+			if !test.notUploaded {
+				assert.Equal(t, test.expectedVersion, server.uploadedVersion)
+			}
+			assert.Equal(t, test.expectedRollback, result.RolledBack)
+			var statuses []string
+			for _, a := range result.Activations {
+				statuses = append(statuses, fmt.Sprintf("%s %s %s", a.Network, a.Version, a.Status))
+				assert.Equal(t, params.Note, a.Note)
+			}
+			assert.Equal(t, test.expectedStatuses, statuses)
+		})
+	}
+}
+
+func TestDeployEvents(t *testing.T) {
+	server := newDeployServer(t)
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	events := make(chan DeployEvent)
+	var received []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range events {
+			received = append(received, strings.TrimSpace(fmt.Sprintf("%s %s %s", e.Type, e.Version, e.Network)))
+		}
+	}()
+	_, err := Deploy(context.Background(), client, DeployRequest{
+		EdgeWorkerID: 42,
+		Source: fstest.MapFS{
+			"bundle.json": {Data: []byte(`{"edgeworker-version": "1.0"}`)},
+			"main.js":     {Data: []byte(`export function onClientRequest(r) {}`)},
+		},
+		Networks:     []ActivationNetwork{ActivationNetworkStaging},
+		Verify:       func(context.Context, ActivationNetwork) error { return nil },
+		PollInterval: time.Millisecond,
+		Progress:     events,
+	})
+	close(events)
+	<-done
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"bundle-built 1.0",
+		"validated 1.0",
+		"version-created 1.0",
+		"activation-status 1.0 STAGING",
+		"activation-status 1.0 STAGING",
+		"verified 1.0 STAGING",
+	}, received)
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := map[string]struct {
+		version   string
+		existing  []string
+		expected  string
+		withError bool
+	}{
+		"not existing":        {version: "1.0", existing: []string{"0.9"}, expected: "1.0"},
+		"last number":         {version: "1.0.9", existing: []string{"1.0.9", "1.0.10"}, expected: "1.0.11"},
+		"pre-release suffix":  {version: "1.2-beta", existing: []string{"1.2-beta"}, expected: "1.3-beta"},
+		"no number":           {version: "latest", existing: []string{"latest"}, withError: true},
+		"single number":       {version: "7", existing: []string{"7", "8"}, expected: "9"},
+		"number with leading": {version: "v01", existing: []string{"v01"}, expected: "v2"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, err := NextVersion(test.version, test.existing)
+			if test.withError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, version)
+		})
+	}
+}
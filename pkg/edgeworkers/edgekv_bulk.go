@@ -0,0 +1,522 @@
+package edgeworkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// EdgeKVItem is an item of a namespace with its group, as exported and imported by bulk operations
+	EdgeKVItem struct {
+		GroupID string `json:"groupId"`
+		ItemID  string `json:"itemId"`
+		Value   Item   `json:"value"`
+	}
+
+	// EdgeKVItemKey identifies an item within a namespace
+	EdgeKVItemKey struct {
+		GroupID string `json:"groupId"`
+		ItemID  string `json:"itemId"`
+	}
+
+	// BulkOptions contains options of bulk item operations
+	BulkOptions struct {
+		// Concurrency is the maximum number of concurrent requests. Defaults to DefaultBulkConcurrency
+		Concurrency int
+		// RequestsPerSecond limits the rate of item requests. No limit if zero
+		RequestsPerSecond int
+	}
+
+	// BulkResult reports the outcome of a bulk item operation. Items neither done nor failed were not processed,
+	// e.g. because the context was cancelled.
+	BulkResult struct {
+		// Done contains keys of items processed successfully, sorted. They can be passed as Skip to resume the operation
+		Done []EdgeKVItemKey `json:"done"`
+		// Errors contains errors of failed items, sorted by key
+		Errors []BulkItemError `json:"-"`
+	}
+
+	// BulkItemError is an error of a single item of a bulk operation
+	BulkItemError struct {
+		EdgeKVItemKey
+		Operation BulkOperation
+		Err       error
+	}
+
+	// BulkOperation is the operation done on an item by a bulk operation
+	BulkOperation string
+
+	// ExportItemsRequest contains parameters of ExportItems
+	ExportItemsRequest struct {
+		Network     ItemNetwork
+		NamespaceID string
+		// GroupIDs limits the export to the groups. All groups of the namespace are exported if empty
+		GroupIDs []string
+		BulkOptions
+	}
+
+	// ImportItemsRequest contains parameters of ImportItems
+	ImportItemsRequest struct {
+		Network     ItemNetwork
+		NamespaceID string
+		Items       []EdgeKVItem
+		// Skip contains keys of items which are not imported, e.g. BulkResult.Done of an interrupted import
+		Skip []EdgeKVItemKey
+		BulkOptions
+	}
+
+	// SyncItemsRequest contains parameters of SyncItems
+	SyncItemsRequest struct {
+		Network     ItemNetwork
+		NamespaceID string
+		// Items is the desired set of items
+		Items []EdgeKVItem
+		// GroupIDs are the groups managed by the sync. Defaults to groups of Items
+		GroupIDs []string
+		// Delete removes items of managed groups which are not in Items
+		Delete bool
+		// DryRun only plans changes
+		DryRun bool
+		// Skip contains keys of items which are not changed, e.g. BulkResult.Done of an interrupted sync
+		Skip []EdgeKVItemKey
+		BulkOptions
+	}
+
+	// SyncItemsResult contains changes planned and applied by SyncItems
+	SyncItemsResult struct {
+		// Changes are sorted by key, unchanged items are not included
+		Changes []EdgeKVItemChange
+		// Result reports applied changes, nil for a dry run
+		Result *BulkResult
+	}
+
+	// EdgeKVItemChange is a change of an item planned by SyncItems
+	EdgeKVItemChange struct {
+		EdgeKVItemKey
+		Operation BulkOperation
+		// Value is the desired value, empty for deletes
+		Value Item
+	}
+)
+
+const (
+	// BulkOperationGet is the operation of fetching an item
+	BulkOperationGet BulkOperation = "get"
+	// BulkOperationCreate is the operation of creating an item
+	BulkOperationCreate BulkOperation = "create"
+	// BulkOperationUpdate is the operation of updating an item
+	BulkOperationUpdate BulkOperation = "update"
+	// BulkOperationUpsert is the operation of creating or updating an item
+	BulkOperationUpsert BulkOperation = "upsert"
+	// BulkOperationDelete is the operation of deleting an item
+	BulkOperationDelete BulkOperation = "delete"
+
+	// DefaultBulkConcurrency is the default number of concurrent requests of bulk item operations
+	DefaultBulkConcurrency = 4
+)
+
+var (
+	// ErrExportItems is returned when ExportItems fails
+	ErrExportItems = errors.New("export items")
+	// ErrImportItems is returned when ImportItems fails
+	ErrImportItems = errors.New("import items")
+	// ErrSyncItems is returned when SyncItems fails
+	ErrSyncItems = errors.New("sync items")
+	// ErrBulkItems is returned when some items of a bulk operation fail
+	ErrBulkItems = errors.New("items failed")
+	// ErrReadItems is returned when items can't be read with ReadEdgeKVItems
+	ErrReadItems = errors.New("read items")
+)
+
+// Key returns the key of the item
+func (i EdgeKVItem) Key() EdgeKVItemKey {
+	return EdgeKVItemKey{GroupID: i.GroupID, ItemID: i.ItemID}
+}
+
+// Error returns the error message with the item key and operation
+func (e BulkItemError) Error() string {
+	return fmt.Sprintf("%s %s/%s: %s", e.Operation, e.GroupID, e.ItemID, e.Err)
+}
+
+// Unwrap returns the error of the item
+func (e BulkItemError) Unwrap() error {
+	return e.Err
+}
+
+// Validate validates BulkOptions
+func (o BulkOptions) Validate() error {
+	return validation.Errors{
+		"Concurrency":       validation.Validate(o.Concurrency, validation.Min(0)),
+		"RequestsPerSecond": validation.Validate(o.RequestsPerSecond, validation.Min(0)),
+	}.Filter()
+}
+
+// Validate validates ExportItemsRequest
+func (r ExportItemsRequest) Validate() error {
+	return validation.Errors{
+		"Network":     validateItemNetwork(r.Network),
+		"NamespaceID": validation.Validate(r.NamespaceID, validation.Required),
+		"BulkOptions": r.BulkOptions.Validate(),
+	}.Filter()
+}
+
+// Validate validates ImportItemsRequest
+func (r ImportItemsRequest) Validate() error {
+	return validation.Errors{
+		"Network":     validateItemNetwork(r.Network),
+		"NamespaceID": validation.Validate(r.NamespaceID, validation.Required),
+		"Items":       validateItems(r.Items),
+		"BulkOptions": r.BulkOptions.Validate(),
+	}.Filter()
+}
+
+// Validate validates SyncItemsRequest
+func (r SyncItemsRequest) Validate() error {
+	return validation.Errors{
+		"Network":     validateItemNetwork(r.Network),
+		"NamespaceID": validation.Validate(r.NamespaceID, validation.Required),
+		"Items":       validateItems(r.Items),
+		"BulkOptions": r.BulkOptions.Validate(),
+	}.Filter()
+}
+
+func validateItemNetwork(network ItemNetwork) error {
+	return validation.Validate(network, validation.Required, validation.In(ItemStagingNetwork, ItemProductionNetwork).Error(
+		fmt.Sprintf("value '%s' is invalid. Must be one of: '%s' or '%s'", network, ItemStagingNetwork, ItemProductionNetwork)))
+}
+
+func validateItems(items []EdgeKVItem) error {
+	seen := make(map[EdgeKVItemKey]bool, len(items))
+	for i, item := range items {
+		if item.GroupID == "" || item.ItemID == "" {
+			return fmt.Errorf("%d: group and item IDs are required", i)
+		}
+		if item.Value == "" {
+			return fmt.Errorf("%d: value of %s/%s is required", i, item.GroupID, item.ItemID)
+		}
+		if seen[item.Key()] {
+			return fmt.Errorf("%d: duplicate item %s/%s", i, item.GroupID, item.ItemID)
+		}
+		seen[item.Key()] = true
+	}
+	return nil
+}
+
+// ExportItems fetches all items of groups of the namespace. Items are sorted by group and item ID.
+// Items which can't be fetched are reported in the result, and ErrBulkItems is returned with the exported items.
+func ExportItems(ctx context.Context, client Edgeworkers, params ExportItemsRequest) ([]EdgeKVItem, *BulkResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w: %s", ErrExportItems, ErrStructValidation, err)
+	}
+
+	groups := params.GroupIDs
+	if len(groups) == 0 {
+		var err error
+		groups, err = client.ListGroupsWithinNamespace(ctx, ListGroupsWithinNamespaceRequest{
+			Network:     NamespaceNetwork(params.Network),
+			NamespaceID: params.NamespaceID,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrExportItems, err)
+		}
+	}
+	keys, err := listItemKeys(ctx, client, params.Network, params.NamespaceID, groups)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrExportItems, err)
+	}
+
+	values, result := getItems(ctx, client, params.Network, params.NamespaceID, keys, params.BulkOptions)
+	items := make([]EdgeKVItem, 0, len(values))
+	for _, key := range result.Done {
+		items = append(items, EdgeKVItem{GroupID: key.GroupID, ItemID: key.ItemID, Value: values[key]})
+	}
+	if err := result.err(ctx); err != nil {
+		return items, result, fmt.Errorf("%w: %w", ErrExportItems, err)
+	}
+	return items, result, nil
+}
+
+// ImportItems creates or updates items with bounded concurrency and rate. Failed items are reported in the result
+// and ErrBulkItems is returned. An interrupted import can be resumed by passing BulkResult.Done as ImportItemsRequest.Skip.
+func ImportItems(ctx context.Context, client Edgeworkers, params ImportItemsRequest) (*BulkResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrImportItems, ErrStructValidation, err)
+	}
+
+	changes := make([]EdgeKVItemChange, 0, len(params.Items))
+	for _, item := range params.Items {
+		changes = append(changes, EdgeKVItemChange{EdgeKVItemKey: item.Key(), Operation: BulkOperationUpsert, Value: item.Value})
+	}
+	result := applyChanges(ctx, client, params.Network, params.NamespaceID, skipChanges(changes, params.Skip), params.BulkOptions)
+	if err := result.err(ctx); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrImportItems, err)
+	}
+	return result, nil
+}
+
+// SyncItems brings items of the managed groups to the desired state. Current items are listed and fetched
+// to plan creates, updates of items with different values and, with SyncItemsRequest.Delete, deletes of items
+// which are not desired. Planned changes are then applied like in ImportItems.
+func SyncItems(ctx context.Context, client Edgeworkers, params SyncItemsRequest) (*SyncItemsResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrSyncItems, ErrStructValidation, err)
+	}
+
+	groups := params.GroupIDs
+	if len(groups) == 0 {
+		seen := make(map[string]bool)
+		for _, item := range params.Items {
+			if !seen[item.GroupID] {
+				seen[item.GroupID] = true
+				groups = append(groups, item.GroupID)
+			}
+		}
+	}
+	keys, err := listItemKeys(ctx, client, params.Network, params.NamespaceID, groups)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSyncItems, err)
+	}
+
+	desired := make(map[EdgeKVItemKey]Item, len(params.Items))
+	for _, item := range params.Items {
+		desired[item.Key()] = item.Value
+	}
+	var fetch []EdgeKVItemKey
+	for _, key := range keys {
+		if _, ok := desired[key]; ok {
+			fetch = append(fetch, key)
+		}
+	}
+	current, fetched := getItems(ctx, client, params.Network, params.NamespaceID, fetch, params.BulkOptions)
+	if err := fetched.err(ctx); err != nil {
+		return nil, fmt.Errorf("%w: fetching current items: %w", ErrSyncItems, err)
+	}
+
+	existing := make(map[EdgeKVItemKey]bool, len(keys))
+	var changes []EdgeKVItemChange
+	for _, key := range keys {
+		existing[key] = true
+		value, ok := desired[key]
+		switch {
+		case !ok && params.Delete:
+			changes = append(changes, EdgeKVItemChange{EdgeKVItemKey: key, Operation: BulkOperationDelete})
+		case ok && current[key] != value:
+			changes = append(changes, EdgeKVItemChange{EdgeKVItemKey: key, Operation: BulkOperationUpdate, Value: value})
+		}
+	}
+	for _, item := range params.Items {
+		if !existing[item.Key()] {
+			changes = append(changes, EdgeKVItemChange{EdgeKVItemKey: item.Key(), Operation: BulkOperationCreate, Value: item.Value})
+		}
+	}
+	changes = skipChanges(changes, params.Skip)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].EdgeKVItemKey.less(changes[j].EdgeKVItemKey) })
+
+	syncResult := &SyncItemsResult{Changes: changes}
+	if params.DryRun {
+		return syncResult, nil
+	}
+	syncResult.Result = applyChanges(ctx, client, params.Network, params.NamespaceID, changes, params.BulkOptions)
+	if err := syncResult.Result.err(ctx); err != nil {
+		return syncResult, fmt.Errorf("%w: %w", ErrSyncItems, err)
+	}
+	return syncResult, nil
+}
+
+// WriteEdgeKVItemsJSON writes items as an indented JSON array
+func WriteEdgeKVItemsJSON(w io.Writer, items []EdgeKVItem) error {
+	if items == nil {
+		items = []EdgeKVItem{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// WriteEdgeKVItemsNDJSON writes items as newline delimited JSON, one item per line
+func WriteEdgeKVItemsNDJSON(w io.Writer, items []EdgeKVItem) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadEdgeKVItems reads items written by WriteEdgeKVItemsJSON or WriteEdgeKVItemsNDJSON. The format is detected
+// from the first character: a JSON array or newline delimited JSON objects.
+func ReadEdgeKVItems(r io.Reader) ([]EdgeKVItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadItems, err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var items []EdgeKVItem
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := decoder.Decode(&items); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadItems, err)
+		}
+		return items, nil
+	}
+	for line := 1; decoder.More(); line++ {
+		var item EdgeKVItem
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("%w: item %d: %w", ErrReadItems, line, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (k EdgeKVItemKey) less(other EdgeKVItemKey) bool {
+	if k.GroupID != other.GroupID {
+		return k.GroupID < other.GroupID
+	}
+	return k.ItemID < other.ItemID
+}
+
+// err returns ErrBulkItems if any item failed, or the context error if the operation was interrupted
+func (r *BulkResult) err(ctx context.Context) error {
+	if len(r.Errors) > 0 {
+		return fmt.Errorf("%w: %d of %d: %w", ErrBulkItems, len(r.Errors), len(r.Errors)+len(r.Done), r.Errors[0])
+	}
+	return ctx.Err()
+}
+
+// listItemKeys lists items of the groups
+func listItemKeys(ctx context.Context, client Edgeworkers, network ItemNetwork, namespaceID string, groups []string) ([]EdgeKVItemKey, error) {
+	var keys []EdgeKVItemKey
+	for _, group := range groups {
+		items, err := client.ListItems(ctx, ListItemsRequest{ItemsRequestParams: ItemsRequestParams{
+			Network:     network,
+			NamespaceID: namespaceID,
+			GroupID:     group,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group, err)
+		}
+		for _, id := range *items {
+			keys = append(keys, EdgeKVItemKey{GroupID: group, ItemID: id})
+		}
+	}
+	return keys, nil
+}
+
+func skipChanges(changes []EdgeKVItemChange, skip []EdgeKVItemKey) []EdgeKVItemChange {
+	if len(skip) == 0 {
+		return changes
+	}
+	skipped := make(map[EdgeKVItemKey]bool, len(skip))
+	for _, key := range skip {
+		skipped[key] = true
+	}
+	result := make([]EdgeKVItemChange, 0, len(changes))
+	for _, c := range changes {
+		if !skipped[c.EdgeKVItemKey] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func getItems(ctx context.Context, client Edgeworkers, network ItemNetwork, namespaceID string, keys []EdgeKVItemKey, opts BulkOptions) (map[EdgeKVItemKey]Item, *BulkResult) {
+	var mu sync.Mutex
+	values := make(map[EdgeKVItemKey]Item, len(keys))
+	changes := make([]EdgeKVItemChange, 0, len(keys))
+	for _, key := range keys {
+		changes = append(changes, EdgeKVItemChange{EdgeKVItemKey: key, Operation: BulkOperationGet})
+	}
+	result := runBulk(ctx, changes, opts, func(ctx context.Context, c EdgeKVItemChange) error {
+		item, err := client.GetItem(ctx, GetItemRequest{ItemID: c.ItemID, ItemsRequestParams: ItemsRequestParams{
+			Network:     network,
+			NamespaceID: namespaceID,
+			GroupID:     c.GroupID,
+		}})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		values[c.EdgeKVItemKey] = *item
+		return nil
+	})
+	return values, result
+}
+
+func applyChanges(ctx context.Context, client Edgeworkers, network ItemNetwork, namespaceID string, changes []EdgeKVItemChange, opts BulkOptions) *BulkResult {
+	return runBulk(ctx, changes, opts, func(ctx context.Context, c EdgeKVItemChange) error {
+		params := ItemsRequestParams{Network: network, NamespaceID: namespaceID, GroupID: c.GroupID}
+		var err error
+		if c.Operation == BulkOperationDelete {
+			_, err = client.DeleteItem(ctx, DeleteItemRequest{ItemID: c.ItemID, ItemsRequestParams: params})
+		} else {
+			_, err = client.UpsertItem(ctx, UpsertItemRequest{ItemID: c.ItemID, ItemData: c.Value, ItemsRequestParams: params})
+		}
+		return err
+	})
+}
+
+// runBulk calls fn for each change with at most opts.Concurrency concurrent calls, started at most
+// opts.RequestsPerSecond times per second. No calls are started once the context is done.
+func runBulk(ctx context.Context, changes []EdgeKVItemChange, opts BulkOptions, fn func(context.Context, EdgeKVItemChange) error) *BulkResult {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBulkConcurrency
+	}
+	var tick <-chan time.Time
+	if opts.RequestsPerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.RequestsPerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	result := &BulkResult{Done: []EdgeKVItemKey{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i, c := range changes {
+		if i > 0 && tick != nil {
+			select {
+			case <-ctx.Done():
+			case <-tick:
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case slots <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(c EdgeKVItemChange) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			err := fn(ctx, c)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Errors = append(result.Errors, BulkItemError{EdgeKVItemKey: c.EdgeKVItemKey, Operation: c.Operation, Err: err})
+				return
+			}
+			result.Done = append(result.Done, c.EdgeKVItemKey)
+		}(c)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Done, func(i, j int) bool { return result.Done[i].less(result.Done[j]) })
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].less(result.Errors[j].EdgeKVItemKey) })
+	return result
+}
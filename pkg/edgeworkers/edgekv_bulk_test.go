@@ -0,0 +1,265 @@
+package edgeworkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// edgeKVServer serves items of namespace 'ns' on the staging network from memory
+type edgeKVServer struct {
+	t        *testing.T
+	mu       sync.Mutex
+	items    map[string]map[string]string
+	failing  map[string]bool
+	requests []string
+	inFlight int
+	maxInFlt int
+}
+
+func newEdgeKVServer(t *testing.T, items map[string]map[string]string) *edgeKVServer {
+	return &edgeKVServer{t: t, items: items, failing: make(map[string]bool)}
+}
+
+func (s *edgeKVServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlt {
+		s.maxInFlt = s.inFlight
+	}
+	s.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+
+	path := strings.TrimPrefix(r.URL.Path, "/edgekv/v1/networks/staging/namespaces/ns/groups")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if r.Method != http.MethodGet || len(parts) > 1 {
+		s.requests = append(s.requests, r.Method+" "+path)
+	}
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		groups := make([]string, 0, len(s.items))
+		for g := range s.items {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+		assert.NoError(s.t, json.NewEncoder(w).Encode(groups))
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		ids := []string{}
+		for id := range s.items[parts[0]] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		assert.NoError(s.t, json.NewEncoder(w).Encode(ids))
+	case len(parts) == 3 && parts[1] == "items":
+		group, id := parts[0], parts[2]
+		if s.failing[group+"/"+id] {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"type": "error", "title": "Server Error", "status": 500}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			value, ok := s.items[group][id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"type": "not-found", "title": "Not Found", "status": 404}`))
+				return
+			}
+			_, _ = w.Write([]byte(value))
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			require.NoError(s.t, err)
+			if s.items[group] == nil {
+				s.items[group] = make(map[string]string)
+			}
+			s.items[group][id] = string(data)
+			_, _ = w.Write([]byte("Item was upserted in KV store with database 123456, namespace ns, group " + group + ", and key " + id + "."))
+		case http.MethodDelete:
+			delete(s.items[group], id)
+			_, _ = w.Write([]byte("Item was marked for deletion from database."))
+		}
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestExportItems(t *testing.T) {
+	server := newEdgeKVServer(t, map[string]map[string]string{
+		"countries": {"de": `{"name":"Germany"}`, "pl": `{"name":"Poland"}`},
+		"flags":     {"beta": "on", "broken": "x"},
+	})
+	server.failing["flags/broken"] = true
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	items, result, err := ExportItems(context.Background(), client, ExportItemsRequest{Network: ItemStagingNetwork, NamespaceID: "ns", BulkOptions: BulkOptions{Concurrency: 2}})
+	assert.True(t, errors.Is(err, ErrBulkItems), "want: %s; got: %s", ErrBulkItems, err)
+	assert.Contains(t, err.Error(), "export items: items failed: 1 of 4: get flags/broken: get item")
+	assert.Equal(t, []EdgeKVItem{
+		{GroupID: "countries", ItemID: "de", Value: `{"name":"Germany"}`},
+		{GroupID: "countries", ItemID: "pl", Value: `{"name":"Poland"}`},
+		{GroupID: "flags", ItemID: "beta", Value: "on"},
+	}, items)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, EdgeKVItemKey{GroupID: "flags", ItemID: "broken"}, result.Errors[0].EdgeKVItemKey)
+	assert.LessOrEqual(t, server.maxInFlt, 2)
+
+	items, _, err = ExportItems(context.Background(), client, ExportItemsRequest{Network: ItemStagingNetwork, NamespaceID: "ns", GroupIDs: []string{"countries"}})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	_, _, err = ExportItems(context.Background(), client, ExportItemsRequest{Network: "test", NamespaceID: "ns"})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+}
+
+func TestImportItems(t *testing.T) {
+	server := newEdgeKVServer(t, map[string]map[string]string{})
+	server.failing["flags/b"] = true
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	items := []EdgeKVItem{
+		{GroupID: "flags", ItemID: "a", Value: "1"},
+		{GroupID: "flags", ItemID: "b", Value: "2"},
+		{GroupID: "flags", ItemID: "c", Value: `{"x":3}`},
+	}
+	params := ImportItemsRequest{Network: ItemStagingNetwork, NamespaceID: "ns", Items: items, BulkOptions: BulkOptions{RequestsPerSecond: 100}}
+	result, err := ImportItems(context.Background(), client, params)
+	assert.True(t, errors.Is(err, ErrBulkItems), "want: %s; got: %s", ErrBulkItems, err)
+	assert.Equal(t, []EdgeKVItemKey{{GroupID: "flags", ItemID: "a"}, {GroupID: "flags", ItemID: "c"}}, result.Done)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, BulkOperationUpsert, result.Errors[0].Operation)
+	var apiErr *Error
+	assert.True(t, errors.As(result.Errors[0], &apiErr))
+	assert.Equal(t, map[string]string{"a": "1", "c": `{"x":3}`}, server.items["flags"])
+
+	// resume with items done before
+	server.failing["flags/b"] = false
+	server.requests = nil
+	params.Skip = result.Done
+	result, err = ImportItems(context.Background(), client, params)
+	require.NoError(t, err)
+	assert.Equal(t, []EdgeKVItemKey{{GroupID: "flags", ItemID: "b"}}, result.Done)
+	assert.Equal(t, []string{"PUT /flags/items/b"}, server.requests)
+
+	// cancelled import processes no items
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = ImportItems(ctx, client, ImportItemsRequest{Network: ItemStagingNetwork, NamespaceID: "ns", Items: items})
+	assert.True(t, errors.Is(err, context.Canceled), "want: %s; got: %s", context.Canceled, err)
+	assert.Empty(t, result.Done)
+
+	_, err = ImportItems(context.Background(), client, ImportItemsRequest{Network: ItemStagingNetwork, NamespaceID: "ns", Items: append(items, items[0])})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "duplicate item flags/a")
+}
+
+func TestSyncItems(t *testing.T) {
+	desired := []EdgeKVItem{
+		{GroupID: "flags", ItemID: "same", Value: "1"},
+		{GroupID: "flags", ItemID: "changed", Value: "new"},
+		{GroupID: "flags", ItemID: "added", Value: "3"},
+	}
+	expectedChanges := []EdgeKVItemChange{
+		{EdgeKVItemKey: EdgeKVItemKey{GroupID: "flags", ItemID: "added"}, Operation: BulkOperationCreate, Value: "3"},
+		{EdgeKVItemKey: EdgeKVItemKey{GroupID: "flags", ItemID: "changed"}, Operation: BulkOperationUpdate, Value: "new"},
+		{EdgeKVItemKey: EdgeKVItemKey{GroupID: "flags", ItemID: "removed"}, Operation: BulkOperationDelete},
+	}
+	newServer := func() *edgeKVServer {
+		return newEdgeKVServer(t, map[string]map[string]string{
+			"flags": {"same": "1", "changed": "old", "removed": "x"},
+			"other": {"kept": "y"},
+		})
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		server := newServer()
+		mockServer := httptest.NewTLSServer(server)
+		defer mockServer.Close()
+
+		result, err := SyncItems(context.Background(), mockAPIClient(t, mockServer), SyncItemsRequest{
+			Network: ItemStagingNetwork, NamespaceID: "ns", Items: desired, Delete: true, DryRun: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedChanges, result.Changes)
+		assert.Nil(t, result.Result)
+		assert.Len(t, server.items["flags"], 3)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		server := newServer()
+		mockServer := httptest.NewTLSServer(server)
+		defer mockServer.Close()
+
+		result, err := SyncItems(context.Background(), mockAPIClient(t, mockServer), SyncItemsRequest{
+			Network: ItemStagingNetwork, NamespaceID: "ns", Items: desired, Delete: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedChanges, result.Changes)
+		assert.Len(t, result.Result.Done, 3)
+		assert.Equal(t, map[string]map[string]string{
+			"flags": {"same": "1", "changed": "new", "added": "3"},
+			"other": {"kept": "y"},
+		}, server.items)
+	})
+
+	t.Run("without delete", func(t *testing.T) {
+		server := newServer()
+		mockServer := httptest.NewTLSServer(server)
+		defer mockServer.Close()
+
+		result, err := SyncItems(context.Background(), mockAPIClient(t, mockServer), SyncItemsRequest{
+			Network: ItemStagingNetwork, NamespaceID: "ns", Items: desired, DryRun: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedChanges[:2], result.Changes)
+	})
+}
+
+func TestReadWriteEdgeKVItems(t *testing.T) {
+	items := []EdgeKVItem{
+		{GroupID: "countries", ItemID: "de", Value: `{"name":"Germany"}`},
+		{GroupID: "flags", ItemID: "beta", Value: "on"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteEdgeKVItemsNDJSON(&buf, items))
+	assert.Equal(t, `{"groupId":"countries","itemId":"de","value":"{\"name\":\"Germany\"}"}
+{"groupId":"flags","itemId":"beta","value":"on"}
+`, buf.String())
+	read, err := ReadEdgeKVItems(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, items, read)
+
+	buf.Reset()
+	require.NoError(t, WriteEdgeKVItemsJSON(&buf, items))
+	read, err = ReadEdgeKVItems(strings.NewReader("\n  " + buf.String()))
+	require.NoError(t, err)
+	assert.Equal(t, items, read)
+
+	read, err = ReadEdgeKVItems(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, read)
+
+	_, err = ReadEdgeKVItems(strings.NewReader(`{"groupId":"a"}` + "\n{"))
+	assert.True(t, errors.Is(err, ErrReadItems), "want: %s; got: %s", ErrReadItems, err)
+	assert.Contains(t, err.Error(), "item 2")
+}
@@ -0,0 +1,356 @@
+package edgeworkers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// EdgeKVTokenManager rotates EdgeKV access tokens before they expire and removes tokens replaced by newer ones.
+	//
+	// Tokens are grouped by base name: a replacement token is named after the base name with the creation date
+	// appended, e.g. 'app-20241018' replaces 'app' or 'app-20240101'. The newest token of each group is current,
+	// the others are superseded. Base names are shortened to fit the date, see TokenBaseName, so names of unrelated
+	// tokens have to differ within their first 23 characters.
+	EdgeKVTokenManager struct {
+		Client Edgeworkers
+		// RenewBefore is the time before expiry when tokens are rotated. Defaults to DefaultTokenRenewBefore
+		RenewBefore time.Duration
+		// EdgeWorkerIDs are EdgeWorkers whose active versions are checked for references before superseded tokens are deleted.
+		// All EdgeWorkers of the account are checked if not set
+		EdgeWorkerIDs []int
+		// Now returns the current time. Defaults to time.Now
+		Now func() time.Time
+	}
+
+	// EdgeKVTokenRotation describes a token replaced by RotateExpiring
+	EdgeKVTokenRotation struct {
+		Old EdgeKVAccessToken
+		New *CreateEdgeKVAccessTokenResponse
+	}
+
+	// EdgeKVTokenCleanup describes the result of DeleteSuperseded
+	EdgeKVTokenCleanup struct {
+		// Deleted are names of deleted tokens
+		Deleted []string
+		// InUse maps names of superseded tokens which were kept to active EdgeWorker versions referencing them
+		InUse map[string][]string
+	}
+)
+
+const (
+	// DefaultTokenRenewBefore is the default time before expiry when EdgeKVTokenManager rotates tokens
+	DefaultTokenRenewBefore = 30 * 24 * time.Hour
+	// EdgeKVTokensFile is the name of the file with access tokens included in EdgeWorker bundles
+	EdgeKVTokensFile = "edgekv_tokens.js"
+
+	// maxTokenNameLength is the maximum length of a token name
+	maxTokenNameLength = 32
+	// tokenDateFormat is the format of the date appended to names of replacement tokens
+	tokenDateFormat = "20060102"
+	// maxTokenBaseNameLength is the maximum length of a base name followed by a hyphen and the date
+	maxTokenBaseNameLength = maxTokenNameLength - len(tokenDateFormat) - 1
+)
+
+var (
+	// ErrEdgeKVTokenManager is returned when EdgeKVTokenManager fails
+	ErrEdgeKVTokenManager = errors.New("manage EdgeKV access tokens")
+
+	tokenDateSuffixRegexp = regexp.MustCompile(`-\d{8}$`)
+)
+
+// Expiring returns current tokens which expire within RenewBefore
+func (m *EdgeKVTokenManager) Expiring(ctx context.Context) ([]EdgeKVAccessToken, error) {
+	current, _, err := m.tokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	renewBefore := m.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = DefaultTokenRenewBefore
+	}
+	deadline := m.now().Add(renewBefore)
+
+	var expiring []EdgeKVAccessToken
+	for _, token := range current {
+		expiry, err := parseTokenDate(token.Expiry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: token %s: %w", ErrEdgeKVTokenManager, token.Name, err)
+		}
+		if expiry.Before(deadline) {
+			expiring = append(expiring, token)
+		}
+	}
+	return expiring, nil
+}
+
+// Rotate creates a replacement of the token with the same namespace permissions, networks and EdgeWorker restrictions.
+// The old token is not deleted, as it stays in use until EdgeWorker versions with the new token are activated.
+func (m *EdgeKVTokenManager) Rotate(ctx context.Context, tokenName string) (*CreateEdgeKVAccessTokenResponse, error) {
+	old, err := m.Client.GetEdgeKVAccessToken(ctx, GetEdgeKVAccessTokenRequest{TokenName: tokenName})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	name := ReplacementTokenName(tokenName, m.now())
+	if name == tokenName {
+		return nil, fmt.Errorf("%w: token %s was already rotated today", ErrEdgeKVTokenManager, tokenName)
+	}
+	token, err := m.Client.CreateEdgeKVAccessToken(ctx, CreateEdgeKVAccessTokenRequest{
+		AllowOnProduction:       old.AllowOnProduction,
+		AllowOnStaging:          old.AllowOnStaging,
+		Name:                    name,
+		NamespacePermissions:    old.NamespacePermissions,
+		RestrictToEdgeWorkerIDs: old.RestrictToEdgeWorkerIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: rotating %s: %w", ErrEdgeKVTokenManager, tokenName, err)
+	}
+	return token, nil
+}
+
+// RotateExpiring rotates all current tokens returned by Expiring
+func (m *EdgeKVTokenManager) RotateExpiring(ctx context.Context) ([]EdgeKVTokenRotation, error) {
+	expiring, err := m.Expiring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rotations []EdgeKVTokenRotation
+	for _, token := range expiring {
+		replacement, err := m.Rotate(ctx, token.Name)
+		if err != nil {
+			return rotations, err
+		}
+		rotations = append(rotations, EdgeKVTokenRotation{Old: token, New: replacement})
+	}
+	return rotations, nil
+}
+
+// WriteTokensJS writes edgekv_tokens.js with current tokens, see WriteEdgeKVTokensJS
+func (m *EdgeKVTokenManager) WriteTokensJS(ctx context.Context, w io.Writer) error {
+	current, _, err := m.tokens(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	tokens := make([]CreateEdgeKVAccessTokenResponse, 0, len(current))
+	for _, t := range current {
+		token, err := m.Client.GetEdgeKVAccessToken(ctx, GetEdgeKVAccessTokenRequest{TokenName: t.Name})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+		}
+		tokens = append(tokens, CreateEdgeKVAccessTokenResponse(*token))
+	}
+	if err := WriteEdgeKVTokensJS(w, tokens); err != nil {
+		return fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	return nil
+}
+
+// DeleteSuperseded deletes superseded tokens which are not referenced by versions active on staging or production
+// of EdgeWorkerIDs, or of all EdgeWorkers if EdgeWorkerIDs are not set. A version references a token if any of its text files contains the token UUID or quoted name.
+func (m *EdgeKVTokenManager) DeleteSuperseded(ctx context.Context) (*EdgeKVTokenCleanup, error) {
+	_, superseded, err := m.tokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	cleanup := &EdgeKVTokenCleanup{InUse: make(map[string][]string)}
+	if len(superseded) == 0 {
+		return cleanup, nil
+	}
+
+	bundles, err := m.activeBundles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+	}
+	for _, token := range superseded {
+		for _, b := range bundles {
+			if b.references(token) {
+				cleanup.InUse[token.Name] = append(cleanup.InUse[token.Name], b.description)
+			}
+		}
+		if len(cleanup.InUse[token.Name]) > 0 {
+			continue
+		}
+		if _, err := m.Client.DeleteEdgeKVAccessToken(ctx, DeleteEdgeKVAccessTokenRequest{TokenName: token.Name}); err != nil {
+			return cleanup, fmt.Errorf("%w: %w", ErrEdgeKVTokenManager, err)
+		}
+		cleanup.Deleted = append(cleanup.Deleted, token.Name)
+	}
+	return cleanup, nil
+}
+
+// ReplacementTokenName returns the name of a token replacing the given one, created at the time.
+// The date is appended to the base name returned by TokenBaseName.
+func ReplacementTokenName(name string, now time.Time) string {
+	return TokenBaseName(name) + "-" + now.UTC().Format(tokenDateFormat)
+}
+
+// TokenBaseName returns the name without the date appended by ReplacementTokenName, shortened to 23 characters
+// so that the name with the date fits 32 characters. A token and its replacements have the same base name.
+func TokenBaseName(name string) string {
+	base := tokenDateSuffixRegexp.ReplaceAllString(name, "")
+	if len(base) > maxTokenBaseNameLength {
+		base = base[:maxTokenBaseNameLength]
+	}
+	return base
+}
+
+// WriteEdgeKVTokensJS writes edgekv_tokens.js, which EdgeWorker code bundles include to access EdgeKV namespaces.
+// Each namespace is mapped to the first token permitted to access it.
+func WriteEdgeKVTokensJS(w io.Writer, tokens []CreateEdgeKVAccessTokenResponse) error {
+	type tokenReference struct {
+		Name      string `json:"name"`
+		Reference string `json:"reference"`
+	}
+	references := make(map[string]tokenReference)
+	for _, token := range tokens {
+		for namespace := range token.NamespacePermissions {
+			if _, ok := references[namespace]; !ok {
+				references[namespace] = tokenReference{Name: token.Name, Reference: token.UUID}
+			}
+		}
+	}
+	namespaces := make([]string, 0, len(references))
+	for namespace := range references {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var b strings.Builder
+	b.WriteString("var edgekv_access_tokens = {\n")
+	for i, namespace := range namespaces {
+		key, err := json.Marshal("namespace-" + namespace)
+		if err != nil {
+			return err
+		}
+		value, err := json.MarshalIndent(references[namespace], "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "  %s: %s", key, value)
+		if i < len(namespaces)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("};\nexport { edgekv_access_tokens };\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m *EdgeKVTokenManager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// tokens lists tokens and splits them into current tokens, the newest of each base name, and superseded ones
+func (m *EdgeKVTokenManager) tokens(ctx context.Context) (current, superseded []EdgeKVAccessToken, err error) {
+	list, err := m.Client.ListEdgeKVAccessTokens(ctx, ListEdgeKVAccessTokensRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make(map[string][]EdgeKVAccessToken)
+	var bases []string
+	for _, token := range list.Tokens {
+		base := TokenBaseName(token.Name)
+		if _, ok := groups[base]; !ok {
+			bases = append(bases, base)
+		}
+		groups[base] = append(groups[base], token)
+	}
+	sort.Strings(bases)
+	for _, base := range bases {
+		tokens := groups[base]
+		// dates sort chronologically, and a token without a date is the oldest
+		sort.Slice(tokens, func(i, j int) bool {
+			if di, dj := tokenDateSuffixRegexp.FindString(tokens[i].Name), tokenDateSuffixRegexp.FindString(tokens[j].Name); di != dj {
+				return di > dj
+			}
+			return tokens[i].Name > tokens[j].Name
+		})
+		current = append(current, tokens[0])
+		superseded = append(superseded, tokens[1:]...)
+	}
+	return current, superseded, nil
+}
+
+// activeBundle is the content of an EdgeWorker version active on a network
+type activeBundle struct {
+	description string
+	content     *BundleContent
+}
+
+func (b activeBundle) references(token EdgeKVAccessToken) bool {
+	for _, f := range b.content.Files {
+		if !isText(f.Content) {
+			continue
+		}
+		if token.UUID != "" && strings.Contains(string(f.Content), token.UUID) ||
+			strings.Contains(string(f.Content), `"`+token.Name+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeBundles fetches content of versions of EdgeWorkerIDs whose activations are the latest completed on each network
+func (m *EdgeKVTokenManager) activeBundles(ctx context.Context) ([]activeBundle, error) {
+	ids := m.EdgeWorkerIDs
+	if len(ids) == 0 {
+		list, err := m.Client.ListEdgeWorkersID(ctx, ListEdgeWorkersIDRequest{})
+		if err != nil {
+			return nil, err
+		}
+		for _, ew := range list.EdgeWorkers {
+			ids = append(ids, ew.EdgeWorkerID)
+		}
+	}
+	var bundles []activeBundle
+	for _, id := range ids {
+		activations, err := m.Client.ListActivations(ctx, ListActivationsRequest{EdgeWorkerID: id})
+		if err != nil {
+			return nil, err
+		}
+		latest := make(map[string]Activation)
+		for _, a := range activations.Activations {
+			if a.Status == ActivationStatusComplete && a.ActivationID > latest[a.Network].ActivationID {
+				latest[a.Network] = a
+			}
+		}
+		for _, network := range []ActivationNetwork{ActivationNetworkStaging, ActivationNetworkProduction} {
+			a, ok := latest[string(network)]
+			if !ok {
+				continue
+			}
+			bundle, err := m.Client.GetEdgeWorkerVersionContent(ctx, GetEdgeWorkerVersionContentRequest{EdgeWorkerID: id, Version: a.Version})
+			if err != nil {
+				return nil, err
+			}
+			content, err := ReadBundle(*bundle)
+			if err != nil {
+				return nil, fmt.Errorf("EdgeWorker %d version %s: %w", id, a.Version, err)
+			}
+			bundles = append(bundles, activeBundle{
+				description: fmt.Sprintf("EdgeWorker %d version %s on %s", id, a.Version, network),
+				content:     content,
+			})
+		}
+	}
+	return bundles, nil
+}
+
+// parseTokenDate parses a date of a token, e.g. '2024-12-31'
+func parseTokenDate(date string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, date); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, date)
+}
@@ -0,0 +1,218 @@
+package edgeworkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer serves EdgeKV access tokens and activations of EdgeWorker 42 with versions built by testBundle
+type tokenServer struct {
+	t           *testing.T
+	tokens      map[string]CreateEdgeKVAccessTokenResponse
+	activations []Activation
+	versions    map[string]*BuiltBundle
+	created     []CreateEdgeKVAccessTokenRequest
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	write := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		assert.NoError(s.t, json.NewEncoder(w).Encode(body))
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/edgekv/v1/tokens/")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/edgekv/v1/tokens":
+		var response ListEdgeKVAccessTokensResponse
+		for _, token := range s.tokens {
+			response.Tokens = append(response.Tokens, EdgeKVAccessToken{Name: token.Name, UUID: token.UUID, Expiry: token.Expiry})
+		}
+		write(http.StatusOK, response)
+	case r.Method == http.MethodPost && r.URL.Path == "/edgekv/v1/tokens":
+		var body CreateEdgeKVAccessTokenRequest
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.created = append(s.created, body)
+		token := CreateEdgeKVAccessTokenResponse{
+			Name:                    body.Name,
+			UUID:                    "uuid-" + body.Name,
+			Expiry:                  "2025-04-18",
+			AllowOnProduction:       body.AllowOnProduction,
+			AllowOnStaging:          body.AllowOnStaging,
+			NamespacePermissions:    body.NamespacePermissions,
+			RestrictToEdgeWorkerIDs: body.RestrictToEdgeWorkerIDs,
+		}
+		s.tokens[token.Name] = token
+		write(http.StatusOK, token)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/edgekv/v1/tokens/"):
+		write(http.StatusOK, s.tokens[name])
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/edgekv/v1/tokens/"):
+		token := s.tokens[name]
+		delete(s.tokens, name)
+		write(http.StatusOK, DeleteEdgeKVAccessTokenResponse{Name: token.Name, UUID: token.UUID})
+	case r.Method == http.MethodGet && r.URL.Path == "/edgeworkers/v1/ids":
+		write(http.StatusOK, ListEdgeWorkersIDResponse{EdgeWorkers: []EdgeWorkerID{{EdgeWorkerID: 42}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/edgeworkers/v1/ids/42/activations":
+		write(http.StatusOK, ListActivationsResponse{Activations: s.activations})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/edgeworkers/v1/ids/42/versions/"):
+		version := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/edgeworkers/v1/ids/42/versions/"), "/content")
+		bundle, ok := s.versions[version]
+		if !ok {
+			s.t.Errorf("unexpected version content request: %s", version)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		_, _ = w.Write(bundle.Data)
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	permissions := NamespacePermissions{"ns": []Permission{PermissionRead}}
+	return &tokenServer{
+		t: t,
+		tokens: map[string]CreateEdgeKVAccessTokenResponse{
+			"app":             {Name: "app", UUID: "uuid-app", Expiry: "2024-05-01", NamespacePermissions: permissions},
+			"app-20240101":    {Name: "app-20240101", UUID: "uuid-app-20240101", Expiry: "2024-11-01", AllowOnStaging: true, NamespacePermissions: permissions, RestrictToEdgeWorkerIDs: []string{"42"}},
+			"legacy-20230101": {Name: "legacy-20230101", UUID: "uuid-legacy-20230101", Expiry: "2024-07-01", NamespacePermissions: NamespacePermissions{"old": []Permission{PermissionRead}}},
+			"legacy-20240601": {Name: "legacy-20240601", UUID: "uuid-legacy-20240601", Expiry: "2025-06-01", NamespacePermissions: NamespacePermissions{"old": []Permission{PermissionRead}}},
+		},
+		activations: []Activation{
+			{ActivationID: 1, EdgeWorkerID: 42, Network: "STAGING", Version: "1", Status: ActivationStatusComplete},
+			{ActivationID: 2, EdgeWorkerID: 42, Network: "STAGING", Version: "2", Status: ActivationStatusAborted},
+		},
+		versions: map[string]*BuiltBundle{
+			"1": testBundle(t, "export function onClientRequest(r) {}\n", fstest.MapFS{
+				"edgekv_tokens.js": {Data: []byte(`var edgekv_access_tokens = {"namespace-ns": {"name": "app", "reference": "uuid-app"}};`)},
+			}),
+		},
+	}
+}
+
+func TestEdgeKVTokenManager(t *testing.T) {
+	server := newTokenServer(t)
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	manager := &EdgeKVTokenManager{
+		Client:        mockAPIClient(t, mockServer),
+		EdgeWorkerIDs: []int{42},
+		Now:           func() time.Time { return time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC) },
+	}
+
+	expiring, err := manager.Expiring(context.Background())
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+	assert.Equal(t, "app-20240101", expiring[0].Name)
+
+	rotations, err := manager.RotateExpiring(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rotations, 1)
+	assert.Equal(t, "app-20241018", rotations[0].New.Name)
+	assert.Equal(t, []CreateEdgeKVAccessTokenRequest{{
+		AllowOnStaging:          true,
+		Name:                    "app-20241018",
+		NamespacePermissions:    NamespacePermissions{"ns": []Permission{PermissionRead}},
+		RestrictToEdgeWorkerIDs: []string{"42"},
+	}}, server.created)
+
+	_, err = manager.Rotate(context.Background(), "app-20241018")
+	assert.True(t, errors.Is(err, ErrEdgeKVTokenManager), "want: %s; got: %s", ErrEdgeKVTokenManager, err)
+
+	var js bytes.Buffer
+	require.NoError(t, manager.WriteTokensJS(context.Background(), &js))
+	assert.Contains(t, js.String(), `"reference": "uuid-app-20241018"`)
+	assert.Contains(t, js.String(), `"reference": "uuid-legacy-20240601"`)
+
+	// 'app' is referenced by version 1 active on staging, 'app-20240101' and 'legacy-20230101' by none
+	cleanup, err := manager.DeleteSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app-20240101", "legacy-20230101"}, cleanup.Deleted)
+	assert.Equal(t, map[string][]string{"app": {"EdgeWorker 42 version 1 on STAGING"}}, cleanup.InUse)
+	assert.Len(t, server.tokens, 3)
+}
+
+func TestEdgeKVTokenManager_ShortenedBaseName(t *testing.T) {
+	server := newTokenServer(t)
+	long := strings.Repeat("a", 30)
+	server.tokens = map[string]CreateEdgeKVAccessTokenResponse{
+		long: {Name: long, UUID: "uuid-long", Expiry: "2024-11-01", AllowOnStaging: true, NamespacePermissions: NamespacePermissions{"ns": []Permission{PermissionRead}}},
+	}
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	manager := &EdgeKVTokenManager{
+		Client: mockAPIClient(t, mockServer),
+		Now:    func() time.Time { return time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC) },
+	}
+
+	rotations, err := manager.RotateExpiring(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rotations, 1)
+	replacement := strings.Repeat("a", 23) + "-20241018"
+	assert.Equal(t, replacement, rotations[0].New.Name)
+
+	expiring, err := manager.Expiring(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, expiring)
+	var js bytes.Buffer
+	require.NoError(t, manager.WriteTokensJS(context.Background(), &js))
+	assert.Contains(t, js.String(), `"reference": "uuid-`+replacement+`"`)
+
+	// without EdgeWorkerIDs, active versions of all EdgeWorkers are checked
+	cleanup, err := manager.DeleteSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{long}, cleanup.Deleted)
+	assert.Empty(t, cleanup.InUse)
+	assert.Len(t, server.tokens, 1)
+}
+
+func TestReplacementTokenName(t *testing.T) {
+	now := time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		name     string
+		expected string
+	}{
+		"without date":             {name: "app", expected: "app-20241018"},
+		"with date":                {name: "app-20240101", expected: "app-20241018"},
+		"shortened base":           {name: strings.Repeat("a", 30), expected: strings.Repeat("a", 23) + "-20241018"},
+		"shortened base with date": {name: strings.Repeat("a", 23) + "-20240101", expected: strings.Repeat("a", 23) + "-20241018"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ReplacementTokenName(test.name, now))
+		})
+	}
+}
+
+func TestWriteEdgeKVTokensJS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteEdgeKVTokensJS(&buf, []CreateEdgeKVAccessTokenResponse{
+		{Name: "b", UUID: "uuid-b", NamespacePermissions: NamespacePermissions{"ns2": nil, "ns1": nil}},
+		{Name: "a", UUID: "uuid-a", NamespacePermissions: NamespacePermissions{"ns1": nil}},
+	}))
+	assert.Equal(t, `var edgekv_access_tokens = {
+  "namespace-ns1": {
+    "name": "b",
+    "reference": "uuid-b"
+  },
+  "namespace-ns2": {
+    "name": "b",
+    "reference": "uuid-b"
+  }
+};
+export { edgekv_access_tokens };
+`, buf.String())
+}
@@ -0,0 +1,167 @@
+package edgeworkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// ItemCodec encodes values of type T as EdgeKV items and decodes them back
+	ItemCodec[T any] interface {
+		Encode(T) (Item, error)
+		Decode(Item) (T, error)
+	}
+
+	// JSONCodec stores values as JSON
+	JSONCodec[T any] struct{}
+
+	// StringCodec stores strings as plain text
+	StringCodec struct{}
+
+	// BytesCodec stores byte slices as standard base64, as items can only hold text
+	BytesCodec struct{}
+
+	// GobCodec stores values encoded with encoding/gob as standard base64
+	GobCodec[T any] struct{}
+
+	// UpsertItemValueRequest contains parameters of UpsertItemValue
+	UpsertItemValueRequest[T any] struct {
+		ItemID string
+		Value  T
+		// Codec encodes the value. Defaults to JSONCodec
+		Codec ItemCodec[T]
+		ItemsRequestParams
+	}
+)
+
+const (
+	// MaxItemValueSize is the maximum size of an item value in bytes
+	MaxItemValueSize = 1 << 20
+	// MaxItemIDLength is the maximum length of an item ID
+	MaxItemIDLength = 512
+)
+
+var (
+	// ErrItemCodec is returned when an item value can't be encoded or decoded
+	ErrItemCodec = errors.New("item codec")
+	// ErrItemTooLarge is returned when an item value exceeds MaxItemValueSize
+	ErrItemTooLarge = errors.New("item value too large")
+)
+
+// Encode encodes the value as JSON
+func (JSONCodec[T]) Encode(value T) (Item, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return Item(data), nil
+}
+
+// Decode decodes the JSON item
+func (JSONCodec[T]) Decode(item Item) (T, error) {
+	var value T
+	err := json.Unmarshal([]byte(item), &value)
+	return value, err
+}
+
+// Encode returns the string as an item
+func (StringCodec) Encode(value string) (Item, error) {
+	return Item(value), nil
+}
+
+// Decode returns the item as a string
+func (StringCodec) Decode(item Item) (string, error) {
+	return string(item), nil
+}
+
+// Encode encodes the bytes with base64
+func (BytesCodec) Encode(value []byte) (Item, error) {
+	return Item(base64.StdEncoding.EncodeToString(value)), nil
+}
+
+// Decode decodes the base64 item
+func (BytesCodec) Decode(item Item) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(item))
+}
+
+// Encode encodes the value with gob and base64
+func (GobCodec[T]) Encode(value T) (Item, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return "", err
+	}
+	return Item(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// Decode decodes the base64 gob item
+func (GobCodec[T]) Decode(item Item) (T, error) {
+	var value T
+	data, err := base64.StdEncoding.DecodeString(string(item))
+	if err != nil {
+		return value, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// ValidateItemSize checks that the item value does not exceed MaxItemValueSize
+func ValidateItemSize(item Item) error {
+	if len(item) > MaxItemValueSize {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrItemTooLarge, len(item), MaxItemValueSize)
+	}
+	return nil
+}
+
+// Validate validates UpsertItemValueRequest
+func (r UpsertItemValueRequest[T]) Validate() error {
+	return validation.Errors{
+		"ItemID":             validation.Validate(r.ItemID, validation.Required, validation.Length(1, MaxItemIDLength)),
+		"ItemsRequestParams": validation.Validate(r.ItemsRequestParams, validation.Required),
+	}.Filter()
+}
+
+// GetItemAs fetches the item and decodes its value with the codec, JSONCodec if nil
+func GetItemAs[T any](ctx context.Context, client Edgeworkers, params GetItemRequest, codec ItemCodec[T]) (T, error) {
+	var value T
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	item, err := client.GetItem(ctx, params)
+	if err != nil {
+		return value, err
+	}
+	if value, err = codec.Decode(*item); err != nil {
+		return value, fmt.Errorf("%s: %w: decoding %s/%s: %w", ErrGetItem, ErrItemCodec, params.GroupID, params.ItemID, err)
+	}
+	return value, nil
+}
+
+// UpsertItemValue encodes the value with the codec, checks its size and creates or updates the item
+func UpsertItemValue[T any](ctx context.Context, client Edgeworkers, params UpsertItemValueRequest[T]) (*string, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrUpsertItem, ErrStructValidation, err)
+	}
+	codec := params.Codec
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	item, err := codec.Encode(params.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: encoding %s/%s: %w", ErrUpsertItem, ErrItemCodec, params.GroupID, params.ItemID, err)
+	}
+	if err := ValidateItemSize(item); err != nil {
+		return nil, fmt.Errorf("%s: %s/%s: %w", ErrUpsertItem, params.GroupID, params.ItemID, err)
+	}
+	return client.UpsertItem(ctx, UpsertItemRequest{
+		ItemID:             params.ItemID,
+		ItemData:           item,
+		ItemsRequestParams: params.ItemsRequestParams,
+	})
+}
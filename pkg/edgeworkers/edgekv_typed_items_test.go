@@ -0,0 +1,92 @@
+package edgeworkers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Origin  string   `json:"origin"`
+	Weights []int    `json:"weights"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func TestItemCodecs(t *testing.T) {
+	config := testConfig{Origin: "origin.example.com", Weights: []int{1, 2}}
+
+	item, err := JSONCodec[testConfig]{}.Encode(config)
+	require.NoError(t, err)
+	assert.Equal(t, Item(`{"origin":"origin.example.com","weights":[1,2]}`), item)
+	decoded, err := JSONCodec[testConfig]{}.Decode(item)
+	require.NoError(t, err)
+	assert.Equal(t, config, decoded)
+
+	item, err = BytesCodec{}.Encode([]byte{0, 1, 0xff})
+	require.NoError(t, err)
+	assert.Equal(t, Item("AAH/"), item)
+	data, err := BytesCodec{}.Decode(item)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 0xff}, data)
+
+	item, err = GobCodec[testConfig]{}.Encode(config)
+	require.NoError(t, err)
+	decoded, err = GobCodec[testConfig]{}.Decode(item)
+	require.NoError(t, err)
+	assert.Equal(t, config, decoded)
+
+	item, err = StringCodec{}.Encode("plain")
+	require.NoError(t, err)
+	s, err := StringCodec{}.Decode(item)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", s)
+
+	_, err = BytesCodec{}.Decode("not base64!")
+	assert.Error(t, err)
+}
+
+func TestTypedItems(t *testing.T) {
+	server := newEdgeKVServer(t, map[string]map[string]string{"config": {"broken": "{"}})
+	mockServer := httptest.NewTLSServer(server)
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+	params := ItemsRequestParams{Network: ItemStagingNetwork, NamespaceID: "ns", GroupID: "config"}
+
+	config := testConfig{Origin: "origin.example.com", Weights: []int{3}, Tags: []string{"a"}}
+	_, err := UpsertItemValue(context.Background(), client, UpsertItemValueRequest[testConfig]{ItemID: "main", Value: config, ItemsRequestParams: params})
+	require.NoError(t, err)
+	assert.Equal(t, `{"origin":"origin.example.com","weights":[3],"tags":["a"]}`, server.items["config"]["main"])
+
+	fetched, err := GetItemAs[testConfig](context.Background(), client, GetItemRequest{ItemID: "main", ItemsRequestParams: params}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, config, fetched)
+
+	_, err = UpsertItemValue(context.Background(), client, UpsertItemValueRequest[[]byte]{ItemID: "blob", Value: []byte{1, 2, 3}, Codec: BytesCodec{}, ItemsRequestParams: params})
+	require.NoError(t, err)
+	blob, err := GetItemAs[[]byte](context.Background(), client, GetItemRequest{ItemID: "blob", ItemsRequestParams: params}, BytesCodec{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, blob)
+
+	_, err = GetItemAs[testConfig](context.Background(), client, GetItemRequest{ItemID: "broken", ItemsRequestParams: params}, nil)
+	assert.True(t, errors.Is(err, ErrItemCodec), "want: %s; got: %s", ErrItemCodec, err)
+	assert.Contains(t, err.Error(), "decoding config/broken")
+
+	_, err = GetItemAs[testConfig](context.Background(), client, GetItemRequest{ItemID: "missing", ItemsRequestParams: params}, nil)
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr), "want: %T; got: %s", apiErr, err)
+
+	_, err = UpsertItemValue(context.Background(), client, UpsertItemValueRequest[string]{ItemID: "large", Value: strings.Repeat("a", MaxItemValueSize+1), Codec: StringCodec{}, ItemsRequestParams: params})
+	assert.True(t, errors.Is(err, ErrItemTooLarge), "want: %s; got: %s", ErrItemTooLarge, err)
+
+	_, err = UpsertItemValue(context.Background(), client, UpsertItemValueRequest[string]{ItemID: strings.Repeat("a", MaxItemIDLength+1), Value: "v", ItemsRequestParams: params})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+
+	_, err = UpsertItemValue(context.Background(), client, UpsertItemValueRequest[func()]{ItemID: "func", Value: func() {}, ItemsRequestParams: params})
+	assert.True(t, errors.Is(err, ErrItemCodec), "want: %s; got: %s", ErrItemCodec, err)
+	assert.NotContains(t, server.items["config"], "large")
+}
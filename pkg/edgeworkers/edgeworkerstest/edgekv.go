@@ -0,0 +1,294 @@
+package edgeworkerstest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgeworkers"
+)
+
+type (
+	edgeKV struct {
+		initialized bool
+		// namespaces maps networks to namespaces
+		namespaces map[string]map[string]*namespace
+		tokens     map[string]*edgeworkers.CreateEdgeKVAccessTokenResponse
+		nextToken  int
+	}
+
+	namespace struct {
+		edgeworkers.Namespace
+		// groups maps group IDs to items
+		groups map[string]map[string]string
+	}
+)
+
+const (
+	// TokenValidity is the time emulated access tokens are valid for
+	TokenValidity = 180 * 24 * time.Hour
+
+	// errorCodeNotFound is the error code making edgeworkers.Error match edgeworkers.ErrNotFound
+	errorCodeNotFound = "EKV_9000"
+	edgeKVCPCode      = "1234567"
+)
+
+func newEdgeKV() edgeKV {
+	return edgeKV{
+		namespaces: map[string]map[string]*namespace{
+			string(edgeworkers.NamespaceStagingNetwork):    {},
+			string(edgeworkers.NamespaceProductionNetwork): {},
+		},
+		tokens: make(map[string]*edgeworkers.CreateEdgeKVAccessTokenResponse),
+	}
+}
+
+func (s *Server) registerEdgeKVRoutes() {
+	s.handle(http.MethodPut, "/edgekv/v1/initialize", s.initializeEdgeKV)
+	s.handle(http.MethodGet, "/edgekv/v1/initialize", s.getEdgeKVInitializationStatus)
+
+	s.handle(http.MethodGet, "/edgekv/v1/networks/*/namespaces", s.withNetwork(s.listNamespaces))
+	s.handle(http.MethodPost, "/edgekv/v1/networks/*/namespaces", s.withNetwork(s.createNamespace))
+	s.handle(http.MethodGet, "/edgekv/v1/networks/*/namespaces/*", s.withNamespace(s.getNamespace))
+	s.handle(http.MethodPut, "/edgekv/v1/networks/*/namespaces/*", s.withNamespace(s.updateNamespace))
+
+	s.handle(http.MethodGet, "/edgekv/v1/networks/*/namespaces/*/groups", s.withNamespace(s.listGroups))
+	s.handle(http.MethodGet, "/edgekv/v1/networks/*/namespaces/*/groups/*", s.withNamespace(s.listItems))
+	s.handle(http.MethodGet, "/edgekv/v1/networks/*/namespaces/*/groups/*/items/*", s.withNamespace(s.getItem))
+	s.handle(http.MethodPut, "/edgekv/v1/networks/*/namespaces/*/groups/*/items/*", s.withNamespace(s.upsertItem))
+	s.handle(http.MethodDelete, "/edgekv/v1/networks/*/namespaces/*/groups/*/items/*", s.withNamespace(s.deleteItem))
+
+	s.handle(http.MethodGet, "/edgekv/v1/tokens", s.listTokens)
+	s.handle(http.MethodPost, "/edgekv/v1/tokens", s.createToken)
+	s.handle(http.MethodGet, "/edgekv/v1/tokens/*", s.getToken)
+	s.handle(http.MethodDelete, "/edgekv/v1/tokens/*", s.deleteToken)
+}
+
+// SetItem stores the item, creating its namespace on the network if needed. It can be used to seed the emulator
+func (s *Server) SetItem(network edgeworkers.ItemNetwork, namespaceID, groupID, itemID string, value edgeworkers.Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns, ok := s.edgeKV.namespaces[string(network)][namespaceID]
+	if !ok {
+		ns = &namespace{Namespace: edgeworkers.Namespace{Name: namespaceID}, groups: make(map[string]map[string]string)}
+		s.edgeKV.namespaces[string(network)][namespaceID] = ns
+	}
+	ns.setItem(groupID, itemID, string(value))
+}
+
+// withNetwork resolves the network from the first path parameter
+func (s *Server) withNetwork(handler func(http.ResponseWriter, *http.Request, map[string]*namespace, []string)) func(http.ResponseWriter, *http.Request, []string) {
+	return func(w http.ResponseWriter, r *http.Request, params []string) {
+		namespaces, ok := s.edgeKV.namespaces[params[0]]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "", "invalid network '%s'", params[0])
+			return
+		}
+		handler(w, r, namespaces, params[1:])
+	}
+}
+
+// withNamespace resolves the network and namespace from the first two path parameters
+func (s *Server) withNamespace(handler func(http.ResponseWriter, *http.Request, *namespace, []string)) func(http.ResponseWriter, *http.Request, []string) {
+	return s.withNetwork(func(w http.ResponseWriter, r *http.Request, namespaces map[string]*namespace, params []string) {
+		ns, ok := namespaces[params[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, errorCodeNotFound, "namespace %s does not exist", params[0])
+			return
+		}
+		handler(w, r, ns, params[1:])
+	})
+}
+
+func (s *Server) initializeEdgeKV(w http.ResponseWriter, _ *http.Request, _ []string) {
+	s.edgeKV.initialized = true
+	writeJSON(w, http.StatusCreated, s.edgeKVInitializationStatus())
+}
+
+func (s *Server) getEdgeKVInitializationStatus(w http.ResponseWriter, _ *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.edgeKVInitializationStatus())
+}
+
+func (s *Server) edgeKVInitializationStatus() edgeworkers.EdgeKVInitializationStatus {
+	status := "UNINITIALIZED"
+	if s.edgeKV.initialized {
+		status = "INITIALIZED"
+	}
+	return edgeworkers.EdgeKVInitializationStatus{
+		AccountStatus:    status,
+		CPCode:           edgeKVCPCode,
+		ProductionStatus: status,
+		StagingStatus:    status,
+	}
+}
+
+func (s *Server) listNamespaces(w http.ResponseWriter, r *http.Request, namespaces map[string]*namespace, _ []string) {
+	response := edgeworkers.ListEdgeKVNamespacesResponse{Namespaces: []edgeworkers.Namespace{}}
+	for _, name := range sortedKeys(namespaces) {
+		if r.URL.Query().Get("details") == "on" {
+			response.Namespaces = append(response.Namespaces, namespaces[name].Namespace)
+			continue
+		}
+		response.Namespaces = append(response.Namespaces, edgeworkers.Namespace{Name: name})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) createNamespace(w http.ResponseWriter, r *http.Request, namespaces map[string]*namespace, _ []string) {
+	var body edgeworkers.Namespace
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if _, ok := namespaces[body.Name]; ok {
+		writeError(w, http.StatusConflict, "", "namespace %s already exists", body.Name)
+		return
+	}
+	namespaces[body.Name] = &namespace{Namespace: body, groups: make(map[string]map[string]string)}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) getNamespace(w http.ResponseWriter, _ *http.Request, ns *namespace, _ []string) {
+	writeJSON(w, http.StatusOK, ns.Namespace)
+}
+
+func (s *Server) updateNamespace(w http.ResponseWriter, r *http.Request, ns *namespace, _ []string) {
+	var body edgeworkers.UpdateNamespace
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Name != ns.Name {
+		writeError(w, http.StatusBadRequest, "", "namespace can't be renamed from %s to %s", ns.Name, body.Name)
+		return
+	}
+	ns.Retention = body.Retention
+	ns.GroupID = body.GroupID
+	writeJSON(w, http.StatusOK, ns.Namespace)
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, _ *http.Request, ns *namespace, _ []string) {
+	writeJSON(w, http.StatusOK, sortedKeys(ns.groups))
+}
+
+func (s *Server) listItems(w http.ResponseWriter, _ *http.Request, ns *namespace, params []string) {
+	items, ok := ns.groups[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errorCodeNotFound, "group %s does not exist", params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, sortedKeys(items))
+}
+
+func (s *Server) getItem(w http.ResponseWriter, _ *http.Request, ns *namespace, params []string) {
+	value, ok := ns.groups[params[0]][params[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errorCodeNotFound, "item %s does not exist in group %s", params[1], params[0])
+		return
+	}
+	if edgeworkers.IsJSON(edgeworkers.Item(value)) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(value))
+		return
+	}
+	writeText(w, http.StatusOK, value)
+}
+
+func (s *Server) upsertItem(w http.ResponseWriter, r *http.Request, ns *namespace, params []string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", "reading item: %s", err)
+		return
+	}
+	ns.setItem(params[0], params[1], string(data))
+	writeText(w, http.StatusOK, fmt.Sprintf("Item was upserted in KV store with database %s, namespace %s, group %s, and key %s.",
+		edgeKVCPCode, ns.Name, params[0], params[1]))
+}
+
+func (s *Server) deleteItem(w http.ResponseWriter, _ *http.Request, ns *namespace, params []string) {
+	if _, ok := ns.groups[params[0]][params[1]]; !ok {
+		writeError(w, http.StatusNotFound, errorCodeNotFound, "item %s does not exist in group %s", params[1], params[0])
+		return
+	}
+	delete(ns.groups[params[0]], params[1])
+	writeText(w, http.StatusOK, "Item was marked for deletion from database, it may take few seconds to be removed.")
+}
+
+func (s *Server) listTokens(w http.ResponseWriter, r *http.Request, _ []string) {
+	includeExpired, _ := strconv.ParseBool(r.URL.Query().Get("includeExpired"))
+	today := s.clock().Format(time.DateOnly)
+	response := edgeworkers.ListEdgeKVAccessTokensResponse{Tokens: []edgeworkers.EdgeKVAccessToken{}}
+	for _, name := range sortedKeys(s.edgeKV.tokens) {
+		token := s.edgeKV.tokens[name]
+		if !includeExpired && token.Expiry < today {
+			continue
+		}
+		status := token.TokenActivationStatus
+		issueDate := token.IssueDate
+		nextRefresh := token.NextScheduledRefreshDate
+		response.Tokens = append(response.Tokens, edgeworkers.EdgeKVAccessToken{
+			Expiry:                   token.Expiry,
+			Name:                     token.Name,
+			UUID:                     token.UUID,
+			TokenActivationStatus:    &status,
+			IssueDate:                &issueDate,
+			LatestRefreshDate:        token.LatestRefreshDate,
+			NextScheduledRefreshDate: &nextRefresh,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request, _ []string) {
+	var body edgeworkers.CreateEdgeKVAccessTokenRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if _, ok := s.edgeKV.tokens[body.Name]; ok {
+		writeError(w, http.StatusConflict, "", "token %s already exists", body.Name)
+		return
+	}
+	s.edgeKV.nextToken++
+	now := s.clock()
+	token := &edgeworkers.CreateEdgeKVAccessTokenResponse{
+		AllowOnProduction:        body.AllowOnProduction,
+		AllowOnStaging:           body.AllowOnStaging,
+		CPCode:                   edgeKVCPCode,
+		Expiry:                   now.Add(TokenValidity).Format(time.DateOnly),
+		IssueDate:                now.Format(time.DateOnly),
+		Name:                     body.Name,
+		NamespacePermissions:     body.NamespacePermissions,
+		NextScheduledRefreshDate: now.Add(TokenValidity / 2).Format(time.DateOnly),
+		RestrictToEdgeWorkerIDs:  body.RestrictToEdgeWorkerIDs,
+		TokenActivationStatus:    edgeworkers.ActivationStatusComplete,
+		UUID:                     fmt.Sprintf("00000000-0000-4000-8000-%012d", s.edgeKV.nextToken),
+	}
+	s.edgeKV.tokens[token.Name] = token
+	writeJSON(w, http.StatusOK, token)
+}
+
+func (s *Server) getToken(w http.ResponseWriter, _ *http.Request, params []string) {
+	token, ok := s.edgeKV.tokens[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errorCodeNotFound, "token %s does not exist", params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+func (s *Server) deleteToken(w http.ResponseWriter, _ *http.Request, params []string) {
+	token, ok := s.edgeKV.tokens[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errorCodeNotFound, "token %s does not exist", params[0])
+		return
+	}
+	delete(s.edgeKV.tokens, token.Name)
+	writeJSON(w, http.StatusOK, edgeworkers.DeleteEdgeKVAccessTokenResponse{Name: token.Name, UUID: token.UUID})
+}
+
+func (ns *namespace) setItem(groupID, itemID, value string) {
+	if ns.groups[groupID] == nil {
+		ns.groups[groupID] = make(map[string]string)
+	}
+	ns.groups[groupID][itemID] = value
+}
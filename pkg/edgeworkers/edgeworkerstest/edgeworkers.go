@@ -0,0 +1,455 @@
+package edgeworkerstest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgeworkers"
+)
+
+type (
+	edgeWorker struct {
+		edgeworkers.EdgeWorkerID
+		versions      []*version
+		activations   []*activation
+		deactivations []*activation
+	}
+
+	version struct {
+		edgeworkers.EdgeWorkerVersion
+		content []byte
+	}
+
+	// activation tracks an activation or a deactivation, whose status is derived from its age
+	activation struct {
+		id       int
+		version  string
+		network  edgeworkers.ActivationNetwork
+		note     string
+		created  time.Time
+		modified time.Time
+		canceled bool
+	}
+)
+
+const (
+	statusPresubmit  = "PRESUBMIT"
+	statusPending    = "PENDING"
+	statusInProgress = "IN_PROGRESS"
+)
+
+func (s *Server) registerEdgeWorkersRoutes() {
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids", s.listEdgeWorkerIDs)
+	s.handle(http.MethodPost, "/edgeworkers/v1/ids", s.createEdgeWorkerID)
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*", s.withEdgeWorker(s.getEdgeWorkerID))
+	s.handle(http.MethodPut, "/edgeworkers/v1/ids/*", s.withEdgeWorker(s.updateEdgeWorkerID))
+	s.handle(http.MethodDelete, "/edgeworkers/v1/ids/*", s.withEdgeWorker(s.deleteEdgeWorkerID))
+
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/versions", s.withEdgeWorker(s.listVersions))
+	s.handle(http.MethodPost, "/edgeworkers/v1/ids/*/versions", s.withEdgeWorker(s.createVersion))
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/versions/*", s.withEdgeWorker(s.getVersion))
+	s.handle(http.MethodDelete, "/edgeworkers/v1/ids/*/versions/*", s.withEdgeWorker(s.deleteVersion))
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/versions/*/content", s.withEdgeWorker(s.getVersionContent))
+
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/activations", s.withEdgeWorker(s.listActivations))
+	s.handle(http.MethodPost, "/edgeworkers/v1/ids/*/activations", s.withEdgeWorker(s.activateVersion))
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/activations/*", s.withEdgeWorker(s.getActivation))
+	s.handle(http.MethodDelete, "/edgeworkers/v1/ids/*/activations/*", s.withEdgeWorker(s.cancelActivation))
+
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/deactivations", s.withEdgeWorker(s.listDeactivations))
+	s.handle(http.MethodPost, "/edgeworkers/v1/ids/*/deactivations", s.withEdgeWorker(s.deactivateVersion))
+	s.handle(http.MethodGet, "/edgeworkers/v1/ids/*/deactivations/*", s.withEdgeWorker(s.getDeactivation))
+
+	s.handle(http.MethodPost, "/edgeworkers/v1/validations", s.validateBundle)
+}
+
+// withEdgeWorker resolves the EdgeWorker ID from the first path parameter
+func (s *Server) withEdgeWorker(handler func(http.ResponseWriter, *http.Request, *edgeWorker, []string)) func(http.ResponseWriter, *http.Request, []string) {
+	return func(w http.ResponseWriter, r *http.Request, params []string) {
+		id, err := strconv.Atoi(params[0])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "", "invalid EdgeWorker ID '%s'", params[0])
+			return
+		}
+		ew, ok := s.edgeWorkers[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "", "EdgeWorker ID %d does not exist", id)
+			return
+		}
+		handler(w, r, ew, params[1:])
+	}
+}
+
+func (s *Server) listEdgeWorkerIDs(w http.ResponseWriter, r *http.Request, _ []string) {
+	groupID, _ := strconv.ParseInt(r.URL.Query().Get("groupId"), 10, 64)
+	resourceTierID, _ := strconv.Atoi(r.URL.Query().Get("resourceTierId"))
+	response := edgeworkers.ListEdgeWorkersIDResponse{EdgeWorkers: []edgeworkers.EdgeWorkerID{}}
+	for _, id := range sortedKeys(s.edgeWorkers) {
+		ew := s.edgeWorkers[id]
+		if groupID != 0 && ew.GroupID != groupID || resourceTierID != 0 && ew.ResourceTierID != resourceTierID {
+			continue
+		}
+		response.EdgeWorkers = append(response.EdgeWorkers, ew.EdgeWorkerID)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) createEdgeWorkerID(w http.ResponseWriter, r *http.Request, _ []string) {
+	var body edgeworkers.CreateEdgeWorkerIDRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	id := s.nextID
+	s.nextID++
+	ew := &edgeWorker{EdgeWorkerID: edgeworkers.EdgeWorkerID{
+		EdgeWorkerID:     id,
+		Name:             body.Name,
+		AccountID:        s.accountID,
+		GroupID:          int64(body.GroupID),
+		ResourceTierID:   body.ResourceTierID,
+		CreatedBy:        DefaultUser,
+		CreatedTime:      s.timestamp(),
+		LastModifiedBy:   DefaultUser,
+		LastModifiedTime: s.timestamp(),
+	}}
+	s.edgeWorkers[id] = ew
+	writeJSON(w, http.StatusCreated, ew.EdgeWorkerID)
+}
+
+func (s *Server) getEdgeWorkerID(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, _ []string) {
+	writeJSON(w, http.StatusOK, ew.EdgeWorkerID)
+}
+
+func (s *Server) updateEdgeWorkerID(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	var body edgeworkers.EdgeWorkerIDRequestBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	ew.Name = body.Name
+	ew.GroupID = int64(body.GroupID)
+	ew.ResourceTierID = body.ResourceTierID
+	ew.LastModifiedBy = DefaultUser
+	ew.LastModifiedTime = s.timestamp()
+	writeJSON(w, http.StatusOK, ew.EdgeWorkerID)
+}
+
+func (s *Server) deleteEdgeWorkerID(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, _ []string) {
+	for _, network := range []edgeworkers.ActivationNetwork{edgeworkers.ActivationNetworkStaging, edgeworkers.ActivationNetworkProduction} {
+		if v := s.activeVersion(ew, network); v != "" {
+			writeError(w, http.StatusBadRequest, "", "EdgeWorker ID %d has version %s active on %s", ew.EdgeWorkerID.EdgeWorkerID, v, network)
+			return
+		}
+	}
+	delete(s.edgeWorkers, ew.EdgeWorkerID.EdgeWorkerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listVersions(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, _ []string) {
+	response := edgeworkers.ListEdgeWorkerVersionsResponse{EdgeWorkerVersions: []edgeworkers.EdgeWorkerVersion{}}
+	for _, v := range ew.versions {
+		response.EdgeWorkerVersions = append(response.EdgeWorkerVersions, v.EdgeWorkerVersion)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) createVersion(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", "reading bundle: %s", err)
+		return
+	}
+	content, err := edgeworkers.ReadBundle(edgeworkers.Bundle{Reader: bytes.NewReader(data)})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid bundle: %s", err)
+		return
+	}
+	if content.Manifest == nil || content.Manifest.EdgeWorkerVersion == "" {
+		writeError(w, http.StatusBadRequest, "", "bundle.json with edgeworker-version is required")
+		return
+	}
+	if ew.version(content.Manifest.EdgeWorkerVersion) != nil {
+		writeError(w, http.StatusConflict, "", "version %s already exists", content.Manifest.EdgeWorkerVersion)
+		return
+	}
+	checksum := sha256.Sum256(data)
+	v := &version{
+		EdgeWorkerVersion: edgeworkers.EdgeWorkerVersion{
+			EdgeWorkerID:   ew.EdgeWorkerID.EdgeWorkerID,
+			Version:        content.Manifest.EdgeWorkerVersion,
+			AccountID:      s.accountID,
+			Checksum:       hex.EncodeToString(checksum[:]),
+			SequenceNumber: len(ew.versions) + 1,
+			CreatedBy:      DefaultUser,
+			CreatedTime:    s.timestamp(),
+		},
+		content: data,
+	}
+	ew.versions = append(ew.versions, v)
+	writeJSON(w, http.StatusCreated, v.EdgeWorkerVersion)
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	v := ew.version(params[0])
+	if v == nil {
+		writeError(w, http.StatusNotFound, "", "version %s does not exist", params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, v.EdgeWorkerVersion)
+}
+
+func (s *Server) getVersionContent(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	v := ew.version(params[0])
+	if v == nil {
+		writeError(w, http.StatusNotFound, "", "version %s does not exist", params[0])
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	_, _ = w.Write(v.content)
+}
+
+func (s *Server) deleteVersion(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	for i, v := range ew.versions {
+		if v.Version != params[0] {
+			continue
+		}
+		for _, network := range []edgeworkers.ActivationNetwork{edgeworkers.ActivationNetworkStaging, edgeworkers.ActivationNetworkProduction} {
+			if s.activeVersion(ew, network) == v.Version {
+				writeError(w, http.StatusBadRequest, "", "version %s is active on %s", v.Version, network)
+				return
+			}
+		}
+		ew.versions = append(ew.versions[:i], ew.versions[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeError(w, http.StatusNotFound, "", "version %s does not exist", params[0])
+}
+
+func (s *Server) listActivations(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	response := edgeworkers.ListActivationsResponse{Activations: []edgeworkers.Activation{}}
+	for _, a := range ew.activations {
+		if v := r.URL.Query().Get("version"); v != "" && a.version != v {
+			continue
+		}
+		response.Activations = append(response.Activations, s.activationResponse(ew, a))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) activateVersion(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	var body edgeworkers.ActivateVersion
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	a, ok := s.newActivation(w, ew, body.Network, body.Version, body.Note)
+	if !ok {
+		return
+	}
+	ew.activations = append(ew.activations, a)
+	response := s.activationResponse(ew, a)
+	response.Status = statusPresubmit
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (s *Server) getActivation(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	a := findActivation(ew.activations, params[0])
+	if a == nil {
+		writeError(w, http.StatusNotFound, "", "activation %s does not exist", params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, s.activationResponse(ew, a))
+}
+
+func (s *Server) cancelActivation(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	a := findActivation(ew.activations, params[0])
+	if a == nil {
+		writeError(w, http.StatusNotFound, "", "activation %s does not exist", params[0])
+		return
+	}
+	if status := s.status(a); status != statusPending && status != statusPresubmit {
+		writeError(w, http.StatusBadRequest, "", "activation %d with status %s can't be cancelled", a.id, status)
+		return
+	}
+	a.canceled = true
+	a.modified = s.clock()
+	writeJSON(w, http.StatusOK, s.activationResponse(ew, a))
+}
+
+func (s *Server) listDeactivations(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	response := edgeworkers.ListDeactivationsResponse{Deactivations: []edgeworkers.Deactivation{}}
+	for _, d := range ew.deactivations {
+		if v := r.URL.Query().Get("version"); v != "" && d.version != v {
+			continue
+		}
+		response.Deactivations = append(response.Deactivations, s.deactivationResponse(ew, d))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) deactivateVersion(w http.ResponseWriter, r *http.Request, ew *edgeWorker, _ []string) {
+	var body edgeworkers.DeactivateVersion
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if active := s.activeVersion(ew, body.Network); active != body.Version {
+		writeError(w, http.StatusBadRequest, "EW1032", "version %s is not active on %s", body.Version, body.Network)
+		return
+	}
+	d, ok := s.newActivation(w, ew, body.Network, body.Version, body.Note)
+	if !ok {
+		return
+	}
+	ew.deactivations = append(ew.deactivations, d)
+	response := s.deactivationResponse(ew, d)
+	response.Status = statusPresubmit
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (s *Server) getDeactivation(w http.ResponseWriter, _ *http.Request, ew *edgeWorker, params []string) {
+	d := findActivation(ew.deactivations, params[0])
+	if d == nil {
+		writeError(w, http.StatusNotFound, "", "deactivation %s does not exist", params[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, s.deactivationResponse(ew, d))
+}
+
+// validateBundle reports bundles which can't be read or miss bundle.json or main.js as errors
+func (s *Server) validateBundle(w http.ResponseWriter, r *http.Request, _ []string) {
+	response := edgeworkers.ValidateBundleResponse{Errors: []edgeworkers.ValidationIssue{}, Warnings: []edgeworkers.ValidationIssue{}}
+	content, err := edgeworkers.ReadBundle(edgeworkers.Bundle{Reader: r.Body})
+	switch {
+	case err != nil:
+		response.Errors = append(response.Errors, edgeworkers.ValidationIssue{Type: "INVALID_TARBALL", Message: err.Error()})
+	case content.Manifest == nil:
+		response.Errors = append(response.Errors, edgeworkers.ValidationIssue{Type: "MISSING_MANIFEST", Message: "bundle.json is missing"})
+	case content.File(edgeworkers.BundleMainFile) == nil:
+		response.Errors = append(response.Errors, edgeworkers.ValidationIssue{Type: "MISSING_MAINJS", Message: "main.js is missing"})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// newActivation validates the network and version, and creates an activation or a deactivation with a new ID
+func (s *Server) newActivation(w http.ResponseWriter, ew *edgeWorker, network edgeworkers.ActivationNetwork, v, note string) (*activation, bool) {
+	if network != edgeworkers.ActivationNetworkStaging && network != edgeworkers.ActivationNetworkProduction {
+		writeError(w, http.StatusBadRequest, "", "invalid network '%s'", network)
+		return nil, false
+	}
+	if ew.version(v) == nil {
+		writeError(w, http.StatusNotFound, "", "version %s does not exist", v)
+		return nil, false
+	}
+	s.nextActivation++
+	return &activation{id: s.nextActivation, version: v, network: network, note: note, created: s.clock(), modified: s.clock()}, true
+}
+
+// status derives the status of an activation or deactivation from the time elapsed since its creation
+func (s *Server) status(a *activation) string {
+	elapsed := s.clock().Sub(a.created)
+	switch {
+	case a.canceled:
+		return edgeworkers.ActivationStatusCanceled
+	case elapsed >= s.activationDuration:
+		return edgeworkers.ActivationStatusComplete
+	case elapsed >= s.activationDuration/2:
+		return statusInProgress
+	default:
+		return statusPending
+	}
+}
+
+// activeVersion returns the version of the latest completed activation on the network, unless it was deactivated
+func (s *Server) activeVersion(ew *edgeWorker, network edgeworkers.ActivationNetwork) string {
+	var latest *activation
+	for _, a := range ew.activations {
+		if a.network == network && s.status(a) == edgeworkers.ActivationStatusComplete {
+			latest = a
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	for _, d := range ew.deactivations {
+		if d.network == network && d.version == latest.version && d.id > latest.id && s.status(d) == edgeworkers.ActivationStatusComplete {
+			return ""
+		}
+	}
+	return latest.version
+}
+
+func (s *Server) activationResponse(ew *edgeWorker, a *activation) edgeworkers.Activation {
+	return edgeworkers.Activation{
+		AccountID:        s.accountID,
+		ActivationID:     a.id,
+		CreatedBy:        DefaultUser,
+		CreatedTime:      a.created.Format(time.RFC3339),
+		EdgeWorkerID:     ew.EdgeWorkerID.EdgeWorkerID,
+		LastModifiedTime: s.lastModified(a).Format(time.RFC3339),
+		Network:          string(a.network),
+		Status:           s.status(a),
+		Version:          a.version,
+		Note:             a.note,
+	}
+}
+
+func (s *Server) deactivationResponse(ew *edgeWorker, d *activation) edgeworkers.Deactivation {
+	return edgeworkers.Deactivation{
+		EdgeWorkerID:     ew.EdgeWorkerID.EdgeWorkerID,
+		Version:          d.version,
+		DeactivationID:   d.id,
+		AccountID:        s.accountID,
+		Status:           s.status(d),
+		Network:          d.network,
+		Note:             d.note,
+		CreatedBy:        DefaultUser,
+		CreatedTime:      d.created.Format(time.RFC3339),
+		LastModifiedTime: s.lastModified(d).Format(time.RFC3339),
+	}
+}
+
+// lastModified returns the time of the last status change of an activation or deactivation
+func (s *Server) lastModified(a *activation) time.Time {
+	if a.canceled {
+		return a.modified
+	}
+	switch s.status(a) {
+	case edgeworkers.ActivationStatusComplete:
+		return a.created.Add(s.activationDuration)
+	case statusInProgress:
+		return a.created.Add(s.activationDuration / 2)
+	}
+	return a.created
+}
+
+func (ew *edgeWorker) version(v string) *version {
+	for _, version := range ew.versions {
+		if version.Version == v {
+			return version
+		}
+	}
+	return nil
+}
+
+func findActivation(activations []*activation, id string) *activation {
+	for _, a := range activations {
+		if strconv.Itoa(a.id) == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,208 @@
+// Package edgeworkerstest provides an in-memory emulator of the EdgeWorkers and EdgeKV APIs for tests
+//
+// The emulator keeps EdgeWorker IDs, versions, activations, deactivations, EdgeKV namespaces, items and access tokens
+// in memory, so code using the edgeworkers package can be exercised end-to-end with the real client and no network:
+//
+//	server := edgeworkerstest.NewServer()
+//	defer server.Close()
+//	client, err := server.Client()
+//
+// Reports, secure tokens, contracts, permission groups, resource tiers, properties and cloning are not emulated.
+package edgeworkerstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegrid"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgeworkers"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/session"
+)
+
+type (
+	// Server is an EdgeWorkers and EdgeKV API emulator listening on a local TLS address
+	Server struct {
+		*httptest.Server
+
+		mu                 sync.Mutex
+		now                func() time.Time
+		offset             time.Duration
+		activationDuration time.Duration
+		accountID          string
+		routes             []route
+
+		edgeWorkers    map[int]*edgeWorker
+		nextID         int
+		nextActivation int
+		edgeKV         edgeKV
+	}
+
+	// Option configures Server
+	Option func(*Server)
+
+	route struct {
+		method  string
+		pattern []string
+		handler func(w http.ResponseWriter, r *http.Request, params []string)
+	}
+)
+
+const (
+	// DefaultAccountID is the account ID set on emulated resources
+	DefaultAccountID = "A-CCT1234"
+	// DefaultUser is the user set as the author of emulated resources
+	DefaultUser = "edgeworkerstest"
+)
+
+// WithActivationDuration sets the time activations and deactivations take to complete. They are PENDING in the first
+// half of the duration and IN_PROGRESS in the second one. Defaults to zero, which completes them on the first read
+func WithActivationDuration(d time.Duration) Option {
+	return func(s *Server) {
+		s.activationDuration = d
+	}
+}
+
+// WithClock sets the function returning the current time. Defaults to time.Now
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithAccountID sets the account ID of emulated resources. Defaults to DefaultAccountID
+func WithAccountID(accountID string) Option {
+	return func(s *Server) {
+		s.accountID = accountID
+	}
+}
+
+// NewServer starts an emulator with empty state. The caller should call Close when finished
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		now:         time.Now,
+		accountID:   DefaultAccountID,
+		edgeWorkers: make(map[int]*edgeWorker),
+		nextID:      1,
+		edgeKV:      newEdgeKV(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registerEdgeWorkersRoutes()
+	s.registerEdgeKVRoutes()
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+// Client returns an EdgeWorkers client sending requests to the emulator
+func (s *Server) Client(opts ...edgeworkers.Option) (edgeworkers.Edgeworkers, error) {
+	serverURL, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := session.New(session.WithClient(s.Server.Client()), session.WithSigner(&edgegrid.Config{Host: serverURL.Host}))
+	if err != nil {
+		return nil, err
+	}
+	return edgeworkers.Client(sess, opts...), nil
+}
+
+// Advance moves the emulator clock forward, e.g. to let pending activations progress
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// ServeHTTP dispatches requests to the emulated endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	pathFound := false
+	for _, rt := range s.routes {
+		params, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		pathFound = true
+		if rt.method == r.Method {
+			rt.handler(w, r, params)
+			return
+		}
+	}
+	if pathFound {
+		writeError(w, http.StatusMethodNotAllowed, "", "method %s is not allowed", r.Method)
+		return
+	}
+	writeError(w, http.StatusNotFound, "", "%s is not emulated", r.URL.Path)
+}
+
+// handle registers a handler for the method and path pattern, in which '*' matches any segment passed as a parameter
+func (s *Server) handle(method, pattern string, handler func(w http.ResponseWriter, r *http.Request, params []string)) {
+	s.routes = append(s.routes, route{method: method, pattern: strings.Split(strings.Trim(pattern, "/"), "/"), handler: handler})
+}
+
+func (rt route) match(segments []string) ([]string, bool) {
+	if len(segments) != len(rt.pattern) {
+		return nil, false
+	}
+	var params []string
+	for i, p := range rt.pattern {
+		if p == "*" {
+			params = append(params, segments[i])
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// clock returns the current emulator time
+func (s *Server) clock() time.Time {
+	return s.now().Add(s.offset).UTC()
+}
+
+func (s *Server) timestamp() string {
+	return s.clock().Format(time.RFC3339)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeText(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+// writeError writes an API error in the format parsed by the edgeworkers client
+func writeError(w http.ResponseWriter, status int, errorCode, format string, args ...interface{}) {
+	writeJSON(w, status, edgeworkers.Error{
+		Type:      "/edgeworkers/error-types/" + strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "-")),
+		Title:     http.StatusText(status),
+		Detail:    fmt.Sprintf(format, args...),
+		Status:    status,
+		ErrorCode: errorCode,
+	})
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid request body: %s", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package edgeworkerstest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgeworkers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle(t *testing.T, version string) edgeworkers.Bundle {
+	bundle, err := edgeworkers.BuildBundle(fstest.MapFS{
+		"main.js": {Data: []byte("export function onClientRequest(request) {}\n")},
+	}, edgeworkers.BundleOptions{Manifest: &edgeworkers.BundleManifest{Description: "test"}, Version: version})
+	require.NoError(t, err)
+	return bundle.Bundle()
+}
+
+func TestEdgeWorkers(t *testing.T) {
+	start := time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC)
+	server := NewServer(WithActivationDuration(10*time.Minute), WithClock(func() time.Time { return start }))
+	defer server.Close()
+	client, err := server.Client()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	ew, err := client.CreateEdgeWorkerID(ctx, edgeworkers.CreateEdgeWorkerIDRequest{Name: "test", GroupID: 1, ResourceTierID: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 1, ew.EdgeWorkerID)
+	assert.Equal(t, "2024-10-18T12:00:00Z", ew.CreatedTime)
+
+	_, err = client.CreateEdgeWorkerVersion(ctx, edgeworkers.CreateEdgeWorkerVersionRequest{EdgeWorkerID: 1, ContentBundle: testBundle(t, "1.0")})
+	require.NoError(t, err)
+	_, err = client.CreateEdgeWorkerVersion(ctx, edgeworkers.CreateEdgeWorkerVersionRequest{EdgeWorkerID: 1, ContentBundle: testBundle(t, "1.0")})
+	var apiErr *edgeworkers.Error
+	require.True(t, errors.As(err, &apiErr), "want: %T; got: %s", apiErr, err)
+	assert.Equal(t, 409, apiErr.Status)
+
+	content, err := client.GetEdgeWorkerVersionContent(ctx, edgeworkers.GetEdgeWorkerVersionContentRequest{EdgeWorkerID: 1, Version: "1.0"})
+	require.NoError(t, err)
+	bundle, err := edgeworkers.ReadBundle(*content)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", bundle.Manifest.EdgeWorkerVersion)
+
+	activation, err := client.ActivateVersion(ctx, edgeworkers.ActivateVersionRequest{EdgeWorkerID: 1, ActivateVersion: edgeworkers.ActivateVersion{Network: edgeworkers.ActivationNetworkStaging, Version: "1.0"}})
+	require.NoError(t, err)
+	assert.Equal(t, "PRESUBMIT", activation.Status)
+	for _, step := range []struct {
+		advance time.Duration
+		status  string
+	}{{0, "PENDING"}, {5 * time.Minute, "IN_PROGRESS"}, {5 * time.Minute, "COMPLETE"}} {
+		server.Advance(step.advance)
+		activation, err = client.GetActivation(ctx, edgeworkers.GetActivationRequest{EdgeWorkerID: 1, ActivationID: activation.ActivationID})
+		require.NoError(t, err)
+		assert.Equal(t, step.status, activation.Status)
+	}
+	assert.Equal(t, "2024-10-18T12:10:00Z", activation.LastModifiedTime)
+
+	err = client.DeleteEdgeWorkerVersion(ctx, edgeworkers.DeleteEdgeWorkerVersionRequest{EdgeWorkerID: 1, Version: "1.0"})
+	assert.Error(t, err, "active version can't be deleted")
+
+	pending, err := client.ActivateVersion(ctx, edgeworkers.ActivateVersionRequest{EdgeWorkerID: 1, ActivateVersion: edgeworkers.ActivateVersion{Network: edgeworkers.ActivationNetworkProduction, Version: "1.0"}})
+	require.NoError(t, err)
+	canceled, err := client.CancelPendingActivation(ctx, edgeworkers.CancelActivationRequest{EdgeWorkerID: 1, ActivationID: pending.ActivationID})
+	require.NoError(t, err)
+	assert.Equal(t, "CANCELED", canceled.Status)
+
+	_, err = client.DeactivateVersion(ctx, edgeworkers.DeactivateVersionRequest{EdgeWorkerID: 1, DeactivateVersion: edgeworkers.DeactivateVersion{Network: edgeworkers.ActivationNetworkProduction, Version: "1.0"}})
+	assert.True(t, errors.Is(err, edgeworkers.ErrVersionAlreadyDeactivated), "want: %s; got: %s", edgeworkers.ErrVersionAlreadyDeactivated, err)
+	deactivation, err := client.DeactivateVersion(ctx, edgeworkers.DeactivateVersionRequest{EdgeWorkerID: 1, DeactivateVersion: edgeworkers.DeactivateVersion{Network: edgeworkers.ActivationNetworkStaging, Version: "1.0"}})
+	require.NoError(t, err)
+	server.Advance(10 * time.Minute)
+	deactivation, err = client.GetDeactivation(ctx, edgeworkers.GetDeactivationRequest{EdgeWorkerID: 1, DeactivationID: deactivation.DeactivationID})
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETE", deactivation.Status)
+
+	require.NoError(t, client.DeleteEdgeWorkerVersion(ctx, edgeworkers.DeleteEdgeWorkerVersionRequest{EdgeWorkerID: 1, Version: "1.0"}))
+	require.NoError(t, client.DeleteEdgeWorkerID(ctx, edgeworkers.DeleteEdgeWorkerIDRequest{EdgeWorkerID: 1}))
+	_, err = client.GetEdgeWorkerID(ctx, edgeworkers.GetEdgeWorkerIDRequest{EdgeWorkerID: 1})
+	require.True(t, errors.As(err, &apiErr), "want: %T; got: %s", apiErr, err)
+	assert.Equal(t, 404, apiErr.Status)
+}
+
+func TestDeploy(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client, err := server.Client()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.CreateEdgeWorkerID(ctx, edgeworkers.CreateEdgeWorkerIDRequest{Name: "test", GroupID: 1, ResourceTierID: 100})
+	require.NoError(t, err)
+	source := fstest.MapFS{
+		"bundle.json": {Data: []byte(`{"edgeworker-version": "1.0"}`)},
+		"main.js":     {Data: []byte("export function onClientRequest(request) {}\n")},
+	}
+	for _, expected := range []string{"1.0", "1.1"} {
+		result, err := edgeworkers.Deploy(ctx, client, edgeworkers.DeployRequest{EdgeWorkerID: 1, Source: source, BumpVersion: true, PollInterval: time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, expected, result.Version)
+	}
+
+	activations, err := client.ListActivations(ctx, edgeworkers.ListActivationsRequest{EdgeWorkerID: 1, Version: "1.1"})
+	require.NoError(t, err)
+	require.Len(t, activations.Activations, 2)
+	for _, a := range activations.Activations {
+		assert.Equal(t, "COMPLETE", a.Status)
+	}
+}
+
+func TestEdgeKV(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client, err := server.Client()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	status, err := client.InitializeEdgeKV(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "INITIALIZED", status.AccountStatus)
+
+	retention, groupID := 86400, 0
+	_, err = client.CreateEdgeKVNamespace(ctx, edgeworkers.CreateEdgeKVNamespaceRequest{Network: edgeworkers.NamespaceStagingNetwork, Namespace: edgeworkers.Namespace{Name: "ns", Retention: &retention, GroupID: &groupID}})
+	require.NoError(t, err)
+	namespaces, err := client.ListEdgeKVNamespaces(ctx, edgeworkers.ListEdgeKVNamespacesRequest{Network: edgeworkers.NamespaceStagingNetwork, Details: true})
+	require.NoError(t, err)
+	assert.Equal(t, []edgeworkers.Namespace{{Name: "ns", Retention: &retention, GroupID: &groupID}}, namespaces.Namespaces)
+
+	params := edgeworkers.ItemsRequestParams{Network: edgeworkers.ItemStagingNetwork, NamespaceID: "ns", GroupID: "config"}
+	_, err = client.UpsertItem(ctx, edgeworkers.UpsertItemRequest{ItemID: "json", ItemData: `{"a":1}`, ItemsRequestParams: params})
+	require.NoError(t, err)
+	server.SetItem(edgeworkers.ItemStagingNetwork, "ns", "config", "text", "plain")
+	items, err := client.ListItems(ctx, edgeworkers.ListItemsRequest{ItemsRequestParams: params})
+	require.NoError(t, err)
+	assert.Equal(t, edgeworkers.ListItemsResponse{"json", "text"}, *items)
+	item, err := client.GetItem(ctx, edgeworkers.GetItemRequest{ItemID: "json", ItemsRequestParams: params})
+	require.NoError(t, err)
+	assert.Equal(t, edgeworkers.Item(`{"a":1}`), *item)
+
+	_, err = client.DeleteItem(ctx, edgeworkers.DeleteItemRequest{ItemID: "json", ItemsRequestParams: params})
+	require.NoError(t, err)
+	_, err = client.GetItem(ctx, edgeworkers.GetItemRequest{ItemID: "json", ItemsRequestParams: params})
+	assert.True(t, errors.Is(err, edgeworkers.ErrNotFound), "want: %s; got: %s", edgeworkers.ErrNotFound, err)
+	_, err = client.ListGroupsWithinNamespace(ctx, edgeworkers.ListGroupsWithinNamespaceRequest{Network: edgeworkers.NamespaceProductionNetwork, NamespaceID: "ns"})
+	assert.True(t, errors.Is(err, edgeworkers.ErrNotFound), "want: %s; got: %s", edgeworkers.ErrNotFound, err)
+
+	token, err := client.CreateEdgeKVAccessToken(ctx, edgeworkers.CreateEdgeKVAccessTokenRequest{
+		Name: "token", AllowOnStaging: true, NamespacePermissions: edgeworkers.NamespacePermissions{"ns": {edgeworkers.PermissionRead}},
+	})
+	require.NoError(t, err)
+	tokens, err := client.ListEdgeKVAccessTokens(ctx, edgeworkers.ListEdgeKVAccessTokensRequest{})
+	require.NoError(t, err)
+	require.Len(t, tokens.Tokens, 1)
+	assert.Equal(t, token.UUID, tokens.Tokens[0].UUID)
+
+	var js strings.Builder
+	require.NoError(t, (&edgeworkers.EdgeKVTokenManager{Client: client}).WriteTokensJS(ctx, &js))
+	assert.Contains(t, js.String(), token.UUID)
+
+	server.Advance(TokenValidity + 24*time.Hour)
+	tokens, err = client.ListEdgeKVAccessTokens(ctx, edgeworkers.ListEdgeKVAccessTokensRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, tokens.Tokens)
+	_, err = client.DeleteEdgeKVAccessToken(ctx, edgeworkers.DeleteEdgeKVAccessTokenRequest{TokenName: "token"})
+	require.NoError(t, err)
+}
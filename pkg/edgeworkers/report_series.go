@@ -0,0 +1,367 @@
+package edgeworkers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// GetReportSeriesRequest contains parameters used to fetch EdgeWorker reports as a time series
+	GetReportSeriesRequest struct {
+		EdgeWorker string
+		// ReportIDs are IDs of reports to fetch, see ListReports. The summary report 1 has no time series
+		ReportIDs []int
+		Start     time.Time
+		End       time.Time
+		// Window is the longest time range fetched with a single request. Defaults to DefaultReportWindow
+		Window       time.Duration
+		Status       *string
+		EventHandler *string
+	}
+
+	// ReportSeries contains rows of EdgeWorker reports sorted by time
+	ReportSeries []ReportRow
+
+	// ReportRow contains metrics of an event handler, or EdgeWorker initialization, in a report interval
+	ReportRow struct {
+		Time         time.Time
+		ReportID     int
+		EdgeWorkerID int
+		// EventHandler is one of EventHandler* constants, or ReportEventInit for initialization
+		EventHandler string
+		Status       string
+		Version      string
+		Invocations  int
+		// Errors is the number of invocations with a status other than success
+		Errors       int
+		ExecDuration *Summary
+		InitDuration *Summary
+		Memory       *Summary
+	}
+)
+
+const (
+	// DefaultReportWindow is the default time range of a single report request
+	DefaultReportWindow = 24 * time.Hour
+	// ReportEventInit is the event handler of rows with EdgeWorker initialization metrics
+	ReportEventInit = "init"
+
+	reportTimeFormat = "2006-01-02T15:04:05.999Z"
+)
+
+var (
+	// ErrGetReportSeries is returned when GetReportSeries fails
+	ErrGetReportSeries = errors.New("get EdgeWorker report series")
+
+	reportCSVHeader = []string{
+		"time", "report_id", "edgeworker_id", "event_handler", "status", "version", "invocations", "errors",
+		"exec_duration_avg", "exec_duration_min", "exec_duration_max",
+		"init_duration_avg", "init_duration_min", "init_duration_max",
+		"memory_avg", "memory_min", "memory_max",
+	}
+)
+
+// Validate validates GetReportSeriesRequest
+func (r GetReportSeriesRequest) Validate() error {
+	return validation.Errors{
+		"EdgeWorker": validation.Validate(r.EdgeWorker, validation.Required),
+		"ReportIDs":  validation.Validate(r.ReportIDs, validation.Required, validation.Each(validation.Min(2))),
+		"Start":      validation.Validate(r.Start, validation.Required),
+		"End": validation.Validate(r.End, validation.Required, validation.By(func(interface{}) error {
+			if !r.End.After(r.Start) {
+				return fmt.Errorf("must be after Start")
+			}
+			return nil
+		})),
+		"Window": validation.Validate(r.Window, validation.Min(time.Duration(0))),
+	}.Filter()
+}
+
+// GetReportSeries fetches the reports over the time range split into windows and flattens them into rows.
+// A report interval returned for more than one window is included once: identical rows are taken once, and parts of
+// an interval split by a window boundary are merged, with invocations and errors summed and summaries combined.
+func GetReportSeries(ctx context.Context, client Edgeworkers, params GetReportSeriesRequest) (ReportSeries, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrGetReportSeries, ErrStructValidation, err)
+	}
+	window := params.Window
+	if window == 0 {
+		window = DefaultReportWindow
+	}
+
+	var series ReportSeries
+	// seen maps rows without metrics to their index in series
+	seen := make(map[ReportRow]int)
+	for start := params.Start; start.Before(params.End); start = start.Add(window) {
+		end := start.Add(window)
+		if end.After(params.End) {
+			end = params.End
+		}
+		for _, reportID := range params.ReportIDs {
+			report, err := client.GetReport(ctx, GetReportRequest{
+				ReportID:     reportID,
+				Start:        start.UTC().Format(reportTimeFormat),
+				End:          end.UTC().Format(reportTimeFormat),
+				EdgeWorker:   params.EdgeWorker,
+				Status:       params.Status,
+				EventHandler: params.EventHandler,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%w: report %d from %s: %w", ErrGetReportSeries, reportID, start.UTC().Format(time.RFC3339), err)
+			}
+			rows, err := reportRows(report)
+			if err != nil {
+				return nil, fmt.Errorf("%w: report %d: %w", ErrGetReportSeries, reportID, err)
+			}
+			for _, row := range rows {
+				key := row
+				key.Invocations, key.Errors, key.ExecDuration, key.InitDuration, key.Memory = 0, 0, nil, nil, nil
+				i, ok := seen[key]
+				if !ok {
+					seen[key] = len(series)
+					series = append(series, row)
+					continue
+				}
+				if !equalReportRows(series[i], row) {
+					series[i] = mergeReportRows(series[i], row)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.ReportID != b.ReportID {
+			return a.ReportID < b.ReportID
+		}
+		if a.EdgeWorkerID != b.EdgeWorkerID {
+			return a.EdgeWorkerID < b.EdgeWorkerID
+		}
+		if a.EventHandler != b.EventHandler {
+			return a.EventHandler < b.EventHandler
+		}
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		return a.Version < b.Version
+	})
+	return series, nil
+}
+
+// reportRows flattens data of all event handlers of the report
+func reportRows(report *GetReportResponse) ([]ReportRow, error) {
+	var rows []ReportRow
+	for _, data := range report.Data {
+		handlers := []struct {
+			name    string
+			entries []OnRequestAndResponse
+		}{
+			{EventHandlerOnClientRequest, derefSlice(data.Data.OnClientRequest)},
+			{EventHandlerOnOriginRequest, derefSlice(data.Data.OnOriginRequest)},
+			{EventHandlerOnOriginResponse, derefSlice(data.Data.OnOriginResponse)},
+			{EventHandlerOnClientResponse, derefSlice(data.Data.OnClientResponse)},
+			{EventHandlerResponseProvider, derefSlice(data.Data.ResponseProvider)},
+		}
+		for _, handler := range handlers {
+			for _, entry := range handler.entries {
+				t, err := time.Parse(time.RFC3339, entry.StartDateTime)
+				if err != nil {
+					return nil, err
+				}
+				row := ReportRow{
+					Time:         t,
+					ReportID:     report.ReportID,
+					EdgeWorkerID: data.EdgeWorkerID,
+					EventHandler: handler.name,
+					Status:       stringFromPtr(entry.Status),
+					Version:      entry.EdgeWorkerVersion,
+					Invocations:  entry.Invocations,
+					ExecDuration: entry.ExecDuration,
+					Memory:       entry.Memory,
+				}
+				if row.Status != "" && row.Status != StatusSuccess {
+					row.Errors = entry.Invocations
+				}
+				rows = append(rows, row)
+			}
+		}
+		if data.Data.Init == nil {
+			continue
+		}
+		for _, entry := range *data.Data.Init {
+			t, err := time.Parse(time.RFC3339, entry.StartDateTime)
+			if err != nil {
+				return nil, err
+			}
+			initDuration := entry.InitDuration
+			rows = append(rows, ReportRow{
+				Time:         t,
+				ReportID:     report.ReportID,
+				EdgeWorkerID: data.EdgeWorkerID,
+				EventHandler: ReportEventInit,
+				Version:      entry.EdgeWorkerVersion,
+				Invocations:  entry.Invocations,
+				InitDuration: &initDuration,
+			})
+		}
+	}
+	return rows, nil
+}
+
+// equalReportRows reports whether rows have the same metrics
+func equalReportRows(a, b ReportRow) bool {
+	equal := func(x, y *Summary) bool {
+		return x == nil && y == nil || x != nil && y != nil && *x == *y
+	}
+	return a.Invocations == b.Invocations && a.Errors == b.Errors &&
+		equal(a.ExecDuration, b.ExecDuration) && equal(a.InitDuration, b.InitDuration) && equal(a.Memory, b.Memory)
+}
+
+// mergeReportRows merges parts of a report interval. Averages are weighted by invocations
+func mergeReportRows(a, b ReportRow) ReportRow {
+	merge := func(x, y *Summary) *Summary {
+		if x == nil || y == nil {
+			if x == nil {
+				return y
+			}
+			return x
+		}
+		merged := Summary{Min: math.Min(x.Min, y.Min), Max: math.Max(x.Max, y.Max), Avg: (x.Avg + y.Avg) / 2}
+		if total := a.Invocations + b.Invocations; total > 0 {
+			merged.Avg = (x.Avg*float64(a.Invocations) + y.Avg*float64(b.Invocations)) / float64(total)
+		}
+		return &merged
+	}
+	merged := a
+	merged.Invocations += b.Invocations
+	merged.Errors += b.Errors
+	merged.ExecDuration = merge(a.ExecDuration, b.ExecDuration)
+	merged.InitDuration = merge(a.InitDuration, b.InitDuration)
+	merged.Memory = merge(a.Memory, b.Memory)
+	return merged
+}
+
+func derefSlice[T ~[]OnRequestAndResponse](s *T) []OnRequestAndResponse {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// WriteCSV writes the rows as CSV with a header. Metrics missing in a row are written as empty fields
+func (s ReportSeries) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportCSVHeader); err != nil {
+		return err
+	}
+	for _, row := range s {
+		record := []string{
+			row.Time.UTC().Format(time.RFC3339),
+			strconv.Itoa(row.ReportID),
+			strconv.Itoa(row.EdgeWorkerID),
+			row.EventHandler,
+			row.Status,
+			row.Version,
+			strconv.Itoa(row.Invocations),
+			strconv.Itoa(row.Errors),
+		}
+		for _, summary := range []*Summary{row.ExecDuration, row.InitDuration, row.Memory} {
+			if summary == nil {
+				record = append(record, "", "", "")
+				continue
+			}
+			record = append(record, formatMetric(summary.Avg), formatMetric(summary.Min), formatMetric(summary.Max))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteOpenMetrics writes the rows in the OpenMetrics text format, with samples timestamped with row times in seconds,
+// e.g. to backfill Prometheus with 'promtool tsdb create-blocks-from openmetrics'. Metrics are gauges, as reports
+// contain values aggregated over their intervals. Samples are labeled with the report ID, as reports may contain
+// the same metric, e.g. invocations, and are grouped by label set and ordered by time within each group.
+func (s ReportSeries) WriteOpenMetrics(w io.Writer) error {
+	type metric struct {
+		name, help string
+		// series maps label sets to sample lines, labels keeps label sets in order of their first sample
+		series map[string][]string
+		labels []string
+	}
+	metrics := []*metric{
+		{name: "edgeworker_invocations", help: "Number of EdgeWorker invocations in the report interval."},
+		{name: "edgeworker_errors", help: "Number of EdgeWorker invocations with a status other than success in the report interval."},
+		{name: "edgeworker_exec_duration_milliseconds", help: "EdgeWorker execution duration."},
+		{name: "edgeworker_init_duration_milliseconds", help: "EdgeWorker initialization duration."},
+		{name: "edgeworker_memory_kilobytes", help: "EdgeWorker memory usage."},
+	}
+	invocations, errorCount, execDuration, initDuration, memory := metrics[0], metrics[1], metrics[2], metrics[3], metrics[4]
+
+	for _, row := range s {
+		labels := fmt.Sprintf(`report_id="%d",edgeworker_id="%d",event_handler=%s,status=%s,version=%s`,
+			row.ReportID, row.EdgeWorkerID, strconv.Quote(row.EventHandler), strconv.Quote(row.Status), strconv.Quote(row.Version))
+		timestamp := row.Time.Unix()
+		sample := func(m *metric, extraLabels string, value float64) {
+			key := labels + extraLabels
+			if m.series == nil {
+				m.series = make(map[string][]string)
+			}
+			if _, ok := m.series[key]; !ok {
+				m.labels = append(m.labels, key)
+			}
+			m.series[key] = append(m.series[key], fmt.Sprintf("%s{%s} %s %d", m.name, key, formatMetric(value), timestamp))
+		}
+		summary := func(m *metric, summary *Summary) {
+			if summary == nil {
+				return
+			}
+			sample(m, `,stat="avg"`, summary.Avg)
+			sample(m, `,stat="min"`, summary.Min)
+			sample(m, `,stat="max"`, summary.Max)
+		}
+		sample(invocations, "", float64(row.Invocations))
+		if row.EventHandler != ReportEventInit {
+			sample(errorCount, "", float64(row.Errors))
+		}
+		summary(execDuration, row.ExecDuration)
+		summary(initDuration, row.InitDuration)
+		summary(memory, row.Memory)
+	}
+
+	var b strings.Builder
+	for _, m := range metrics {
+		if len(m.labels) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# TYPE %s gauge\n# HELP %s %s\n", m.name, m.name, m.help)
+		for _, labels := range m.labels {
+			for _, sample := range m.series[labels] {
+				b.WriteString(sample)
+				b.WriteString("\n")
+			}
+		}
+	}
+	b.WriteString("# EOF\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
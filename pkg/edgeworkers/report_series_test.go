@@ -0,0 +1,126 @@
+package edgeworkers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReportSeries(t *testing.T) {
+	var requests []string
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, r.URL.Path+" "+q.Get("start")+" "+q.Get("end"))
+		success, runtimeError := StatusSuccess, StatusRuntimeError
+		report := GetReportResponse{ReportID: 3, Data: []ReportData{{EdgeWorkerID: 42}}}
+		switch r.URL.Path {
+		case "/edgeworkers/v1/reports/3":
+			// every window returns the bucket at its start and the one at the end of the range
+			requests := OnClientRequest{
+				{Status: &success, StartDateTime: q.Get("start"), EdgeWorkerVersion: "1.0", Invocations: 10,
+					ExecDuration: &Summary{Avg: 1.5, Min: 1, Max: 2}, Memory: &Summary{Avg: 100, Min: 50, Max: 150}},
+				{Status: &runtimeError, StartDateTime: "2024-01-02T12:00:00Z", EdgeWorkerVersion: "1.0", Invocations: 2},
+			}
+			// the interval at the window boundary is split between windows
+			if q.Get("start") == "2024-01-01T00:00:00Z" {
+				requests = append(requests, OnRequestAndResponse{Status: &success, StartDateTime: "2024-01-01T23:00:00Z", EdgeWorkerVersion: "1.0",
+					Invocations: 1, ExecDuration: &Summary{Avg: 4, Min: 4, Max: 4}})
+			} else {
+				requests = append(requests, OnRequestAndResponse{Status: &success, StartDateTime: "2024-01-01T23:00:00Z", EdgeWorkerVersion: "1.0",
+					Invocations: 3, ExecDuration: &Summary{Avg: 2, Min: 1, Max: 3}})
+			}
+			report.Data[0].Data.OnClientRequest = &requests
+		case "/edgeworkers/v1/reports/4":
+			report.ReportID = 4
+			report.Data[0].Data.Init = &Init{{StartDateTime: "2024-01-01T00:00:00Z", EdgeWorkerVersion: "1.0", Invocations: 1, InitDuration: Summary{Avg: 3, Min: 3, Max: 3}}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(report))
+	}))
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	series, err := GetReportSeries(context.Background(), client, GetReportSeriesRequest{
+		EdgeWorker: "42",
+		ReportIDs:  []int{3, 4},
+		Start:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:        time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/edgeworkers/v1/reports/3 2024-01-01T00:00:00Z 2024-01-02T00:00:00Z",
+		"/edgeworkers/v1/reports/4 2024-01-01T00:00:00Z 2024-01-02T00:00:00Z",
+		"/edgeworkers/v1/reports/3 2024-01-02T00:00:00Z 2024-01-02T12:00:00Z",
+		"/edgeworkers/v1/reports/4 2024-01-02T00:00:00Z 2024-01-02T12:00:00Z",
+	}, requests)
+	require.Len(t, series, 5)
+	assert.Equal(t, ReportRow{
+		Time: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), ReportID: 3, EdgeWorkerID: 42, EventHandler: EventHandlerOnClientRequest,
+		Status: StatusSuccess, Version: "1.0", Invocations: 4, ExecDuration: &Summary{Avg: 2.5, Min: 1, Max: 4},
+	}, series[2])
+	assert.Equal(t, ReportRow{
+		Time: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), ReportID: 3, EdgeWorkerID: 42, EventHandler: EventHandlerOnClientRequest,
+		Status: StatusRuntimeError, Version: "1.0", Invocations: 2, Errors: 2,
+	}, series[4])
+
+	var csv bytes.Buffer
+	require.NoError(t, series.WriteCSV(&csv))
+	assert.Equal(t, `time,report_id,edgeworker_id,event_handler,status,version,invocations,errors,exec_duration_avg,exec_duration_min,exec_duration_max,init_duration_avg,init_duration_min,init_duration_max,memory_avg,memory_min,memory_max
+2024-01-01T00:00:00Z,3,42,onClientRequest,success,1.0,10,0,1.5,1,2,,,,100,50,150
+2024-01-01T00:00:00Z,4,42,init,,1.0,1,0,,,,3,3,3,,,
+2024-01-01T23:00:00Z,3,42,onClientRequest,success,1.0,4,0,2.5,1,4,,,,,,
+2024-01-02T00:00:00Z,3,42,onClientRequest,success,1.0,10,0,1.5,1,2,,,,100,50,150
+2024-01-02T12:00:00Z,3,42,onClientRequest,runtimeError,1.0,2,2,,,,,,,,,
+`, csv.String())
+
+	var openMetrics bytes.Buffer
+	require.NoError(t, series[1:2].WriteOpenMetrics(&openMetrics))
+	assert.Equal(t, `# TYPE edgeworker_invocations gauge
+# HELP edgeworker_invocations Number of EdgeWorker invocations in the report interval.
+edgeworker_invocations{report_id="4",edgeworker_id="42",event_handler="init",status="",version="1.0"} 1 1704067200
+# TYPE edgeworker_init_duration_milliseconds gauge
+# HELP edgeworker_init_duration_milliseconds EdgeWorker initialization duration.
+edgeworker_init_duration_milliseconds{report_id="4",edgeworker_id="42",event_handler="init",status="",version="1.0",stat="avg"} 3 1704067200
+edgeworker_init_duration_milliseconds{report_id="4",edgeworker_id="42",event_handler="init",status="",version="1.0",stat="min"} 3 1704067200
+edgeworker_init_duration_milliseconds{report_id="4",edgeworker_id="42",event_handler="init",status="",version="1.0",stat="max"} 3 1704067200
+# EOF
+`, openMetrics.String())
+
+	// samples of a label set are contiguous and ordered by time
+	openMetrics.Reset()
+	require.NoError(t, series.WriteOpenMetrics(&openMetrics))
+	assert.Contains(t, openMetrics.String(), `# HELP edgeworker_invocations Number of EdgeWorker invocations in the report interval.
+edgeworker_invocations{report_id="3",edgeworker_id="42",event_handler="onClientRequest",status="success",version="1.0"} 10 1704067200
+edgeworker_invocations{report_id="3",edgeworker_id="42",event_handler="onClientRequest",status="success",version="1.0"} 4 1704150000
+edgeworker_invocations{report_id="3",edgeworker_id="42",event_handler="onClientRequest",status="success",version="1.0"} 10 1704153600
+edgeworker_invocations{report_id="4",edgeworker_id="42",event_handler="init",status="",version="1.0"} 1 1704067200
+edgeworker_invocations{report_id="3",edgeworker_id="42",event_handler="onClientRequest",status="runtimeError",version="1.0"} 2 1704196800
+`)
+	assert.Contains(t, openMetrics.String(), `edgeworker_memory_kilobytes{report_id="3",edgeworker_id="42",event_handler="onClientRequest",status="success",version="1.0",stat="max"} 150 1704067200`)
+	assert.True(t, strings.HasSuffix(openMetrics.String(), "\n# EOF\n"))
+}
+
+func TestGetReportSeriesValidation(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]GetReportSeriesRequest{
+		"missing report IDs": {EdgeWorker: "42", Start: start, End: start.Add(time.Hour)},
+		"summary report":     {EdgeWorker: "42", ReportIDs: []int{1}, Start: start, End: start.Add(time.Hour)},
+		"end before start":   {EdgeWorker: "42", ReportIDs: []int{3}, Start: start, End: start.Add(-time.Hour)},
+		"negative window":    {EdgeWorker: "42", ReportIDs: []int{3}, Start: start, End: start.Add(time.Hour), Window: -time.Hour},
+	}
+	for name, params := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := GetReportSeries(context.Background(), Client(nil), params)
+			assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package edgeworkers
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	// GenerateSecureTokenRequest contains parameters of GenerateSecureToken
+	GenerateSecureTokenRequest struct {
+		// Key is the hex encoded secret key of the property, as configured in its enhanced debug behavior
+		Key string
+		// Algorithm is the HMAC algorithm. Defaults to SecureTokenAlgorithmSHA256
+		Algorithm SecureTokenAlgorithm
+		// ACL contains path patterns, e.g. '/*', the token is valid for. Either ACL or URL is required
+		ACL []string
+		// URL is the single path the token is valid for
+		URL string
+		// Expiry is the time the token is valid for, from 1 minute to 12 hours. Defaults to 15 minutes
+		Expiry time.Duration
+		// StartTime is the time the token is valid from. Not included in the token if zero
+		StartTime time.Time
+		// Now is the time the expiry is counted from when StartTime is not set. Defaults to the current time
+		Now time.Time
+		// IP, SessionID, Payload and Salt are optional fields of the token
+		IP        string
+		SessionID string
+		Payload   string
+		Salt      string
+	}
+
+	// SecureTokenAlgorithm is the HMAC algorithm of secure tokens
+	SecureTokenAlgorithm string
+)
+
+const (
+	// SecureTokenAlgorithmSHA256 is the HMAC-SHA256 algorithm
+	SecureTokenAlgorithmSHA256 SecureTokenAlgorithm = "sha256"
+	// SecureTokenAlgorithmSHA1 is the HMAC-SHA1 algorithm
+	SecureTokenAlgorithmSHA1 SecureTokenAlgorithm = "sha1"
+	// SecureTokenAlgorithmMD5 is the HMAC-MD5 algorithm
+	SecureTokenAlgorithmMD5 SecureTokenAlgorithm = "md5"
+
+	// DefaultSecureTokenExpiry is the default expiry of generated tokens
+	DefaultSecureTokenExpiry = 15 * time.Minute
+
+	// secureTokenFieldDelimiter separates fields of the token
+	secureTokenFieldDelimiter = "~"
+	// secureTokenACLDelimiter separates ACL entries
+	secureTokenACLDelimiter = "!"
+)
+
+var (
+	// ErrGenerateSecureToken is returned when a secure token can't be generated
+	ErrGenerateSecureToken = errors.New("generate secure token")
+
+	secureTokenHashes = map[SecureTokenAlgorithm]func() hash.Hash{
+		SecureTokenAlgorithmSHA256: sha256.New,
+		SecureTokenAlgorithmSHA1:   sha1.New,
+		SecureTokenAlgorithmMD5:    md5.New,
+	}
+)
+
+// Validate validates GenerateSecureTokenRequest
+func (r GenerateSecureTokenRequest) Validate() error {
+	notDelimited := validation.By(func(value interface{}) error {
+		if s, _ := value.(string); strings.Contains(s, secureTokenFieldDelimiter) {
+			return fmt.Errorf("must not contain '%s'", secureTokenFieldDelimiter)
+		}
+		return nil
+	})
+	return validation.Errors{
+		"Key": validation.Validate(r.Key, validation.Required, is.Hexadecimal,
+			validation.By(func(interface{}) error {
+				if len(r.Key)%2 != 0 {
+					return errors.New("must have an even number of hex digits")
+				}
+				return nil
+			})),
+		"Algorithm": validation.Validate(r.Algorithm, validation.In(SecureTokenAlgorithmSHA256, SecureTokenAlgorithmSHA1, SecureTokenAlgorithmMD5).Error(
+			fmt.Sprintf("value '%s' is invalid. Must be one of: '%s', '%s' or '%s'", r.Algorithm, SecureTokenAlgorithmSHA256, SecureTokenAlgorithmSHA1, SecureTokenAlgorithmMD5))),
+		"ACL": validation.Validate(r.ACL,
+			validation.Required.When(r.URL == "").Error("provide either the acl or the url"),
+			validation.Empty.When(r.URL != "").Error("if you specify an acl don't specify a url"),
+			validation.Each(validation.Required, notDelimited, validation.By(func(value interface{}) error {
+				if s, _ := value.(string); strings.Contains(s, secureTokenACLDelimiter) {
+					return fmt.Errorf("must not contain '%s'", secureTokenACLDelimiter)
+				}
+				return nil
+			}))),
+		"URL":       validation.Validate(r.URL, notDelimited),
+		"Expiry":    validation.Validate(r.Expiry, validation.Min(time.Minute), validation.Max(12*time.Hour)),
+		"IP":        validation.Validate(r.IP, is.IP),
+		"SessionID": validation.Validate(r.SessionID, notDelimited),
+		"Payload":   validation.Validate(r.Payload, notDelimited),
+		"Salt":      validation.Validate(r.Salt, notDelimited),
+	}.Filter()
+}
+
+// GenerateSecureToken generates an Akamai-EW-Trace token for enhanced debug headers locally, without calling
+// CreateSecureToken. The token is signed with the secret key of the property using the HMAC scheme of Akamai
+// token authentication, so it's valid only for hostnames of that property.
+func GenerateSecureToken(params GenerateSecureTokenRequest) (*CreateSecureTokenResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrGenerateSecureToken, ErrStructValidation, err)
+	}
+	if params.Algorithm == "" {
+		params.Algorithm = SecureTokenAlgorithmSHA256
+	}
+	if params.Expiry == 0 {
+		params.Expiry = DefaultSecureTokenExpiry
+	}
+	start := params.StartTime
+	if start.IsZero() {
+		start = params.Now
+		if start.IsZero() {
+			start = time.Now()
+		}
+	}
+	key, err := hex.DecodeString(params.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGenerateSecureToken, err)
+	}
+
+	var fields []string
+	if params.IP != "" {
+		fields = append(fields, "ip="+params.IP)
+	}
+	if !params.StartTime.IsZero() {
+		fields = append(fields, "st="+strconv.FormatInt(params.StartTime.Unix(), 10))
+	}
+	fields = append(fields, "exp="+strconv.FormatInt(start.Add(params.Expiry).Unix(), 10))
+	if len(params.ACL) > 0 {
+		fields = append(fields, "acl="+strings.Join(params.ACL, secureTokenACLDelimiter))
+	}
+	if params.SessionID != "" {
+		fields = append(fields, "id="+params.SessionID)
+	}
+	if params.Payload != "" {
+		fields = append(fields, "data="+params.Payload)
+	}
+
+	// the URL and the salt are signed, but not included in the token
+	signed := append([]string{}, fields...)
+	if params.URL != "" {
+		signed = append(signed, "url="+params.URL)
+	}
+	if params.Salt != "" {
+		signed = append(signed, "salt="+params.Salt)
+	}
+	mac := hmac.New(secureTokenHashes[params.Algorithm], key)
+	mac.Write([]byte(strings.Join(signed, secureTokenFieldDelimiter)))
+	fields = append(fields, "hmac="+hex.EncodeToString(mac.Sum(nil)))
+
+	return &CreateSecureTokenResponse{AkamaiEWTrace: strings.Join(fields, secureTokenFieldDelimiter)}, nil
+}
+
+// GenerateSecureTokens generates tokens for multiple hostnames, each signed with the key of its property.
+// keys maps hostnames to keys, params.Key is ignored. Tokens are returned by hostname.
+func GenerateSecureTokens(keys map[string]string, params GenerateSecureTokenRequest) (map[string]string, error) {
+	hostnames := make([]string, 0, len(keys))
+	for hostname := range keys {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	tokens := make(map[string]string, len(keys))
+	for _, hostname := range hostnames {
+		params.Key = keys[hostname]
+		token, err := GenerateSecureToken(params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hostname, err)
+		}
+		tokens[hostname] = token.AkamaiEWTrace
+	}
+	return tokens, nil
+}
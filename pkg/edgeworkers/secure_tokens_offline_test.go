@@ -0,0 +1,98 @@
+package edgeworkers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecureToken(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	key := "aabbccddeeff00112233445566778899"
+
+	tests := map[string]struct {
+		params            GenerateSecureTokenRequest
+		expected          string
+		withError         error
+		withErrorContains string
+	}{
+		"acl with default algorithm": {
+			params:   GenerateSecureTokenRequest{Key: key, ACL: []string{"/*"}, Expiry: 30 * time.Minute, Now: now},
+			expected: "exp=1717201800~acl=/*~hmac=a803cc2ccd67e5bfa5f6fc49e13623b6d2eb8d48e7ed51f0de931fddad34b837",
+		},
+		"all fields": {
+			params: GenerateSecureTokenRequest{
+				Key:       key,
+				Algorithm: SecureTokenAlgorithmSHA1,
+				ACL:       []string{"/api/*", "/static/*"},
+				Expiry:    time.Hour,
+				StartTime: now,
+				IP:        "192.0.2.1",
+				SessionID: "ci",
+				Payload:   "build-1",
+				Salt:      "pepper",
+			},
+			expected: "ip=192.0.2.1~st=1717200000~exp=1717203600~acl=/api/*!/static/*~id=ci~data=build-1~hmac=a0f1bef9378c52a049f992cb1a2383bfa31628fb",
+		},
+		"url is signed, but not included": {
+			params:   GenerateSecureTokenRequest{Key: key, URL: "/index.html", Now: now},
+			expected: "exp=1717200900~hmac=1dfcf7b65af1a03c60fb48e49b241f9cd1639dbb860bbff62c3d7e0c3b6f5b11",
+		},
+		"missing acl and url": {
+			params:            GenerateSecureTokenRequest{Key: key},
+			withError:         ErrStructValidation,
+			withErrorContains: "ACL: provide either the acl or the url",
+		},
+		"acl and url": {
+			params:            GenerateSecureTokenRequest{Key: key, ACL: []string{"/*"}, URL: "/"},
+			withError:         ErrStructValidation,
+			withErrorContains: "ACL: if you specify an acl don't specify a url",
+		},
+		"invalid key": {
+			params:            GenerateSecureTokenRequest{Key: "abc", ACL: []string{"/*"}},
+			withError:         ErrStructValidation,
+			withErrorContains: "Key: must have an even number of hex digits",
+		},
+		"delimiters in fields": {
+			params:            GenerateSecureTokenRequest{Key: key, ACL: []string{"/a!b"}, SessionID: "a~b"},
+			withError:         ErrStructValidation,
+			withErrorContains: "ACL: (0: must not contain '!'.); SessionID: must not contain '~'",
+		},
+		"expiry out of range": {
+			params:            GenerateSecureTokenRequest{Key: key, ACL: []string{"/*"}, Expiry: 13 * time.Hour},
+			withError:         ErrStructValidation,
+			withErrorContains: "Expiry: must be no greater than",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := GenerateSecureToken(test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.Contains(t, err.Error(), test.withErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result.AkamaiEWTrace)
+		})
+	}
+}
+
+func TestGenerateSecureTokens(t *testing.T) {
+	params := GenerateSecureTokenRequest{ACL: []string{"/*"}, Expiry: 30 * time.Minute, Now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	tokens, err := GenerateSecureTokens(map[string]string{
+		"www.example.com": "aabbccddeeff00112233445566778899",
+		"api.example.com": "00112233",
+	}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "exp=1717201800~acl=/*~hmac=a803cc2ccd67e5bfa5f6fc49e13623b6d2eb8d48e7ed51f0de931fddad34b837", tokens["www.example.com"])
+	assert.NotEqual(t, tokens["www.example.com"], tokens["api.example.com"])
+
+	_, err = GenerateSecureTokens(map[string]string{"www.example.com": "not hex"}, params)
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+	assert.Contains(t, err.Error(), "www.example.com: generate secure token")
+}
@@ -15,7 +15,8 @@ type (
 	// ASAssignment represents a GTM as map assignment structure
 	ASAssignment struct {
 		DatacenterBase
-		ASNumbers []int64 `json:"asNumbers"`
+		ASNumbers     []int64       `json:"asNumbers"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// ASMap  represents a GTM ASMap
@@ -24,6 +25,7 @@ type (
 		Assignments       []ASAssignment  `json:"assignments,omitempty"`
 		Name              string          `json:"name"`
 		Links             []Link          `json:"links,omitempty"`
+		UnknownFields     UnknownFields   `json:"-"`
 	}
 
 	// ASMapList represents the returned GTM ASMap List body
@@ -287,3 +289,32 @@ func (g *gtm) DeleteASMap(ctx context.Context, params DeleteASMapRequest) (*Dele
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes ASMap, keeping fields unknown to this version of the library in UnknownFields
+func (a *ASMap) UnmarshalJSON(data []byte) error {
+	type asMap ASMap
+	return unmarshalKeepingUnknown(data, (*asMap)(a), &a.UnknownFields)
+}
+
+// MarshalJSON encodes ASMap together with its UnknownFields
+func (a ASMap) MarshalJSON() ([]byte, error) {
+	type asMap ASMap
+	return marshalKeepingUnknown(asMap(a), a.UnknownFields)
+}
+
+// UnmarshalJSON decodes GetASMapResponse the same way as ASMap
+func (r *GetASMapResponse) UnmarshalJSON(data []byte) error {
+	return (*ASMap)(r).UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes ASAssignment, keeping fields unknown to this version of the library in UnknownFields
+func (a *ASAssignment) UnmarshalJSON(data []byte) error {
+	type asAssignment ASAssignment
+	return unmarshalKeepingUnknown(data, (*asAssignment)(a), &a.UnknownFields)
+}
+
+// MarshalJSON encodes ASAssignment together with its UnknownFields
+func (a ASAssignment) MarshalJSON() ([]byte, error) {
+	type asAssignment ASAssignment
+	return marshalKeepingUnknown(asAssignment(a), a.UnknownFields)
+}
@@ -15,7 +15,8 @@ type (
 	// CIDRAssignment represents a GTM CIDR assignment element
 	CIDRAssignment struct {
 		DatacenterBase
-		Blocks []string `json:"blocks"`
+		Blocks        []string      `json:"blocks"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// CIDRMap represents a GTM CIDRMap element
@@ -24,6 +25,7 @@ type (
 		Assignments       []CIDRAssignment `json:"assignments,omitempty"`
 		Name              string           `json:"name"`
 		Links             []Link           `json:"links,omitempty"`
+		UnknownFields     UnknownFields    `json:"-"`
 	}
 
 	// CIDRMapList represents a GTM returned CIDRMap list body
@@ -284,3 +286,32 @@ func (g *gtm) DeleteCIDRMap(ctx context.Context, params DeleteCIDRMapRequest) (*
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes CIDRMap, keeping fields unknown to this version of the library in UnknownFields
+func (c *CIDRMap) UnmarshalJSON(data []byte) error {
+	type cidrMap CIDRMap
+	return unmarshalKeepingUnknown(data, (*cidrMap)(c), &c.UnknownFields)
+}
+
+// MarshalJSON encodes CIDRMap together with its UnknownFields
+func (c CIDRMap) MarshalJSON() ([]byte, error) {
+	type cidrMap CIDRMap
+	return marshalKeepingUnknown(cidrMap(c), c.UnknownFields)
+}
+
+// UnmarshalJSON decodes GetCIDRMapResponse the same way as CIDRMap
+func (r *GetCIDRMapResponse) UnmarshalJSON(data []byte) error {
+	return (*CIDRMap)(r).UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes CIDRAssignment, keeping fields unknown to this version of the library in UnknownFields
+func (a *CIDRAssignment) UnmarshalJSON(data []byte) error {
+	type cidrAssignment CIDRAssignment
+	return unmarshalKeepingUnknown(data, (*cidrAssignment)(a), &a.UnknownFields)
+}
+
+// MarshalJSON encodes CIDRAssignment together with its UnknownFields
+func (a CIDRAssignment) MarshalJSON() ([]byte, error) {
+	type cidrAssignment CIDRAssignment
+	return marshalKeepingUnknown(cidrAssignment(a), a.UnknownFields)
+}
@@ -15,26 +15,27 @@ import (
 type (
 	// Datacenter represents a GTM datacenter
 	Datacenter struct {
-		City                          string      `json:"city,omitempty"`
-		CloneOf                       int         `json:"cloneOf,omitempty"`
-		CloudServerHostHeaderOverride bool        `json:"cloudServerHostHeaderOverride"`
-		CloudServerTargeting          bool        `json:"cloudServerTargeting"`
-		Continent                     string      `json:"continent,omitempty"`
-		Country                       string      `json:"country,omitempty"`
-		DefaultLoadObject             *LoadObject `json:"defaultLoadObject,omitempty"`
-		Latitude                      float64     `json:"latitude,omitempty"`
-		Links                         []Link      `json:"links,omitempty"`
-		Longitude                     float64     `json:"longitude,omitempty"`
-		Nickname                      string      `json:"nickname,omitempty"`
-		PingInterval                  int         `json:"pingInterval,omitempty"`
-		PingPacketSize                int         `json:"pingPacketSize,omitempty"`
-		DatacenterID                  int         `json:"datacenterId,omitempty"`
-		ScorePenalty                  int         `json:"scorePenalty,omitempty"`
-		ServermonitorLivenessCount    int         `json:"servermonitorLivenessCount,omitempty"`
-		ServermonitorLoadCount        int         `json:"servermonitorLoadCount,omitempty"`
-		ServermonitorPool             string      `json:"servermonitorPool,omitempty"`
-		StateOrProvince               string      `json:"stateOrProvince,omitempty"`
-		Virtual                       bool        `json:"virtual"`
+		City                          string        `json:"city,omitempty"`
+		CloneOf                       *int          `json:"cloneOf,omitempty"`
+		CloudServerHostHeaderOverride bool          `json:"cloudServerHostHeaderOverride"`
+		CloudServerTargeting          bool          `json:"cloudServerTargeting"`
+		Continent                     string        `json:"continent,omitempty"`
+		Country                       string        `json:"country,omitempty"`
+		DefaultLoadObject             *LoadObject   `json:"defaultLoadObject,omitempty"`
+		Latitude                      *float64      `json:"latitude,omitempty"`
+		Links                         []Link        `json:"links,omitempty"`
+		Longitude                     *float64      `json:"longitude,omitempty"`
+		Nickname                      string        `json:"nickname,omitempty"`
+		PingInterval                  *int          `json:"pingInterval,omitempty"`
+		PingPacketSize                *int          `json:"pingPacketSize,omitempty"`
+		DatacenterID                  int           `json:"datacenterId,omitempty"`
+		ScorePenalty                  *int          `json:"scorePenalty,omitempty"`
+		ServermonitorLivenessCount    *int          `json:"servermonitorLivenessCount,omitempty"`
+		ServermonitorLoadCount        *int          `json:"servermonitorLoadCount,omitempty"`
+		ServermonitorPool             string        `json:"servermonitorPool,omitempty"`
+		StateOrProvince               string        `json:"stateOrProvince,omitempty"`
+		Virtual                       bool          `json:"virtual"`
+		UnknownFields                 UnknownFields `json:"-"`
 	}
 
 	// DatacenterList contains a list of Datacenters
@@ -372,3 +373,15 @@ func (g *gtm) DeleteDatacenter(ctx context.Context, params DeleteDatacenterReque
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes Datacenter, keeping fields unknown to this version of the library in UnknownFields
+func (d *Datacenter) UnmarshalJSON(data []byte) error {
+	type datacenter Datacenter
+	return unmarshalKeepingUnknown(data, (*datacenter)(d), &d.UnknownFields)
+}
+
+// MarshalJSON encodes Datacenter together with its UnknownFields
+func (d Datacenter) MarshalJSON() ([]byte, error) {
+	type datacenter Datacenter
+	return marshalKeepingUnknown(datacenter(d), d.UnknownFields)
+}
@@ -5,8 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
-	"unicode"
 
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegriderr"
 	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/session"
@@ -20,45 +18,46 @@ type (
 		Type                         string          `json:"type"`
 		ASMaps                       []ASMap         `json:"asMaps,omitempty"`
 		Resources                    []Resource      `json:"resources,omitempty"`
-		DefaultUnreachableThreshold  float32         `json:"defaultUnreachableThreshold,omitempty"`
+		DefaultUnreachableThreshold  *float32        `json:"defaultUnreachableThreshold,omitempty"`
 		EmailNotificationList        []string        `json:"emailNotificationList,omitempty"`
-		MinPingableRegionFraction    float32         `json:"minPingableRegionFraction,omitempty"`
-		DefaultTimeoutPenalty        int             `json:"defaultTimeoutPenalty,omitempty"`
+		MinPingableRegionFraction    *float32        `json:"minPingableRegionFraction,omitempty"`
+		DefaultTimeoutPenalty        *int            `json:"defaultTimeoutPenalty,omitempty"`
 		Datacenters                  []Datacenter    `json:"datacenters,omitempty"`
-		ServermonitorLivenessCount   int             `json:"servermonitorLivenessCount,omitempty"`
+		ServermonitorLivenessCount   *int            `json:"servermonitorLivenessCount,omitempty"`
 		RoundRobinPrefix             string          `json:"roundRobinPrefix,omitempty"`
-		ServermonitorLoadCount       int             `json:"servermonitorLoadCount,omitempty"`
-		PingInterval                 int             `json:"pingInterval,omitempty"`
-		MaxTTL                       int64           `json:"maxTTL,omitempty"`
-		LoadImbalancePercentage      float64         `json:"loadImbalancePercentage,omitempty"`
-		DefaultHealthMax             float64         `json:"defaultHealthMax,omitempty"`
+		ServermonitorLoadCount       *int            `json:"servermonitorLoadCount,omitempty"`
+		PingInterval                 *int            `json:"pingInterval,omitempty"`
+		MaxTTL                       *int64          `json:"maxTTL,omitempty"`
+		LoadImbalancePercentage      *float64        `json:"loadImbalancePercentage,omitempty"`
+		DefaultHealthMax             *float64        `json:"defaultHealthMax,omitempty"`
 		LastModified                 string          `json:"lastModified,omitempty"`
 		Status                       *ResponseStatus `json:"status,omitempty"`
-		MapUpdateInterval            int             `json:"mapUpdateInterval,omitempty"`
-		MaxProperties                int             `json:"maxProperties,omitempty"`
-		MaxResources                 int             `json:"maxResources,omitempty"`
+		MapUpdateInterval            *int            `json:"mapUpdateInterval,omitempty"`
+		MaxProperties                *int            `json:"maxProperties,omitempty"`
+		MaxResources                 *int            `json:"maxResources,omitempty"`
 		DefaultSSLClientPrivateKey   string          `json:"defaultSslClientPrivateKey,omitempty"`
-		DefaultErrorPenalty          int             `json:"defaultErrorPenalty,omitempty"`
+		DefaultErrorPenalty          *int            `json:"defaultErrorPenalty,omitempty"`
 		Links                        []Link          `json:"links,omitempty"`
 		Properties                   []Property      `json:"properties,omitempty"`
-		MaxTestTimeout               float64         `json:"maxTestTimeout,omitempty"`
+		MaxTestTimeout               *float64        `json:"maxTestTimeout,omitempty"`
 		CNameCoalescingEnabled       bool            `json:"cnameCoalescingEnabled"`
-		DefaultHealthMultiplier      float64         `json:"defaultHealthMultiplier,omitempty"`
+		DefaultHealthMultiplier      *float64        `json:"defaultHealthMultiplier,omitempty"`
 		ServermonitorPool            string          `json:"servermonitorPool,omitempty"`
 		LoadFeedback                 bool            `json:"loadFeedback"`
-		MinTTL                       int64           `json:"minTTL,omitempty"`
+		MinTTL                       *int64          `json:"minTTL,omitempty"`
 		GeographicMaps               []GeoMap        `json:"geographicMaps,omitempty"`
 		CIDRMaps                     []CIDRMap       `json:"cidrMaps,omitempty"`
 		DefaultMaxUnreachablePenalty int             `json:"defaultMaxUnreachablePenalty"`
-		DefaultHealthThreshold       float64         `json:"defaultHealthThreshold,omitempty"`
+		DefaultHealthThreshold       *float64        `json:"defaultHealthThreshold,omitempty"`
 		LastModifiedBy               string          `json:"lastModifiedBy,omitempty"`
 		ModificationComments         string          `json:"modificationComments,omitempty"`
-		MinTestInterval              int             `json:"minTestInterval,omitempty"`
-		PingPacketSize               int             `json:"pingPacketSize,omitempty"`
+		MinTestInterval              *int            `json:"minTestInterval,omitempty"`
+		PingPacketSize               *int            `json:"pingPacketSize,omitempty"`
 		DefaultSSLClientCertificate  string          `json:"defaultSslClientCertificate,omitempty"`
 		EndUserMappingEnabled        bool            `json:"endUserMappingEnabled"`
 		SignAndServe                 bool            `json:"signAndServe"`
 		SignAndServeAlgorithm        *string         `json:"signAndServeAlgorithm"`
+		UnknownFields                UnknownFields   `json:"-"`
 	}
 
 	// DomainQueryArgs contains query parameters for domain request
@@ -400,150 +399,19 @@ func (g *gtm) DeleteDomain(ctx context.Context, params DeleteDomainRequest) (*De
 	return &result, nil
 }
 
-// NullPerObjectAttributeStruct represents core and child null object attributes
-type NullPerObjectAttributeStruct struct {
-	CoreObjectFields  map[string]string
-	ChildObjectFields map[string]interface{} // NullObjectAttributeStruct
+// UnmarshalJSON decodes Domain, keeping fields unknown to this version of the library in UnknownFields
+func (d *Domain) UnmarshalJSON(data []byte) error {
+	type domain Domain
+	return unmarshalKeepingUnknown(data, (*domain)(d), &d.UnknownFields)
 }
 
-// NullFieldMapStruct returned null Objects structure
-type NullFieldMapStruct struct {
-	Domain      NullPerObjectAttributeStruct            // entry is domain
-	Properties  map[string]NullPerObjectAttributeStruct // entries are properties
-	Datacenters map[string]NullPerObjectAttributeStruct // entries are datacenters
-	Resources   map[string]NullPerObjectAttributeStruct // entries are resources
-	CidrMaps    map[string]NullPerObjectAttributeStruct // entries are cidrmaps
-	GeoMaps     map[string]NullPerObjectAttributeStruct // entries are geomaps
-	AsMaps      map[string]NullPerObjectAttributeStruct // entries are asmaps
+// MarshalJSON encodes Domain together with its UnknownFields
+func (d Domain) MarshalJSON() ([]byte, error) {
+	type domain Domain
+	return marshalKeepingUnknown(domain(d), d.UnknownFields)
 }
 
-// ObjectMap represents ObjectMap datatype
-type ObjectMap map[string]interface{}
-
-func (g *gtm) NullFieldMap(ctx context.Context, domain *Domain) (*NullFieldMapStruct, error) {
-	logger := g.Log(ctx)
-	logger.Debug("NullFieldMap")
-
-	if err := domain.Validate(); err != nil {
-		return nil, fmt.Errorf("domain validation failed. %w", err)
-	}
-
-	var nullFieldMap = &NullFieldMapStruct{}
-	var domFields = NullPerObjectAttributeStruct{}
-	domainMap := make(map[string]string)
-	var objMap ObjectMap
-
-	getURL := fmt.Sprintf("/config-gtm/v1/domains/%s", domain.Name)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GetDomain request: %w", err)
-	}
-	setVersionHeader(req, schemaVersion)
-	resp, err := g.Exec(req, &objMap)
-	if err != nil {
-		return nil, fmt.Errorf("GetDomain request failed: %w", err)
-	}
-	defer session.CloseResponseBody(resp)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, g.Error(resp)
-	}
-
-	for i, d := range objMap {
-		objVal := fmt.Sprint(d)
-		if fmt.Sprintf("%T", d) == "<nil>" {
-			if objVal == "<nil>" {
-				domainMap[makeFirstCharUpperCase(i)] = ""
-			}
-			continue
-		}
-		list, ok := d.([]interface{})
-		if !ok {
-			continue
-		}
-
-		switch i {
-		case "properties":
-			nullFieldMap.Properties = processObjectList(list)
-		case "datacenters":
-			nullFieldMap.Datacenters = processObjectList(list)
-		case "resources":
-			nullFieldMap.Resources = processObjectList(list)
-		case "cidrMaps":
-			nullFieldMap.CidrMaps = processObjectList(list)
-		case "geographicMaps":
-			nullFieldMap.GeoMaps = processObjectList(list)
-		case "asMaps":
-			nullFieldMap.AsMaps = processObjectList(list)
-		}
-	}
-
-	domFields.CoreObjectFields = domainMap
-	nullFieldMap.Domain = domFields
-
-	return nullFieldMap, nil
-
-}
-
-func makeFirstCharUpperCase(origString string) string {
-	a := []rune(origString)
-	a[0] = unicode.ToUpper(a[0])
-	// hack
-	if origString == "cname" {
-		a[1] = unicode.ToUpper(a[1])
-	}
-	return string(a)
-}
-
-func processObjectList(objectList []interface{}) map[string]NullPerObjectAttributeStruct {
-	nullObjectsList := make(map[string]NullPerObjectAttributeStruct)
-	for _, obj := range objectList {
-		nullObjectFields := NullPerObjectAttributeStruct{}
-		objectName := ""
-		objectDCID := ""
-		objectMap := make(map[string]string)
-		objectChildList := make(map[string]interface{})
-		for objF, objD := range obj.(map[string]interface{}) {
-			objVal := fmt.Sprint(objD)
-			switch fmt.Sprintf("%T", objD) {
-			case "<nil>":
-				if objVal == "<nil>" {
-					objectMap[makeFirstCharUpperCase(objF)] = ""
-				}
-			case "map[string]interface {}":
-				// include null stand alone struct elements in core
-				for moName, moValue := range objD.(map[string]interface{}) {
-					if fmt.Sprintf("%T", moValue) == "<nil>" {
-						objectMap[makeFirstCharUpperCase(moName)] = ""
-					}
-				}
-			case "[]interface {}":
-				iSlice := objD.([]interface{})
-				if len(iSlice) > 0 && reflect.TypeOf(iSlice[0]).Kind() != reflect.String && reflect.TypeOf(iSlice[0]).Kind() != reflect.Int64 && reflect.TypeOf(iSlice[0]).Kind() != reflect.Float64 && reflect.TypeOf(iSlice[0]).Kind() != reflect.Int32 {
-					objectChildList[makeFirstCharUpperCase(objF)] = processObjectList(objD.([]interface{}))
-				}
-			default:
-				if objF == "name" {
-					objectName = objVal
-				}
-				if objF == "datacenterId" {
-					objectDCID = objVal
-				}
-			}
-		}
-		nullObjectFields.CoreObjectFields = objectMap
-		nullObjectFields.ChildObjectFields = objectChildList
-
-		if objectDCID == "" {
-			if objectName != "" {
-				nullObjectsList[objectName] = nullObjectFields
-			} else {
-				nullObjectsList["unknown"] = nullObjectFields // TODO: What if mnore than one?
-			}
-		} else {
-			nullObjectsList[objectDCID] = nullObjectFields
-		}
-	}
-
-	return nullObjectsList
+// UnmarshalJSON decodes GetDomainResponse the same way as Domain
+func (r *GetDomainResponse) UnmarshalJSON(data []byte) error {
+	return (*Domain)(r).UnmarshalJSON(data)
 }
@@ -3,7 +3,6 @@ package gtm
 import (
 	"bytes"
 	"context"
-	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -179,87 +178,6 @@ func TestGTM_ListDomains(t *testing.T) {
 	}
 }
 
-func TestGTM_NullFieldMap(t *testing.T) {
-	var result NullFieldMapStruct
-
-	gob.Register(map[string]NullPerObjectAttributeStruct{})
-
-	respData, err := loadTestData("TestGTM_NullFieldMap.resp.json")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	resultData, err := loadTestData("TestGTM_NullFieldMap.result.gob")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err := gob.NewDecoder(bytes.NewBuffer(resultData)).Decode(&result); err != nil {
-		t.Fatal(err)
-	}
-
-	tests := map[string]struct {
-		arg              *Domain
-		responseStatus   int
-		responseBody     []byte
-		expectedPath     string
-		expectedResponse *NullFieldMapStruct
-		withError        error
-	}{
-		"200 OK": {
-			arg: &Domain{
-				Name: "example.akadns.net",
-				Type: "primary",
-			},
-			responseStatus:   http.StatusOK,
-			responseBody:     respData,
-			expectedPath:     "/config-gtm/v1/domains/example.akadns.net",
-			expectedResponse: &result,
-		},
-		"500 internal server error": {
-			arg: &Domain{
-				Name: "example.akadns.net",
-				Type: "primary",
-			},
-			responseStatus: http.StatusInternalServerError,
-			responseBody: []byte(`
-{
-    "type": "internal_error",
-    "title": "Internal Server Error",
-    "detail": "Error fetching null field map",
-    "status": 500
-}`),
-			expectedPath: "/config-gtm/v1/domains/example.akadns.net",
-			withError: &Error{
-				Type:       "internal_error",
-				Title:      "Internal Server Error",
-				Detail:     "Error fetching null field map",
-				StatusCode: http.StatusInternalServerError,
-			},
-		},
-	}
-
-	for name, test := range tests {
-		t.Run(name, func(t *testing.T) {
-			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				assert.Equal(t, test.expectedPath, r.URL.String())
-				assert.Equal(t, http.MethodGet, r.Method)
-				w.WriteHeader(test.responseStatus)
-				_, err := w.Write(test.responseBody)
-				assert.NoError(t, err)
-			}))
-			client := mockAPIClient(t, mockServer)
-			result, err := client.NullFieldMap(context.Background(), test.arg)
-			if test.withError != nil {
-				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
-				return
-			}
-			require.NoError(t, err)
-			assert.Equal(t, test.expectedResponse, result)
-		})
-	}
-}
-
 func TestGTM_GetDomain(t *testing.T) {
 	var result GetDomainResponse
 
@@ -0,0 +1,449 @@
+package gtm
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	// minLivenessTestInterval is the minimum interval between liveness tests, in seconds
+	minLivenessTestInterval = 10
+	// maxLivenessTestTimeout is the maximum liveness test timeout used when the domain does not define MaxTestTimeout, in seconds
+	maxLivenessTestTimeout = 60
+	// constrainAllProperties is the ConstrainedProperty value of resources constraining all properties
+	constrainAllProperties = "**"
+)
+
+var livenessTestProtocols = []interface{}{
+	"HTTP", "HTTPS", "FTP", "POP", "POPS", "SMTP", "SMTPS", "TCP", "TCPS", "DNS", "SNMP", "SNMPV3", "SIP",
+}
+
+// domainIndex contains names and IDs of objects defined in the domain, used to check references between them
+type domainIndex struct {
+	datacenters map[int]bool
+	properties  map[string]bool
+	geoMaps     map[string]*GeoMap
+	cidrMaps    map[string]*CIDRMap
+	asMaps      map[string]*ASMap
+}
+
+// ValidateDomain validates the whole domain before it's submitted with UpdateDomain. On top of checks of single objects,
+// it verifies references between properties, datacenters, maps and resources, overlapping CIDR blocks, duplicate ASNs
+// and countries in maps, traffic target weights and liveness test parameter ranges.
+func ValidateDomain(d *Domain) error {
+	if d == nil {
+		return edgegriderr.ParseValidationErrors(validation.Errors{"Domain": validation.ErrRequired})
+	}
+
+	errs := validation.Errors{
+		"Name": validation.Validate(d.Name, validation.Required),
+		"Type": validation.Validate(d.Type, validation.Required),
+	}
+	index, datacenterErrs := indexDomain(d)
+	errs["Datacenters"] = datacenterErrs.Filter()
+	errs["Properties"] = validateProperties(d, index).Filter()
+	errs["GeographicMaps"] = validateGeoMaps(d.GeographicMaps, index).Filter()
+	errs["CIDRMaps"] = validateCIDRMaps(d.CIDRMaps, index).Filter()
+	errs["ASMaps"] = validateASMaps(d.ASMaps, index).Filter()
+	errs["Resources"] = validateResources(d.Resources, index).Filter()
+
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// indexDomain collects datacenter IDs and names of properties and maps, reporting duplicate datacenters
+func indexDomain(d *Domain) (domainIndex, validation.Errors) {
+	index := domainIndex{
+		datacenters: make(map[int]bool),
+		properties:  make(map[string]bool),
+		geoMaps:     make(map[string]*GeoMap),
+		cidrMaps:    make(map[string]*CIDRMap),
+		asMaps:      make(map[string]*ASMap),
+	}
+
+	errs := validation.Errors{}
+	for i, dc := range d.Datacenters {
+		if dc.DatacenterID == 0 {
+			continue
+		}
+		if index.datacenters[dc.DatacenterID] {
+			errs[strconv.Itoa(i)] = validation.Errors{
+				"DatacenterID": fmt.Errorf("duplicate datacenter %d", dc.DatacenterID),
+			}
+		}
+		index.datacenters[dc.DatacenterID] = true
+	}
+	for _, p := range d.Properties {
+		index.properties[p.Name] = true
+	}
+	for i := range d.GeographicMaps {
+		index.geoMaps[d.GeographicMaps[i].Name] = &d.GeographicMaps[i]
+	}
+	for i := range d.CIDRMaps {
+		index.cidrMaps[d.CIDRMaps[i].Name] = &d.CIDRMaps[i]
+	}
+	for i := range d.ASMaps {
+		index.asMaps[d.ASMaps[i].Name] = &d.ASMaps[i]
+	}
+	return index, errs
+}
+
+// datacenterExists returns a rule checking that the datacenter ID refers to a datacenter of the domain
+func (index domainIndex) datacenterExists(value interface{}) error {
+	id, _ := value.(int)
+	if !index.datacenters[id] {
+		return fmt.Errorf("datacenter %d does not exist in the domain", id)
+	}
+	return nil
+}
+
+func validateProperties(d *Domain, index domainIndex) validation.Errors {
+	errs := validation.Errors{}
+	seen := make(map[string]bool)
+	for i, p := range d.Properties {
+		propertyErrs := validation.Errors{
+			"Name": validation.Validate(p.Name, validation.Required, validation.By(func(interface{}) error {
+				if seen[p.Name] {
+					return fmt.Errorf("duplicate property %q", p.Name)
+				}
+				return nil
+			})),
+			"Type":           validation.Validate(p.Type, validation.Required),
+			"MapName":        validation.Validate(p.MapName, validation.By(index.mapExists(p.Type)), validation.By(index.mappedTargetsExist(p))),
+			"TrafficTargets": validateTrafficTargets(p, index),
+			"LivenessTests":  validateLivenessTests(d, p.LivenessTests).Filter(),
+		}
+		if err := propertyErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+		seen[p.Name] = true
+	}
+	return errs
+}
+
+// mapExists returns a rule checking that map based properties refer to an existing map of their type
+func (index domainIndex) mapExists(propertyType string) validation.RuleFunc {
+	return func(value interface{}) error {
+		name, _ := value.(string)
+		var exists bool
+		var kind string
+		switch propertyType {
+		case PropertyTypeGeographic:
+			exists, kind = index.geoMaps[name] != nil, "geographic map"
+		case PropertyTypeCIDRMapping:
+			exists, kind = index.cidrMaps[name] != nil, "CIDR map"
+		case PropertyTypeASMapping:
+			exists, kind = index.asMaps[name] != nil, "AS map"
+		default:
+			return nil
+		}
+		if name == "" {
+			return fmt.Errorf("is required for %s properties", propertyType)
+		}
+		if !exists {
+			return fmt.Errorf("%s %q does not exist in the domain", kind, name)
+		}
+		return nil
+	}
+}
+
+// mappedTargetsExist returns a rule checking that every datacenter assigned by the property's map has a traffic target
+func (index domainIndex) mappedTargetsExist(p Property) validation.RuleFunc {
+	return func(interface{}) error {
+		targets := make(map[int]bool)
+		for _, tt := range p.TrafficTargets {
+			targets[tt.DatacenterID] = true
+		}
+		for _, id := range index.mappedDatacenters(p) {
+			if !targets[id] {
+				return fmt.Errorf("datacenter %d assigned by map %q has no traffic target", id, p.MapName)
+			}
+		}
+		return nil
+	}
+}
+
+// mappedDatacenters returns IDs of datacenters the property's map assigns clients to
+func (index domainIndex) mappedDatacenters(p Property) []int {
+	var ids []int
+	addDefault := func(dc *DatacenterBase) {
+		if dc != nil {
+			ids = append(ids, dc.DatacenterID)
+		}
+	}
+	switch p.Type {
+	case PropertyTypeGeographic:
+		if m := index.geoMaps[p.MapName]; m != nil {
+			addDefault(m.DefaultDatacenter)
+			for _, a := range m.Assignments {
+				ids = append(ids, a.DatacenterID)
+			}
+		}
+	case PropertyTypeCIDRMapping:
+		if m := index.cidrMaps[p.MapName]; m != nil {
+			addDefault(m.DefaultDatacenter)
+			for _, a := range m.Assignments {
+				ids = append(ids, a.DatacenterID)
+			}
+		}
+	case PropertyTypeASMapping:
+		if m := index.asMaps[p.MapName]; m != nil {
+			addDefault(m.DefaultDatacenter)
+			for _, a := range m.Assignments {
+				ids = append(ids, a.DatacenterID)
+			}
+		}
+	}
+	return ids
+}
+
+// validateTrafficTargets returns errors of single traffic targets or, if there are none, an error of the targets as a whole
+func validateTrafficTargets(p Property, index domainIndex) error {
+	errs := validation.Errors{}
+	targets := make(map[int]bool)
+	var enabled int
+	var weights float64
+	for i, tt := range p.TrafficTargets {
+		targetErrs := validation.Errors{
+			"DatacenterID": validation.Validate(tt.DatacenterID, validation.By(index.datacenterExists), validation.By(func(interface{}) error {
+				if targets[tt.DatacenterID] {
+					return fmt.Errorf("duplicate traffic target for datacenter %d", tt.DatacenterID)
+				}
+				return nil
+			})),
+			"Weight": validation.Validate(tt.Weight, validation.Min(0.0)),
+		}
+		if err := targetErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+		targets[tt.DatacenterID] = true
+		if tt.Enabled {
+			enabled++
+			weights += deref(tt.Weight)
+		}
+	}
+	if err := errs.Filter(); err != nil {
+		return err
+	}
+
+	weighted := p.Type == PropertyTypeWeightedRoundRobin || p.Type == PropertyTypeWeightedRoundRobinLoadFeedback ||
+		p.Type == PropertyTypeWeightedHashed || p.Type == PropertyTypeFailover
+	switch {
+	case len(p.TrafficTargets) == 0:
+		return nil
+	case enabled == 0:
+		return errors.New("no traffic targets are enabled")
+	case weighted && weights <= 0:
+		return fmt.Errorf("weights of enabled traffic targets have to sum up to a positive value for %s properties", p.Type)
+	}
+	return nil
+}
+
+func validateLivenessTests(d *Domain, tests []LivenessTest) validation.Errors {
+	maxTimeout := deref(d.MaxTestTimeout)
+	if maxTimeout == 0 {
+		maxTimeout = maxLivenessTestTimeout
+	}
+	minInterval := deref(d.MinTestInterval)
+	if minInterval == 0 {
+		minInterval = minLivenessTestInterval
+	}
+
+	errs := validation.Errors{}
+	seen := make(map[string]bool)
+	for i, lt := range tests {
+		isHTTP := strings.HasPrefix(strings.ToUpper(lt.TestObjectProtocol), "HTTP")
+		testErrs := validation.Errors{
+			"Name": validation.Validate(lt.Name, validation.Required, validation.By(func(interface{}) error {
+				if seen[lt.Name] {
+					return fmt.Errorf("duplicate liveness test %q", lt.Name)
+				}
+				return nil
+			})),
+			"TestObjectProtocol": validation.Validate(strings.ToUpper(lt.TestObjectProtocol), validation.Required, validation.In(livenessTestProtocols...)),
+			"TestInterval":       validation.Validate(lt.TestInterval, validation.Required, validation.Min(minInterval)),
+			"TestTimeout":        validation.Validate(float64(deref(lt.TestTimeout)), validation.Min(0.001), validation.Max(maxTimeout)),
+			"TestObjectPort":     validation.Validate(lt.TestObjectPort, validation.Min(0), validation.Max(65535)),
+			"TestObject":         validation.Validate(lt.TestObject, validation.When(isHTTP, validation.Required)),
+			"ErrorPenalty":       validation.Validate(lt.ErrorPenalty, validation.Min(0.0)),
+			"TimeoutPenalty":     validation.Validate(lt.TimeoutPenalty, validation.Min(0.0)),
+		}
+		if err := testErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+		seen[lt.Name] = true
+	}
+	return errs
+}
+
+// validateMap returns errors common for all map types and tracks duplicate names
+func validateMap(name string, defaultDC *DatacenterBase, seen map[string]bool, index domainIndex) validation.Errors {
+	errs := validation.Errors{
+		"Name": validation.Validate(name, validation.Required, validation.By(func(interface{}) error {
+			if seen[name] {
+				return fmt.Errorf("duplicate map %q", name)
+			}
+			return nil
+		})),
+		"DefaultDatacenter": validation.Validate(defaultDC, validation.Required, validation.By(func(interface{}) error {
+			if defaultDC == nil {
+				return nil
+			}
+			return index.datacenterExists(defaultDC.DatacenterID)
+		})),
+	}
+	seen[name] = true
+	return errs
+}
+
+func validateGeoMaps(maps []GeoMap, index domainIndex) validation.Errors {
+	errs := validation.Errors{}
+	names := make(map[string]bool)
+	for i, m := range maps {
+		mapErrs := validateMap(m.Name, m.DefaultDatacenter, names, index)
+		assignmentErrs := validation.Errors{}
+		countries := make(map[string]int)
+		for j, a := range m.Assignments {
+			aErrs := validation.Errors{
+				"DatacenterID": validation.Validate(a.DatacenterID, validation.By(index.datacenterExists)),
+			}
+			for _, c := range a.Countries {
+				c = strings.ToUpper(c)
+				if first, ok := countries[c]; ok {
+					aErrs["Countries"] = fmt.Errorf("country %s is already assigned at index %d", c, first)
+					break
+				}
+				countries[c] = j
+			}
+			if err := aErrs.Filter(); err != nil {
+				assignmentErrs[strconv.Itoa(j)] = err
+			}
+		}
+		mapErrs["Assignments"] = assignmentErrs.Filter()
+		if err := mapErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return errs
+}
+
+// cidrBlock is a parsed block of a CIDR map assignment
+type cidrBlock struct {
+	prefix     netip.Prefix
+	assignment int
+}
+
+func validateCIDRMaps(maps []CIDRMap, index domainIndex) validation.Errors {
+	errs := validation.Errors{}
+	names := make(map[string]bool)
+	for i, m := range maps {
+		mapErrs := validateMap(m.Name, m.DefaultDatacenter, names, index)
+		assignmentErrs := validation.Errors{}
+		var blocks []cidrBlock
+		for j, a := range m.Assignments {
+			aErrs := validation.Errors{
+				"DatacenterID": validation.Validate(a.DatacenterID, validation.By(index.datacenterExists)),
+			}
+			for _, b := range a.Blocks {
+				prefix, err := netip.ParsePrefix(b)
+				if err != nil {
+					aErrs["Blocks"] = fmt.Errorf("invalid block %q: %w", b, err)
+					break
+				}
+				prefix = prefix.Masked()
+				if overlap := overlappingBlock(prefix, blocks); overlap != nil {
+					aErrs["Blocks"] = fmt.Errorf("block %s overlaps with block %s at index %d", b, overlap.prefix, overlap.assignment)
+					break
+				}
+				blocks = append(blocks, cidrBlock{prefix: prefix, assignment: j})
+			}
+			if err := aErrs.Filter(); err != nil {
+				assignmentErrs[strconv.Itoa(j)] = err
+			}
+		}
+		mapErrs["Assignments"] = assignmentErrs.Filter()
+		if err := mapErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return errs
+}
+
+// overlappingBlock returns the first block overlapping with the prefix, or nil
+func overlappingBlock(prefix netip.Prefix, blocks []cidrBlock) *cidrBlock {
+	for i := range blocks {
+		if blocks[i].prefix.Overlaps(prefix) {
+			return &blocks[i]
+		}
+	}
+	return nil
+}
+
+func validateASMaps(maps []ASMap, index domainIndex) validation.Errors {
+	errs := validation.Errors{}
+	names := make(map[string]bool)
+	for i, m := range maps {
+		mapErrs := validateMap(m.Name, m.DefaultDatacenter, names, index)
+		assignmentErrs := validation.Errors{}
+		numbers := make(map[int64]int)
+		for j, a := range m.Assignments {
+			aErrs := validation.Errors{
+				"DatacenterID": validation.Validate(a.DatacenterID, validation.By(index.datacenterExists)),
+			}
+			for _, n := range a.ASNumbers {
+				if first, ok := numbers[n]; ok {
+					aErrs["ASNumbers"] = fmt.Errorf("AS number %d is already assigned at index %d", n, first)
+					break
+				}
+				numbers[n] = j
+			}
+			if err := aErrs.Filter(); err != nil {
+				assignmentErrs[strconv.Itoa(j)] = err
+			}
+		}
+		mapErrs["Assignments"] = assignmentErrs.Filter()
+		if err := mapErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return errs
+}
+
+func validateResources(resources []Resource, index domainIndex) validation.Errors {
+	errs := validation.Errors{}
+	names := make(map[string]bool)
+	for i, r := range resources {
+		instanceErrs := validation.Errors{}
+		for j, ri := range r.ResourceInstances {
+			if err := validation.Validate(ri.DatacenterID, validation.By(index.datacenterExists)); err != nil {
+				instanceErrs[strconv.Itoa(j)] = validation.Errors{"DatacenterID": err}
+			}
+		}
+		resourceErrs := validation.Errors{
+			"Name": validation.Validate(r.Name, validation.Required, validation.By(func(interface{}) error {
+				if names[r.Name] {
+					return fmt.Errorf("duplicate resource %q", r.Name)
+				}
+				return nil
+			})),
+			"Type": validation.Validate(r.Type, validation.Required),
+			"ConstrainedProperty": validation.Validate(r.ConstrainedProperty, validation.By(func(interface{}) error {
+				if r.ConstrainedProperty == "" || r.ConstrainedProperty == constrainAllProperties || index.properties[r.ConstrainedProperty] {
+					return nil
+				}
+				return fmt.Errorf("property %q does not exist in the domain", r.ConstrainedProperty)
+			})),
+			"ResourceInstances": instanceErrs.Filter(),
+		}
+		if err := resourceErrs.Filter(); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+		names[r.Name] = true
+	}
+	return errs
+}
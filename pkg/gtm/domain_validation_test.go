@@ -0,0 +1,219 @@
+package gtm
+
+import (
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validDomain() *Domain {
+	return &Domain{
+		Name:        "example.akadns.net",
+		Type:        "full",
+		Datacenters: []Datacenter{{DatacenterID: 3131}, {DatacenterID: 3132}, {DatacenterID: 5400}},
+		Properties: []Property{
+			{
+				Name: "www", Type: "weighted-round-robin",
+				TrafficTargets: []TrafficTarget{
+					{DatacenterID: 3131, Enabled: true, Weight: ptr.To(50.0)},
+					{DatacenterID: 3132, Enabled: true, Weight: ptr.To(50.0)},
+				},
+				LivenessTests: []LivenessTest{
+					{Name: "http", TestObjectProtocol: "HTTP", TestObject: "/health", TestInterval: ptr.To(60), TestTimeout: ptr.To(float32(10)), TestObjectPort: ptr.To(80)},
+				},
+			},
+			{
+				Name: "geo", Type: "geographic", MapName: "geo-map",
+				TrafficTargets: []TrafficTarget{
+					{DatacenterID: 3131, Enabled: true},
+					{DatacenterID: 5400, Enabled: true},
+				},
+			},
+		},
+		GeographicMaps: []GeoMap{{
+			Name:              "geo-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 5400},
+			Assignments:       []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Countries: []string{"PL", "DE"}}},
+		}},
+		CIDRMaps: []CIDRMap{{
+			Name:              "cidr-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 5400},
+			Assignments: []CIDRAssignment{
+				{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Blocks: []string{"10.0.0.0/16", "2001:db8::/32"}},
+				{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Blocks: []string{"10.1.0.0/16"}},
+			},
+		}},
+		ASMaps: []ASMap{{
+			Name:              "as-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 5400},
+			Assignments:       []ASAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, ASNumbers: []int64{12222}}},
+		}},
+		Resources: []Resource{{
+			Name: "cpu", Type: "XML load object via HTTP", ConstrainedProperty: "www",
+			ResourceInstances: []ResourceInstance{{DatacenterID: 3131}},
+		}},
+	}
+}
+
+func TestValidateDomain(t *testing.T) {
+	tests := map[string]struct {
+		modify    func(*Domain)
+		withError string
+	}{
+		"valid domain": {
+			modify: func(*Domain) {},
+		},
+		"missing name and type": {
+			modify: func(d *Domain) {
+				d.Name, d.Type = "", ""
+			},
+			withError: "Name: cannot be blank\nType: cannot be blank",
+		},
+		"duplicate datacenter": {
+			modify: func(d *Domain) {
+				d.Datacenters = append(d.Datacenters, Datacenter{DatacenterID: 3131})
+			},
+			withError: "Datacenters[3]: {\n\tDatacenterID: duplicate datacenter 3131\n}",
+		},
+		"traffic target with unknown datacenter": {
+			modify: func(d *Domain) {
+				d.Properties[0].TrafficTargets[1].DatacenterID = 9999
+			},
+			withError: "Properties[0]: {\n\tTrafficTargets[1]: {\n\t\tDatacenterID: datacenter 9999 does not exist in the domain\n\t}\n}",
+		},
+		"duplicate traffic target and negative weight": {
+			modify: func(d *Domain) {
+				d.Properties[0].TrafficTargets[1].DatacenterID = 3131
+				d.Properties[0].TrafficTargets[1].Weight = ptr.To(-1.0)
+			},
+			withError: "Properties[0]: {\n\tTrafficTargets[1]: {\n\t\tDatacenterID: duplicate traffic target for datacenter 3131\n\t\tWeight: must be no less than 0\n\t}\n}",
+		},
+		"weights do not sum up": {
+			modify: func(d *Domain) {
+				d.Properties[0].TrafficTargets[0].Weight = ptr.To(0.0)
+				d.Properties[0].TrafficTargets[1].Weight = ptr.To(0.0)
+			},
+			withError: "Properties[0]: {\n\tTrafficTargets: weights of enabled traffic targets have to sum up to a positive value for weighted-round-robin properties\n}",
+		},
+		"no enabled traffic targets": {
+			modify: func(d *Domain) {
+				d.Properties[0].TrafficTargets[0].Enabled = false
+				d.Properties[0].TrafficTargets[1].Enabled = false
+			},
+			withError: "TrafficTargets: no traffic targets are enabled",
+		},
+		"duplicate property": {
+			modify: func(d *Domain) {
+				d.Properties[1].Name = "www"
+			},
+			withError: "Properties[1]: {\n\tName: duplicate property \"www\"\n}",
+		},
+		"missing map": {
+			modify: func(d *Domain) {
+				d.Properties[1].MapName = "other"
+			},
+			withError: "MapName: geographic map \"other\" does not exist in the domain",
+		},
+		"map datacenter without traffic target": {
+			modify: func(d *Domain) {
+				d.Properties[1].TrafficTargets = d.Properties[1].TrafficTargets[:1]
+			},
+			withError: "MapName: datacenter 5400 assigned by map \"geo-map\" has no traffic target",
+		},
+		"liveness test out of range": {
+			modify: func(d *Domain) {
+				lt := &d.Properties[0].LivenessTests[0]
+				lt.TestInterval = ptr.To(5)
+				lt.TestTimeout = ptr.To(float32(90))
+				lt.TestObjectPort = ptr.To(70000)
+				lt.TestObjectProtocol = "gopher"
+				lt.TestObject = ""
+			},
+			withError: "Properties[0]: {\n\tLivenessTests[0]: {\n\t\tTestInterval: must be no less than 10\n\t\tTestObjectPort: must be no greater than 65535\n" +
+				"\t\tTestObjectProtocol: must be a valid value\n\t\tTestTimeout: must be no greater than 60\n\t}\n}",
+		},
+		"HTTP liveness test without test object": {
+			modify: func(d *Domain) {
+				d.Properties[0].LivenessTests[0].TestObject = ""
+			},
+			withError: "TestObject: cannot be blank",
+		},
+		"liveness test timeout above domain max": {
+			modify: func(d *Domain) {
+				d.MaxTestTimeout = ptr.To(5.0)
+			},
+			withError: "TestTimeout: must be no greater than 5",
+		},
+		"duplicate country": {
+			modify: func(d *Domain) {
+				d.GeographicMaps[0].Assignments = append(d.GeographicMaps[0].Assignments,
+					GeoAssignment{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Countries: []string{"US", "pl"}})
+			},
+			withError: "GeographicMaps[0]: {\n\tAssignments[1]: {\n\t\tCountries: country PL is already assigned at index 0\n\t}\n}",
+		},
+		"overlapping CIDR blocks": {
+			modify: func(d *Domain) {
+				d.CIDRMaps[0].Assignments[1].Blocks = []string{"10.0.128.0/24"}
+			},
+			withError: "CIDRMaps[0]: {\n\tAssignments[1]: {\n\t\tBlocks: block 10.0.128.0/24 overlaps with block 10.0.0.0/16 at index 0\n\t}\n}",
+		},
+		"invalid CIDR block": {
+			modify: func(d *Domain) {
+				d.CIDRMaps[0].Assignments[1].Blocks = []string{"10.0.0/8"}
+			},
+			withError: `Blocks: invalid block "10.0.0/8"`,
+		},
+		"duplicate ASN": {
+			modify: func(d *Domain) {
+				d.ASMaps[0].Assignments = append(d.ASMaps[0].Assignments,
+					ASAssignment{DatacenterBase: DatacenterBase{DatacenterID: 3132}, ASNumbers: []int64{1, 12222}})
+			},
+			withError: "ASMaps[0]: {\n\tAssignments[1]: {\n\t\tASNumbers: AS number 12222 is already assigned at index 0\n\t}\n}",
+		},
+		"map with unknown datacenters": {
+			modify: func(d *Domain) {
+				d.ASMaps[0].DefaultDatacenter = &DatacenterBase{DatacenterID: 1}
+				d.ASMaps[0].Assignments[0].DatacenterID = 2
+			},
+			withError: "ASMaps[0]: {\n\tAssignments[0]: {\n\t\tDatacenterID: datacenter 2 does not exist in the domain\n\t}\n" +
+				"\tDefaultDatacenter: datacenter 1 does not exist in the domain\n}",
+		},
+		"map without default datacenter": {
+			modify: func(d *Domain) {
+				d.CIDRMaps[0].DefaultDatacenter = nil
+			},
+			withError: "CIDRMaps[0]: {\n\tDefaultDatacenter: cannot be blank\n}",
+		},
+		"resource references": {
+			modify: func(d *Domain) {
+				d.Resources[0].ConstrainedProperty = "missing"
+				d.Resources[0].ResourceInstances[0].DatacenterID = 7
+			},
+			withError: "Resources[0]: {\n\tConstrainedProperty: property \"missing\" does not exist in the domain\n" +
+				"\tResourceInstances[0]: {\n\t\tDatacenterID: datacenter 7 does not exist in the domain\n\t}\n}",
+		},
+		"resource constraining all properties": {
+			modify: func(d *Domain) {
+				d.Resources[0].ConstrainedProperty = "**"
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			domain := validDomain()
+			test.modify(domain)
+			err := ValidateDomain(domain)
+			if test.withError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	assert.EqualError(t, ValidateDomain(nil), "Domain: cannot be blank")
+}
@@ -15,7 +15,8 @@ type (
 	// GeoAssignment represents a GTM Geo assignment element
 	GeoAssignment struct {
 		DatacenterBase
-		Countries []string `json:"countries"`
+		Countries     []string      `json:"countries"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// GeoMap represents a GTM GeoMap
@@ -24,6 +25,7 @@ type (
 		Assignments       []GeoAssignment `json:"assignments,omitempty"`
 		Name              string          `json:"name"`
 		Links             []Link          `json:"links,omitempty"`
+		UnknownFields     UnknownFields   `json:"-"`
 	}
 
 	// GeoMapList represents the returned GTM GeoMap List body
@@ -286,3 +288,32 @@ func (g *gtm) DeleteGeoMap(ctx context.Context, params DeleteGeoMapRequest) (*De
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes GeoMap, keeping fields unknown to this version of the library in UnknownFields
+func (g *GeoMap) UnmarshalJSON(data []byte) error {
+	type geoMap GeoMap
+	return unmarshalKeepingUnknown(data, (*geoMap)(g), &g.UnknownFields)
+}
+
+// MarshalJSON encodes GeoMap together with its UnknownFields
+func (g GeoMap) MarshalJSON() ([]byte, error) {
+	type geoMap GeoMap
+	return marshalKeepingUnknown(geoMap(g), g.UnknownFields)
+}
+
+// UnmarshalJSON decodes GetGeoMapResponse the same way as GeoMap
+func (r *GetGeoMapResponse) UnmarshalJSON(data []byte) error {
+	return (*GeoMap)(r).UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes GeoAssignment, keeping fields unknown to this version of the library in UnknownFields
+func (a *GeoAssignment) UnmarshalJSON(data []byte) error {
+	type geoAssignment GeoAssignment
+	return unmarshalKeepingUnknown(data, (*geoAssignment)(a), &a.UnknownFields)
+}
+
+// MarshalJSON encodes GeoAssignment together with its UnknownFields
+func (a GeoAssignment) MarshalJSON() ([]byte, error) {
+	type geoAssignment GeoAssignment
+	return marshalKeepingUnknown(geoAssignment(a), a.UnknownFields)
+}
@@ -21,9 +21,6 @@ type (
 	GTM interface {
 		// Domains
 
-		// NullFieldMap retrieves map of null fields.
-		NullFieldMap(context.Context, *Domain) (*NullFieldMapStruct, error)
-
 		// GetDomainStatus retrieves current status for the given domain name.
 		//
 		// See: https://techdocs.akamai.com/gtm/reference/get-status-current
@@ -253,3 +250,12 @@ func Client(sess session.Session, opts ...Option) GTM {
 func (g *gtm) Exec(r *http.Request, out interface{}, in ...interface{}) (*http.Response, error) {
 	return g.Session.Exec(r, out, in...)
 }
+
+// deref returns the value the pointer points to, or the zero value if it's nil
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,391 @@
+package gtm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type (
+	// LivenessDryRunOptions contains options of liveness test dry runs
+	LivenessDryRunOptions struct {
+		// Addresses maps servers of traffic targets to 'host:port' addresses which are probed instead,
+		// e.g. to point probes at a local httptest server
+		Addresses map[string]string
+		// RootCAs is used to verify certificates when PeerCertificateVerification is enabled. Defaults to the system pool
+		RootCAs *x509.CertPool
+	}
+
+	// LivenessDryRunResult contains the outcome of a single probe
+	LivenessDryRunResult struct {
+		TestName     string
+		DatacenterID int
+		Server       string
+		// Up tells whether GTM would consider the server up
+		Up bool
+		// Reason explains why the server is considered down
+		Reason string
+		// StatusCode is the HTTP status code returned by the server, for HTTP and HTTPS tests
+		StatusCode int
+		Duration   time.Duration
+	}
+)
+
+const (
+	// defaultLivenessTestTimeout is the timeout of probes of liveness tests without TestTimeout, in seconds
+	defaultLivenessTestTimeout = 25
+	// maxLivenessResponseSize limits the size of response read when looking for ResponseString
+	maxLivenessResponseSize = 1 << 20
+)
+
+var (
+	// ErrLivenessDryRun is returned when the liveness test can't be run
+	ErrLivenessDryRun = errors.New("liveness test dry run")
+)
+
+// DryRunLivenessTests runs each enabled liveness test of the property against servers of its enabled traffic targets
+func DryRunLivenessTests(ctx context.Context, property *Property, opts LivenessDryRunOptions) ([]LivenessDryRunResult, error) {
+	if property == nil {
+		return nil, fmt.Errorf("%w: property is required", ErrLivenessDryRun)
+	}
+	var results []LivenessDryRunResult
+	for _, test := range property.LivenessTests {
+		if test.Disabled {
+			continue
+		}
+		testResults, err := DryRunLivenessTest(ctx, test, property.TrafficTargets, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, testResults...)
+	}
+	return results, nil
+}
+
+// DryRunLivenessTest runs the liveness test locally against each server of enabled traffic targets
+// and reports whether GTM would consider it up. HTTP, HTTPS, TCP, TCPS and DNS tests are supported.
+//
+// Probes are sent from the local machine, so the results can differ from those of GTM because of firewalls
+// and network paths, but they verify that the test definition matches responses of the servers.
+func DryRunLivenessTest(ctx context.Context, test LivenessTest, targets []TrafficTarget, opts LivenessDryRunOptions) ([]LivenessDryRunResult, error) {
+	probe, err := livenessProbe(test, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: test %q: %s", ErrLivenessDryRun, test.Name, err)
+	}
+
+	var results []LivenessDryRunResult
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+		for _, server := range target.Servers {
+			if err := ctx.Err(); err != nil {
+				return results, fmt.Errorf("%w: %w", ErrLivenessDryRun, err)
+			}
+			result := LivenessDryRunResult{TestName: test.Name, DatacenterID: target.DatacenterID, Server: server}
+			start := time.Now()
+			result.StatusCode, err = probe(ctx, server)
+			result.Duration = time.Since(start)
+			result.Up = err == nil
+			if err != nil {
+				result.Reason = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// livenessProbeFunc probes a single server, it returns the HTTP status code, if any, and the reason of failure
+type livenessProbeFunc func(ctx context.Context, server string) (int, error)
+
+func livenessProbe(test LivenessTest, opts LivenessDryRunOptions) (livenessProbeFunc, error) {
+	timeout := time.Duration(float64(deref(test.TestTimeout)) * float64(time.Second))
+	if timeout <= 0 {
+		timeout = defaultLivenessTestTimeout * time.Second
+	}
+	tlsConfig, err := livenessTLSConfig(test, opts)
+	if err != nil {
+		return nil, err
+	}
+	address := func(server string, defaultPort int) string {
+		port := deref(test.TestObjectPort)
+		if port == 0 {
+			port = defaultPort
+		}
+		if addr, ok := opts.Addresses[server]; ok {
+			return addr
+		}
+		return net.JoinHostPort(server, strconv.Itoa(port))
+	}
+
+	switch protocol := strings.ToUpper(test.TestObjectProtocol); protocol {
+	case "HTTP", "HTTPS":
+		return httpProbe(test, protocol, timeout, tlsConfig, address), nil
+	case "TCP", "TCPS":
+		if deref(test.TestObjectPort) == 0 {
+			return nil, fmt.Errorf("TestObjectPort is required for %s tests", protocol)
+		}
+		if protocol == "TCP" {
+			tlsConfig = nil
+		}
+		return tcpProbe(test, timeout, tlsConfig, address), nil
+	case "DNS":
+		if test.TestObject == "" {
+			return nil, errors.New("TestObject is required for DNS tests")
+		}
+		return dnsProbe(test, timeout, address)
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", test.TestObjectProtocol)
+	}
+}
+
+func livenessTLSConfig(test LivenessTest, opts LivenessDryRunOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		RootCAs:            opts.RootCAs,
+		InsecureSkipVerify: !test.PeerCertificateVerification,
+	}
+	if test.SSLClientCertificate != "" && test.SSLClientPrivateKey != "" {
+		cert, err := tls.X509KeyPair([]byte(test.SSLClientCertificate), []byte(test.SSLClientPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func httpProbe(test LivenessTest, protocol string, timeout time.Duration, tlsConfig *tls.Config,
+	address func(string, int) string) livenessProbeFunc {
+
+	scheme, defaultPort := "http", 80
+	if protocol == "HTTPS" {
+		scheme, defaultPort = "https", 443
+	}
+
+	return func(ctx context.Context, server string) (int, error) {
+		addr := address(server, defaultPort)
+		dialer := &net.Dialer{Timeout: timeout}
+		client := &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, addr)
+				},
+			},
+			// GTM does not follow redirects
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		defer client.CloseIdleConnections()
+
+		method := http.MethodGet
+		if test.HTTPMethod != nil && *test.HTTPMethod != "" {
+			method = strings.ToUpper(*test.HTTPMethod)
+		}
+		var body io.Reader
+		if test.HTTPRequestBody != nil {
+			body = strings.NewReader(*test.HTTPRequestBody)
+		}
+		path := test.TestObject
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		host := server
+		if port := deref(test.TestObjectPort); port != 0 && port != defaultPort {
+			host = net.JoinHostPort(server, strconv.Itoa(port))
+		} else if strings.Contains(server, ":") {
+			host = "[" + server + "]"
+		}
+		url := fmt.Sprintf("%s://%s%s", scheme, host, path)
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return 0, fmt.Errorf("invalid request: %w", err)
+		}
+		for _, h := range test.HTTPHeaders {
+			if strings.EqualFold(h.Name, "Host") {
+				req.Host = h.Value
+				continue
+			}
+			req.Header.Add(h.Name, h.Value)
+		}
+		if test.TestObjectUsername != "" {
+			req.SetBasicAuth(test.TestObjectUsername, test.TestObjectPassword)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, probeError(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		code := resp.StatusCode
+		switch {
+		case code >= 300 && code < 400 && test.HTTPError3xx,
+			code >= 400 && code < 500 && test.HTTPError4xx,
+			code >= 500 && test.HTTPError5xx:
+			return code, fmt.Errorf("HTTP status %d is treated as an error", code)
+		}
+		if test.ResponseString != "" {
+			if err := expectResponseString(resp.Body, test.ResponseString); err != nil {
+				return code, err
+			}
+		}
+		return code, nil
+	}
+}
+
+func tcpProbe(test LivenessTest, timeout time.Duration, tlsConfig *tls.Config, address func(string, int) string) livenessProbeFunc {
+	return func(ctx context.Context, server string) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		addr := address(server, deref(test.TestObjectPort))
+		var conn net.Conn
+		var err error
+		if tlsConfig != nil {
+			dialer := &tls.Dialer{Config: tlsConfig}
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		} else {
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		}
+		if err != nil {
+			return 0, probeError(err)
+		}
+		defer func() { _ = conn.Close() }()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		if test.RequestString != "" {
+			if _, err := conn.Write([]byte(test.RequestString)); err != nil {
+				return 0, probeError(err)
+			}
+		}
+		if test.ResponseString != "" {
+			return 0, expectResponseString(conn, test.ResponseString)
+		}
+		return 0, nil
+	}
+}
+
+// expectResponseString reads the response until it contains expected, fails on EOF or a read error
+func expectResponseString(r io.Reader, expected string) error {
+	var response []byte
+	buf := make([]byte, 4096)
+	for len(response) < maxLivenessResponseSize {
+		n, err := r.Read(buf)
+		response = append(response, buf[:n]...)
+		if bytes.Contains(response, []byte(expected)) {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return probeError(err)
+		}
+	}
+	return fmt.Errorf("response does not contain %q", expected)
+}
+
+func dnsProbe(test LivenessTest, timeout time.Duration, address func(string, int) string) (livenessProbeFunc, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(test.TestObject))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TestObject: %w", err)
+	}
+	recordType := dnsmessage.TypeA
+	if test.ResourceType != "" {
+		var ok bool
+		if recordType, ok = dnsTypes[strings.ToUpper(test.ResourceType)]; !ok {
+			return nil, fmt.Errorf("unsupported ResourceType %q", test.ResourceType)
+		}
+	}
+
+	return func(ctx context.Context, server string) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		id := uint16(time.Now().UnixNano())
+		query := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: id, RecursionDesired: test.RecursionRequested},
+			Questions: []dnsmessage.Question{{Name: name, Type: recordType, Class: dnsmessage.ClassINET}},
+		}
+		packed, err := query.Pack()
+		if err != nil {
+			return 0, err
+		}
+
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "udp", address(server, 53))
+		if err != nil {
+			return 0, probeError(err)
+		}
+		defer func() { _ = conn.Close() }()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		if _, err := conn.Write(packed); err != nil {
+			return 0, probeError(err)
+		}
+
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, probeError(err)
+		}
+		var response dnsmessage.Message
+		if err := response.Unpack(buf[:n]); err != nil {
+			return 0, fmt.Errorf("invalid DNS response: %w", err)
+		}
+		switch {
+		case response.ID != id:
+			return 0, errors.New("DNS response ID does not match the query")
+		case response.RCode != dnsmessage.RCodeSuccess:
+			return 0, fmt.Errorf("DNS response code %s", response.RCode)
+		case test.AnswersRequired && len(response.Answers) == 0:
+			return 0, errors.New("DNS response contains no answers")
+		}
+		return 0, nil
+	}, nil
+}
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// probeError distinguishes timeouts, which GTM penalizes with TimeoutPenalty, from other errors
+func probeError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timeout: %w", err)
+	}
+	return fmt.Errorf("error: %w", err)
+}
@@ -0,0 +1,270 @@
+package gtm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDryRunLivenessTest_HTTP(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if r.Header.Get("X-Check") == "yes" {
+				assert.Equal(t, "origin.example.com", r.Host)
+			} else {
+				assert.Equal(t, "1.2.3.4", r.Host)
+			}
+			_, _ = w.Write([]byte("status: OK"))
+		case "/post":
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPost, r.Method)
+			_, _ = w.Write(body)
+		case "/redirect":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+	httpsServer := httptest.NewTLSServer(handler)
+	defer httpsServer.Close()
+
+	opts := LivenessDryRunOptions{Addresses: map[string]string{
+		"1.2.3.4": httpServer.Listener.Addr().String(),
+		"1.2.3.5": httpsServer.Listener.Addr().String(),
+	}}
+	targets := func(server string) []TrafficTarget {
+		return []TrafficTarget{
+			{DatacenterID: 3131, Enabled: true, Servers: []string{server}},
+			{DatacenterID: 3132, Enabled: false, Servers: []string{"1.2.3.6"}},
+		}
+	}
+
+	tests := map[string]struct {
+		test           LivenessTest
+		server         string
+		expectedUp     bool
+		expectedStatus int
+		expectedReason string
+	}{
+		"HTTP up": {
+			test: LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/health", ResponseString: "OK",
+				HTTPHeaders: []HTTPHeader{{Name: "Host", Value: "origin.example.com"}, {Name: "X-Check", Value: "yes"}}},
+			server:         "1.2.3.4",
+			expectedUp:     true,
+			expectedStatus: http.StatusOK,
+		},
+		"HTTPS up, certificate not verified": {
+			test: LivenessTest{Name: "lt", TestObjectProtocol: "HTTPS", TestObject: "post",
+				HTTPMethod: ptr.To("post"), HTTPRequestBody: ptr.To("ping"), ResponseString: "ping"},
+			server:         "1.2.3.5",
+			expectedUp:     true,
+			expectedStatus: http.StatusOK,
+		},
+		"HTTPS down, certificate verified": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTPS", TestObject: "/health", PeerCertificateVerification: true},
+			server:         "1.2.3.5",
+			expectedReason: "error: ",
+		},
+		"redirect is not followed": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/redirect"},
+			server:         "1.2.3.4",
+			expectedUp:     true,
+			expectedStatus: http.StatusFound,
+		},
+		"3xx treated as error": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/redirect", HTTPError3xx: true},
+			server:         "1.2.3.4",
+			expectedStatus: http.StatusFound,
+			expectedReason: "HTTP status 302 is treated as an error",
+		},
+		"4xx ignored": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/missing", HTTPError5xx: true},
+			server:         "1.2.3.4",
+			expectedUp:     true,
+			expectedStatus: http.StatusNotFound,
+		},
+		"response string missing": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/health", ResponseString: "FAIL"},
+			server:         "1.2.3.4",
+			expectedStatus: http.StatusOK,
+			expectedReason: `response does not contain "FAIL"`,
+		},
+		"timeout": {
+			test:           LivenessTest{Name: "lt", TestObjectProtocol: "HTTP", TestObject: "/slow", TestTimeout: ptr.To(float32(0.05))},
+			server:         "1.2.3.4",
+			expectedReason: "timeout: ",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			results, err := DryRunLivenessTest(context.Background(), test.test, targets(test.server), opts)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			result := results[0]
+			assert.Equal(t, "lt", result.TestName)
+			assert.Equal(t, 3131, result.DatacenterID)
+			assert.Equal(t, test.server, result.Server)
+			assert.Equal(t, test.expectedUp, result.Up, result.Reason)
+			assert.Equal(t, test.expectedStatus, result.StatusCode)
+			assert.True(t, strings.HasPrefix(result.Reason, test.expectedReason), "want: %s; got: %s", test.expectedReason, result.Reason)
+		})
+	}
+}
+
+func TestDryRunLivenessTest_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				_, _ = conn.Write([]byte("echo " + line))
+			}()
+		}
+	}()
+
+	opts := LivenessDryRunOptions{Addresses: map[string]string{"1.2.3.4": listener.Addr().String()}}
+	targets := []TrafficTarget{{DatacenterID: 3131, Enabled: true, Servers: []string{"1.2.3.4"}}}
+
+	results, err := DryRunLivenessTest(context.Background(), LivenessTest{
+		TestObjectProtocol: "TCP", TestObjectPort: ptr.To(7), RequestString: "hello\n", ResponseString: "echo hello",
+	}, targets, opts)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Up, results[0].Reason)
+
+	results, err = DryRunLivenessTest(context.Background(), LivenessTest{
+		TestObjectProtocol: "TCP", TestObjectPort: ptr.To(7), RequestString: "hello\n", ResponseString: "bye",
+	}, targets, opts)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Up)
+	assert.Equal(t, `response does not contain "bye"`, results[0].Reason)
+}
+
+func TestDryRunLivenessTest_DNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionDesired: query.RecursionDesired},
+				Questions: query.Questions,
+			}
+			if query.Questions[0].Name.String() == "www.example.com." && query.RecursionDesired {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{1, 2, 3, 4}},
+				}}
+			} else if query.Questions[0].Name.String() != "www.example.com." {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+
+	opts := LivenessDryRunOptions{Addresses: map[string]string{"1.2.3.4": conn.LocalAddr().String()}}
+	targets := []TrafficTarget{{DatacenterID: 3131, Enabled: true, Servers: []string{"1.2.3.4"}}}
+
+	tests := map[string]struct {
+		test           LivenessTest
+		expectedReason string
+	}{
+		"answer returned": {
+			test: LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com", ResourceType: "A",
+				RecursionRequested: true, AnswersRequired: true},
+		},
+		"no answers required": {
+			test: LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com"},
+		},
+		"no answers": {
+			test:           LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com", AnswersRequired: true},
+			expectedReason: "DNS response contains no answers",
+		},
+		"name error": {
+			test:           LivenessTest{TestObjectProtocol: "DNS", TestObject: "missing.example.com"},
+			expectedReason: "DNS response code RCodeNameError",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			results, err := DryRunLivenessTest(context.Background(), test.test, targets, opts)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, test.expectedReason == "", results[0].Up)
+			assert.Equal(t, test.expectedReason, results[0].Reason)
+		})
+	}
+}
+
+func TestDryRunLivenessTests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	property := &Property{
+		TrafficTargets: []TrafficTarget{{DatacenterID: 3131, Enabled: true, Servers: []string{"1.2.3.4", "1.2.3.5"}}},
+		LivenessTests: []LivenessTest{
+			{Name: "http", TestObjectProtocol: "HTTP", TestObject: "/", HTTPError5xx: true},
+			{Name: "disabled", TestObjectProtocol: "HTTP", Disabled: true},
+		},
+	}
+	opts := LivenessDryRunOptions{Addresses: map[string]string{
+		"1.2.3.4": server.Listener.Addr().String(),
+		"1.2.3.5": server.Listener.Addr().String(),
+	}}
+
+	results, err := DryRunLivenessTests(context.Background(), property, opts)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, "http", result.TestName)
+		assert.False(t, result.Up)
+		assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	}
+
+	property.LivenessTests = append(property.LivenessTests, LivenessTest{Name: "ftp", TestObjectProtocol: "FTP"})
+	_, err = DryRunLivenessTests(context.Background(), property, opts)
+	assert.True(t, errors.Is(err, ErrLivenessDryRun), "want: %s; got: %s", ErrLivenessDryRun, err)
+	assert.Contains(t, err.Error(), `unsupported protocol "FTP"`)
+}
@@ -0,0 +1,354 @@
+package gtm
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MapEntry is a single assignment of a CIDR block, AS number or country to a datacenter,
+// as kept in CSV and JSON files of CIDR, AS and geographic maps
+type MapEntry struct {
+	DatacenterID int    `json:"datacenterId"`
+	Nickname     string `json:"nickname,omitempty"`
+	// Value is a CIDR block or an IP address, an AS number or a country code, depending on the map type
+	Value string `json:"value"`
+}
+
+var (
+	// ErrMapImport is returned when map entries can't be read or converted to assignments
+	ErrMapImport = errors.New("map import")
+	// ErrMapConflict is returned when the same block, AS number or country is assigned to more than one datacenter
+	ErrMapConflict = errors.New("conflicting map entries")
+)
+
+// mapEntriesCSVHeader is the header of CSV files written by WriteMapEntriesCSV
+var mapEntriesCSVHeader = []string{"datacenterId", "nickname", "value"}
+
+// ReadMapEntriesCSV reads map entries from CSV with 'datacenterId,nickname,value' columns.
+// The header row is optional, empty lines and lines starting with '#' are skipped.
+func ReadMapEntriesCSV(r io.Reader) ([]MapEntry, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = len(mapEntriesCSVHeader)
+	reader.TrimLeadingSpace = true
+
+	var entries []MapEntry
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMapImport, err)
+		}
+		if line == 1 && strings.EqualFold(record[0], mapEntriesCSVHeader[0]) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			row, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%w: line %d: invalid datacenter ID %q", ErrMapImport, row, record[0])
+		}
+		entries = append(entries, MapEntry{
+			DatacenterID: id,
+			Nickname:     strings.TrimSpace(record[1]),
+			Value:        strings.TrimSpace(record[2]),
+		})
+	}
+}
+
+// ReadMapEntriesJSON reads map entries from a JSON array of objects with 'datacenterId', 'nickname' and 'value' fields
+func ReadMapEntriesJSON(r io.Reader) ([]MapEntry, error) {
+	var entries []MapEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMapImport, err)
+	}
+	return entries, nil
+}
+
+// WriteMapEntriesCSV writes map entries as CSV with a 'datacenterId,nickname,value' header
+func WriteMapEntriesCSV(w io.Writer, entries []MapEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(mapEntriesCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := writer.Write([]string{strconv.Itoa(e.DatacenterID), e.Nickname, e.Value}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CIDRAssignmentsFromEntries builds CIDR map assignments from entries. Blocks of each datacenter are aggregated,
+// i.e. blocks contained in other blocks are removed and adjacent blocks are merged.
+// ErrMapConflict is returned if blocks assigned to different datacenters overlap.
+func CIDRAssignmentsFromEntries(entries []MapEntry) ([]CIDRAssignment, error) {
+	datacenters, values, err := groupMapEntries(entries, parseCIDRBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []error
+	aggregated := make(map[int][]netip.Prefix, len(values))
+	// assignment of the blocks holds the datacenter ID, as assignments are built afterwards
+	var all []cidrBlock
+	for _, dc := range datacenters {
+		prefixes := make([]netip.Prefix, 0, len(values[dc.DatacenterID]))
+		for _, v := range values[dc.DatacenterID] {
+			prefixes = append(prefixes, netip.MustParsePrefix(v))
+		}
+		aggregated[dc.DatacenterID] = aggregatePrefixes(prefixes)
+		for _, p := range aggregated[dc.DatacenterID] {
+			if overlap := overlappingBlock(p, all); overlap != nil {
+				conflicts = append(conflicts, fmt.Errorf("block %s of datacenter %d overlaps with block %s of datacenter %d",
+					p, dc.DatacenterID, overlap.prefix, overlap.assignment))
+				continue
+			}
+			all = append(all, cidrBlock{prefix: p, assignment: dc.DatacenterID})
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrMapConflict, errors.Join(conflicts...))
+	}
+
+	assignments := make([]CIDRAssignment, 0, len(datacenters))
+	for _, dc := range datacenters {
+		a := CIDRAssignment{DatacenterBase: dc}
+		for _, p := range aggregated[dc.DatacenterID] {
+			a.Blocks = append(a.Blocks, p.String())
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// ASAssignmentsFromEntries builds AS map assignments from entries.
+// ErrMapConflict is returned if an AS number is assigned to different datacenters.
+func ASAssignmentsFromEntries(entries []MapEntry) ([]ASAssignment, error) {
+	datacenters, values, err := groupMapEntries(entries, parseASNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := mapConflicts(datacenters, values); err != nil {
+		return nil, err
+	}
+
+	assignments := make([]ASAssignment, 0, len(datacenters))
+	for _, dc := range datacenters {
+		a := ASAssignment{DatacenterBase: dc}
+		for _, v := range values[dc.DatacenterID] {
+			asn, _ := strconv.ParseInt(v, 10, 64)
+			a.ASNumbers = append(a.ASNumbers, asn)
+		}
+		sort.Slice(a.ASNumbers, func(i, j int) bool { return a.ASNumbers[i] < a.ASNumbers[j] })
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// GeoAssignmentsFromEntries builds geographic map assignments from entries. Country codes are converted to upper case.
+// ErrMapConflict is returned if a country is assigned to different datacenters.
+func GeoAssignmentsFromEntries(entries []MapEntry) ([]GeoAssignment, error) {
+	datacenters, values, err := groupMapEntries(entries, parseCountry)
+	if err != nil {
+		return nil, err
+	}
+	if err := mapConflicts(datacenters, values); err != nil {
+		return nil, err
+	}
+
+	assignments := make([]GeoAssignment, 0, len(datacenters))
+	for _, dc := range datacenters {
+		a := GeoAssignment{DatacenterBase: dc, Countries: values[dc.DatacenterID]}
+		sort.Strings(a.Countries)
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// Entries returns assignments of the CIDR map as map entries, one per block
+func (m *CIDRMap) Entries() []MapEntry {
+	var entries []MapEntry
+	for _, a := range m.Assignments {
+		for _, b := range a.Blocks {
+			entries = append(entries, MapEntry{DatacenterID: a.DatacenterID, Nickname: a.Nickname, Value: b})
+		}
+	}
+	return entries
+}
+
+// Entries returns assignments of the AS map as map entries, one per AS number
+func (m *ASMap) Entries() []MapEntry {
+	var entries []MapEntry
+	for _, a := range m.Assignments {
+		for _, asn := range a.ASNumbers {
+			entries = append(entries, MapEntry{DatacenterID: a.DatacenterID, Nickname: a.Nickname, Value: strconv.FormatInt(asn, 10)})
+		}
+	}
+	return entries
+}
+
+// Entries returns assignments of the geographic map as map entries, one per country
+func (m *GeoMap) Entries() []MapEntry {
+	var entries []MapEntry
+	for _, a := range m.Assignments {
+		for _, c := range a.Countries {
+			entries = append(entries, MapEntry{DatacenterID: a.DatacenterID, Nickname: a.Nickname, Value: c})
+		}
+	}
+	return entries
+}
+
+// AggregateCIDRBlocks removes blocks contained in other blocks and merges adjacent blocks into their common supernet.
+// Single IP addresses are accepted as host blocks. Blocks are returned sorted, IPv4 before IPv6.
+func AggregateCIDRBlocks(blocks []string) ([]string, error) {
+	prefixes := make([]netip.Prefix, 0, len(blocks))
+	for _, b := range blocks {
+		normalized, err := parseCIDRBlock(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMapImport, err)
+		}
+		prefixes = append(prefixes, netip.MustParsePrefix(normalized))
+	}
+	aggregated := aggregatePrefixes(prefixes)
+	result := make([]string, 0, len(aggregated))
+	for _, p := range aggregated {
+		result = append(result, p.String())
+	}
+	return result, nil
+}
+
+// aggregatePrefixes returns sorted, non-overlapping prefixes covering the same addresses as the given masked prefixes
+func aggregatePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := append([]netip.Prefix(nil), prefixes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	var result []netip.Prefix
+	for _, p := range sorted {
+		if len(result) > 0 && result[len(result)-1].Overlaps(p) {
+			// sorted by address and length, so the previous prefix contains this one
+			continue
+		}
+		result = append(result, p)
+		for len(result) > 1 {
+			last, prev := result[len(result)-1], result[len(result)-2]
+			parent, ok := siblingsParent(prev, last)
+			if !ok {
+				break
+			}
+			result = append(result[:len(result)-2], parent)
+		}
+	}
+	return result
+}
+
+// siblingsParent returns the common supernet of two prefixes if they are both halves of it
+func siblingsParent(a, b netip.Prefix) (netip.Prefix, bool) {
+	if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().BitLen() != b.Addr().BitLen() || a == b {
+		return netip.Prefix{}, false
+	}
+	parentA, _ := a.Addr().Prefix(a.Bits() - 1)
+	parentB, _ := b.Addr().Prefix(b.Bits() - 1)
+	return parentA, parentA == parentB
+}
+
+// groupMapEntries groups normalized entry values by datacenter. Datacenters are returned in order of their IDs,
+// with the first non-empty nickname. Duplicate values of a datacenter are dropped.
+func groupMapEntries(entries []MapEntry, normalize func(string) (string, error)) ([]DatacenterBase, map[int][]string, error) {
+	datacenters := make(map[int]*DatacenterBase)
+	values := make(map[int][]string)
+	seen := make(map[int]map[string]bool)
+	var errs []error
+	for i, e := range entries {
+		value, err := normalize(e.Value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: %w", i, err))
+			continue
+		}
+		dc, ok := datacenters[e.DatacenterID]
+		if !ok {
+			dc = &DatacenterBase{DatacenterID: e.DatacenterID}
+			datacenters[e.DatacenterID] = dc
+			seen[e.DatacenterID] = make(map[string]bool)
+		}
+		if dc.Nickname == "" {
+			dc.Nickname = e.Nickname
+		}
+		if seen[e.DatacenterID][value] {
+			continue
+		}
+		seen[e.DatacenterID][value] = true
+		values[e.DatacenterID] = append(values[e.DatacenterID], value)
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("%w: %w", ErrMapImport, errors.Join(errs...))
+	}
+
+	result := make([]DatacenterBase, 0, len(datacenters))
+	for _, dc := range datacenters {
+		result = append(result, *dc)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DatacenterID < result[j].DatacenterID })
+	return result, values, nil
+}
+
+// mapConflicts returns ErrMapConflict listing values assigned to more than one datacenter
+func mapConflicts(datacenters []DatacenterBase, values map[int][]string) error {
+	owners := make(map[string]int)
+	var conflicts []error
+	for _, dc := range datacenters {
+		for _, v := range values[dc.DatacenterID] {
+			if owner, ok := owners[v]; ok {
+				conflicts = append(conflicts, fmt.Errorf("%s is assigned to datacenters %d and %d", v, owner, dc.DatacenterID))
+				continue
+			}
+			owners[v] = dc.DatacenterID
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %w", ErrMapConflict, errors.Join(conflicts...))
+	}
+	return nil
+}
+
+// parseCIDRBlock parses a CIDR block or an IP address and returns the masked block
+func parseCIDRBlock(value string) (string, error) {
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		addr, addrErr := netip.ParseAddr(value)
+		if addrErr != nil {
+			return "", fmt.Errorf("invalid block %q", value)
+		}
+		prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+	}
+	return prefix.Masked().String(), nil
+}
+
+func parseASNumber(value string) (string, error) {
+	asn, err := strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 64)
+	if err != nil || asn < 1 || asn > 4294967295 {
+		return "", fmt.Errorf("invalid AS number %q", value)
+	}
+	return strconv.FormatInt(asn, 10), nil
+}
+
+func parseCountry(value string) (string, error) {
+	if value == "" {
+		return "", errors.New("empty country code")
+	}
+	return strings.ToUpper(value), nil
+}
@@ -0,0 +1,147 @@
+package gtm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateCIDRBlocks(t *testing.T) {
+	tests := map[string]struct {
+		blocks    []string
+		expected  []string
+		withError bool
+	}{
+		"adjacent blocks merged": {
+			blocks:   []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/23"},
+			expected: []string{"10.0.0.0/22"},
+		},
+		"contained blocks removed": {
+			blocks:   []string{"10.1.2.0/24", "10.0.0.0/8", "10.0.0.1"},
+			expected: []string{"10.0.0.0/8"},
+		},
+		"non-sibling blocks kept": {
+			blocks:   []string{"10.0.1.0/24", "10.0.2.0/24", "192.0.2.7"},
+			expected: []string{"10.0.1.0/24", "10.0.2.0/24", "192.0.2.7/32"},
+		},
+		"unmasked blocks and IPv6": {
+			blocks:   []string{"2001:db8::/33", "2001:db8:8000::1/33", "10.0.0.5/24"},
+			expected: []string{"10.0.0.0/24", "2001:db8::/32"},
+		},
+		"invalid block": {
+			blocks:    []string{"10.0.0.0/33"},
+			withError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := AggregateCIDRBlocks(test.blocks)
+			if test.withError {
+				assert.True(t, errors.Is(err, ErrMapImport), "want: %s; got: %s", ErrMapImport, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestCIDRAssignmentsFromEntries(t *testing.T) {
+	entries, err := ReadMapEntriesCSV(strings.NewReader(`datacenterId,nickname,value
+# office networks
+3132,,192.0.2.0/25
+3131,dc1,10.0.0.0/24
+3131,,10.0.1.0/24
+3132,dc2,192.0.2.128/25
+3131,dc1,10.0.0.0/24
+`))
+	require.NoError(t, err)
+
+	assignments, err := CIDRAssignmentsFromEntries(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []CIDRAssignment{
+		{DatacenterBase: DatacenterBase{DatacenterID: 3131, Nickname: "dc1"}, Blocks: []string{"10.0.0.0/23"}},
+		{DatacenterBase: DatacenterBase{DatacenterID: 3132, Nickname: "dc2"}, Blocks: []string{"192.0.2.0/24"}},
+	}, assignments)
+
+	_, err = CIDRAssignmentsFromEntries([]MapEntry{
+		{DatacenterID: 3131, Value: "10.0.0.0/8"},
+		{DatacenterID: 3132, Value: "10.1.0.0/16"},
+	})
+	assert.True(t, errors.Is(err, ErrMapConflict), "want: %s; got: %s", ErrMapConflict, err)
+	assert.Contains(t, err.Error(), "block 10.1.0.0/16 of datacenter 3132 overlaps with block 10.0.0.0/8 of datacenter 3131")
+
+	_, err = CIDRAssignmentsFromEntries([]MapEntry{{DatacenterID: 3131, Value: "10.0.0"}})
+	assert.True(t, errors.Is(err, ErrMapImport), "want: %s; got: %s", ErrMapImport, err)
+}
+
+func TestASAndGeoAssignmentsFromEntries(t *testing.T) {
+	entries, err := ReadMapEntriesJSON(strings.NewReader(`[
+		{"datacenterId": 3132, "value": "AS64512"},
+		{"datacenterId": 3131, "nickname": "dc1", "value": "64513"},
+		{"datacenterId": 3131, "value": "12"}
+	]`))
+	require.NoError(t, err)
+
+	asAssignments, err := ASAssignmentsFromEntries(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []ASAssignment{
+		{DatacenterBase: DatacenterBase{DatacenterID: 3131, Nickname: "dc1"}, ASNumbers: []int64{12, 64513}},
+		{DatacenterBase: DatacenterBase{DatacenterID: 3132}, ASNumbers: []int64{64512}},
+	}, asAssignments)
+
+	_, err = ASAssignmentsFromEntries(append(entries, MapEntry{DatacenterID: 3133, Value: "64512"}))
+	assert.True(t, errors.Is(err, ErrMapConflict), "want: %s; got: %s", ErrMapConflict, err)
+	assert.Contains(t, err.Error(), "64512 is assigned to datacenters 3132 and 3133")
+
+	geoAssignments, err := GeoAssignmentsFromEntries([]MapEntry{
+		{DatacenterID: 3131, Value: "pl"}, {DatacenterID: 3131, Value: "DE"}, {DatacenterID: 3131, Value: "PL"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Countries: []string{"DE", "PL"}}}, geoAssignments)
+
+	_, err = GeoAssignmentsFromEntries([]MapEntry{{DatacenterID: 3131, Value: "PL"}, {DatacenterID: 3132, Value: "pl"}})
+	assert.True(t, errors.Is(err, ErrMapConflict), "want: %s; got: %s", ErrMapConflict, err)
+}
+
+func TestReadMapEntriesCSV_Errors(t *testing.T) {
+	_, err := ReadMapEntriesCSV(strings.NewReader("3131,dc1,10.0.0.0/8\nabc,dc2,10.1.0.0/16\n"))
+	assert.True(t, errors.Is(err, ErrMapImport), "want: %s; got: %s", ErrMapImport, err)
+	assert.Contains(t, err.Error(), `line 2: invalid datacenter ID "abc"`)
+
+	_, err = ReadMapEntriesCSV(strings.NewReader("3131,10.0.0.0/8\n"))
+	assert.True(t, errors.Is(err, ErrMapImport), "want: %s; got: %s", ErrMapImport, err)
+}
+
+func TestMapEntries_CSVRoundTrip(t *testing.T) {
+	m := CIDRMap{
+		Name: "cidr",
+		Assignments: []CIDRAssignment{
+			{DatacenterBase: DatacenterBase{DatacenterID: 3131, Nickname: "dc, one"}, Blocks: []string{"10.0.0.0/8", "192.0.2.0/24"}},
+			{DatacenterBase: DatacenterBase{DatacenterID: 3132, Nickname: "dc2"}, Blocks: []string{"2001:db8::/32"}},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteMapEntriesCSV(&buf, m.Entries()))
+	assert.Equal(t, `datacenterId,nickname,value
+3131,"dc, one",10.0.0.0/8
+3131,"dc, one",192.0.2.0/24
+3132,dc2,2001:db8::/32
+`, buf.String())
+
+	entries, err := ReadMapEntriesCSV(&buf)
+	require.NoError(t, err)
+	assignments, err := CIDRAssignmentsFromEntries(entries)
+	require.NoError(t, err)
+	assert.Equal(t, m.Assignments, assignments)
+
+	asMap := ASMap{Assignments: []ASAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, ASNumbers: []int64{1, 2}}}}
+	assert.Equal(t, []MapEntry{{DatacenterID: 3131, Value: "1"}, {DatacenterID: 3131, Value: "2"}}, asMap.Entries())
+	geoMap := GeoMap{Assignments: []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Countries: []string{"PL"}}}}
+	assert.Equal(t, []MapEntry{{DatacenterID: 3131, Value: "PL"}}, geoMap.Entries())
+}
@@ -14,16 +14,6 @@ type Mock struct {
 
 var _ GTM = &Mock{}
 
-func (p *Mock) NullFieldMap(ctx context.Context, domain *Domain) (*NullFieldMapStruct, error) {
-	args := p.Called(ctx, domain)
-
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-
-	return args.Get(0).(*NullFieldMapStruct), args.Error(1)
-}
-
 func (p *Mock) GetDomainStatus(ctx context.Context, req GetDomainStatusRequest) (*GetDomainStatusResponse, error) {
 	args := p.Called(ctx, req)
 
@@ -14,59 +14,63 @@ import (
 type (
 	// TrafficTarget struct contains information about where to direct data center traffic
 	TrafficTarget struct {
-		DatacenterID int      `json:"datacenterId"`
-		Enabled      bool     `json:"enabled"`
-		Weight       float64  `json:"weight,omitempty"`
-		Servers      []string `json:"servers,omitempty"`
-		Name         string   `json:"name,omitempty"`
-		HandoutCName string   `json:"handoutCName,omitempty"`
-		Precedence   *int     `json:"precedence,omitempty"`
+		DatacenterID  int           `json:"datacenterId"`
+		Enabled       bool          `json:"enabled"`
+		Weight        *float64      `json:"weight,omitempty"`
+		Servers       []string      `json:"servers,omitempty"`
+		Name          string        `json:"name,omitempty"`
+		HandoutCName  string        `json:"handoutCName,omitempty"`
+		Precedence    *int          `json:"precedence,omitempty"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// HTTPHeader struct contains HTTP headers to send if the testObjectProtocol is http or https
 	HTTPHeader struct {
-		Name  string `json:"name"`
-		Value string `json:"value"`
+		Name          string        `json:"name"`
+		Value         string        `json:"value"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// LivenessTest contains configuration of liveness tests to determine whether your servers respond to requests
 	LivenessTest struct {
-		Name                          string       `json:"name"`
-		ErrorPenalty                  float64      `json:"errorPenalty,omitempty"`
-		PeerCertificateVerification   bool         `json:"peerCertificateVerification"`
-		TestInterval                  int          `json:"testInterval,omitempty"`
-		TestObject                    string       `json:"testObject,omitempty"`
-		Links                         []Link       `json:"links,omitempty"`
-		RequestString                 string       `json:"requestString,omitempty"`
-		ResponseString                string       `json:"responseString,omitempty"`
-		HTTPError3xx                  bool         `json:"httpError3xx"`
-		HTTPError4xx                  bool         `json:"httpError4xx"`
-		HTTPError5xx                  bool         `json:"httpError5xx"`
-		HTTPMethod                    *string      `json:"httpMethod"`
-		HTTPRequestBody               *string      `json:"httpRequestBody"`
-		Disabled                      bool         `json:"disabled"`
-		TestObjectProtocol            string       `json:"testObjectProtocol,omitempty"`
-		TestObjectPassword            string       `json:"testObjectPassword,omitempty"`
-		TestObjectPort                int          `json:"testObjectPort,omitempty"`
-		SSLClientPrivateKey           string       `json:"sslClientPrivateKey,omitempty"`
-		SSLClientCertificate          string       `json:"sslClientCertificate,omitempty"`
-		Pre2023SecurityPosture        bool         `json:"pre2023SecurityPosture"`
-		DisableNonstandardPortWarning bool         `json:"disableNonstandardPortWarning"`
-		HTTPHeaders                   []HTTPHeader `json:"httpHeaders,omitempty"`
-		TestObjectUsername            string       `json:"testObjectUsername,omitempty"`
-		TestTimeout                   float32      `json:"testTimeout,omitempty"`
-		TimeoutPenalty                float64      `json:"timeoutPenalty,omitempty"`
-		AnswersRequired               bool         `json:"answersRequired"`
-		ResourceType                  string       `json:"resourceType,omitempty"`
-		RecursionRequested            bool         `json:"recursionRequested"`
-		AlternateCACertificates       []string     `json:"alternateCACertificates"`
+		Name                          string        `json:"name"`
+		ErrorPenalty                  *float64      `json:"errorPenalty,omitempty"`
+		PeerCertificateVerification   bool          `json:"peerCertificateVerification"`
+		TestInterval                  *int          `json:"testInterval,omitempty"`
+		TestObject                    string        `json:"testObject,omitempty"`
+		Links                         []Link        `json:"links,omitempty"`
+		RequestString                 string        `json:"requestString,omitempty"`
+		ResponseString                string        `json:"responseString,omitempty"`
+		HTTPError3xx                  bool          `json:"httpError3xx"`
+		HTTPError4xx                  bool          `json:"httpError4xx"`
+		HTTPError5xx                  bool          `json:"httpError5xx"`
+		HTTPMethod                    *string       `json:"httpMethod"`
+		HTTPRequestBody               *string       `json:"httpRequestBody"`
+		Disabled                      bool          `json:"disabled"`
+		TestObjectProtocol            string        `json:"testObjectProtocol,omitempty"`
+		TestObjectPassword            string        `json:"testObjectPassword,omitempty"`
+		TestObjectPort                *int          `json:"testObjectPort,omitempty"`
+		SSLClientPrivateKey           string        `json:"sslClientPrivateKey,omitempty"`
+		SSLClientCertificate          string        `json:"sslClientCertificate,omitempty"`
+		Pre2023SecurityPosture        bool          `json:"pre2023SecurityPosture"`
+		DisableNonstandardPortWarning bool          `json:"disableNonstandardPortWarning"`
+		HTTPHeaders                   []HTTPHeader  `json:"httpHeaders,omitempty"`
+		TestObjectUsername            string        `json:"testObjectUsername,omitempty"`
+		TestTimeout                   *float32      `json:"testTimeout,omitempty"`
+		TimeoutPenalty                *float64      `json:"timeoutPenalty,omitempty"`
+		AnswersRequired               bool          `json:"answersRequired"`
+		ResourceType                  string        `json:"resourceType,omitempty"`
+		RecursionRequested            bool          `json:"recursionRequested"`
+		AlternateCACertificates       []string      `json:"alternateCACertificates"`
+		UnknownFields                 UnknownFields `json:"-"`
 	}
 
 	// StaticRRSet contains static recordset
 	StaticRRSet struct {
-		Type  string   `json:"type"`
-		TTL   int      `json:"ttl"`
-		Rdata []string `json:"rdata"`
+		Type          string        `json:"type"`
+		TTL           int           `json:"ttl"`
+		Rdata         []string      `json:"rdata"`
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// Property represents a GTM property
@@ -75,36 +79,37 @@ type (
 		Type                      string          `json:"type"`
 		IPv6                      bool            `json:"ipv6"`
 		ScoreAggregationType      string          `json:"scoreAggregationType"`
-		StickinessBonusPercentage int             `json:"stickinessBonusPercentage,omitempty"`
-		StickinessBonusConstant   int             `json:"stickinessBonusConstant,omitempty"`
-		HealthThreshold           float64         `json:"healthThreshold,omitempty"`
+		StickinessBonusPercentage *int            `json:"stickinessBonusPercentage,omitempty"`
+		StickinessBonusConstant   *int            `json:"stickinessBonusConstant,omitempty"`
+		HealthThreshold           *float64        `json:"healthThreshold,omitempty"`
 		UseComputedTargets        bool            `json:"useComputedTargets"`
 		BackupIP                  string          `json:"backupIp,omitempty"`
 		BalanceByDownloadScore    bool            `json:"balanceByDownloadScore"`
-		StaticTTL                 int             `json:"staticTTL,omitempty"`
+		StaticTTL                 *int            `json:"staticTTL,omitempty"`
 		StaticRRSets              []StaticRRSet   `json:"staticRRSets,omitempty"`
 		LastModified              string          `json:"lastModified"`
-		UnreachableThreshold      float64         `json:"unreachableThreshold,omitempty"`
-		MinLiveFraction           float64         `json:"minLiveFraction,omitempty"`
-		HealthMultiplier          float64         `json:"healthMultiplier,omitempty"`
-		DynamicTTL                int             `json:"dynamicTTL,omitempty"`
-		MaxUnreachablePenalty     int             `json:"maxUnreachablePenalty,omitempty"`
+		UnreachableThreshold      *float64        `json:"unreachableThreshold,omitempty"`
+		MinLiveFraction           *float64        `json:"minLiveFraction,omitempty"`
+		HealthMultiplier          *float64        `json:"healthMultiplier,omitempty"`
+		DynamicTTL                *int            `json:"dynamicTTL,omitempty"`
+		MaxUnreachablePenalty     *int            `json:"maxUnreachablePenalty,omitempty"`
 		MapName                   string          `json:"mapName,omitempty"`
 		HandoutLimit              int             `json:"handoutLimit"`
 		HandoutMode               string          `json:"handoutMode"`
-		FailoverDelay             int             `json:"failoverDelay,omitempty"`
+		FailoverDelay             *int            `json:"failoverDelay,omitempty"`
 		BackupCName               string          `json:"backupCName,omitempty"`
-		FailbackDelay             int             `json:"failbackDelay,omitempty"`
-		LoadImbalancePercentage   float64         `json:"loadImbalancePercentage,omitempty"`
-		HealthMax                 float64         `json:"healthMax,omitempty"`
+		FailbackDelay             *int            `json:"failbackDelay,omitempty"`
+		LoadImbalancePercentage   *float64        `json:"loadImbalancePercentage,omitempty"`
+		HealthMax                 *float64        `json:"healthMax,omitempty"`
 		GhostDemandReporting      bool            `json:"ghostDemandReporting"`
 		Comments                  string          `json:"comments,omitempty"`
 		CName                     string          `json:"cname,omitempty"`
-		WeightedHashBitsForIPv4   int             `json:"weightedHashBitsForIPv4,omitempty"`
-		WeightedHashBitsForIPv6   int             `json:"weightedHashBitsForIPv6,omitempty"`
+		WeightedHashBitsForIPv4   *int            `json:"weightedHashBitsForIPv4,omitempty"`
+		WeightedHashBitsForIPv6   *int            `json:"weightedHashBitsForIPv6,omitempty"`
 		TrafficTargets            []TrafficTarget `json:"trafficTargets,omitempty"`
 		Links                     []Link          `json:"links,omitempty"`
 		LivenessTests             []LivenessTest  `json:"livenessTests,omitempty"`
+		UnknownFields             UnknownFields   `json:"-"`
 	}
 
 	// PropertyRequest contains request parameters
@@ -398,3 +403,68 @@ func (g *gtm) DeleteProperty(ctx context.Context, params DeletePropertyRequest)
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes TrafficTarget, keeping fields unknown to this version of the library in UnknownFields
+func (t *TrafficTarget) UnmarshalJSON(data []byte) error {
+	type trafficTarget TrafficTarget
+	return unmarshalKeepingUnknown(data, (*trafficTarget)(t), &t.UnknownFields)
+}
+
+// MarshalJSON encodes TrafficTarget together with its UnknownFields
+func (t TrafficTarget) MarshalJSON() ([]byte, error) {
+	type trafficTarget TrafficTarget
+	return marshalKeepingUnknown(trafficTarget(t), t.UnknownFields)
+}
+
+// UnmarshalJSON decodes LivenessTest, keeping fields unknown to this version of the library in UnknownFields
+func (l *LivenessTest) UnmarshalJSON(data []byte) error {
+	type livenessTest LivenessTest
+	return unmarshalKeepingUnknown(data, (*livenessTest)(l), &l.UnknownFields)
+}
+
+// MarshalJSON encodes LivenessTest together with its UnknownFields
+func (l LivenessTest) MarshalJSON() ([]byte, error) {
+	type livenessTest LivenessTest
+	return marshalKeepingUnknown(livenessTest(l), l.UnknownFields)
+}
+
+// UnmarshalJSON decodes Property, keeping fields unknown to this version of the library in UnknownFields
+func (p *Property) UnmarshalJSON(data []byte) error {
+	type property Property
+	return unmarshalKeepingUnknown(data, (*property)(p), &p.UnknownFields)
+}
+
+// MarshalJSON encodes Property together with its UnknownFields
+func (p Property) MarshalJSON() ([]byte, error) {
+	type property Property
+	return marshalKeepingUnknown(property(p), p.UnknownFields)
+}
+
+// UnmarshalJSON decodes GetPropertyResponse the same way as Property
+func (r *GetPropertyResponse) UnmarshalJSON(data []byte) error {
+	return (*Property)(r).UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes HTTPHeader, keeping fields unknown to this version of the library in UnknownFields
+func (h *HTTPHeader) UnmarshalJSON(data []byte) error {
+	type httpHeader HTTPHeader
+	return unmarshalKeepingUnknown(data, (*httpHeader)(h), &h.UnknownFields)
+}
+
+// MarshalJSON encodes HTTPHeader together with its UnknownFields
+func (h HTTPHeader) MarshalJSON() ([]byte, error) {
+	type httpHeader HTTPHeader
+	return marshalKeepingUnknown(httpHeader(h), h.UnknownFields)
+}
+
+// UnmarshalJSON decodes StaticRRSet, keeping fields unknown to this version of the library in UnknownFields
+func (s *StaticRRSet) UnmarshalJSON(data []byte) error {
+	type staticRRSet StaticRRSet
+	return unmarshalKeepingUnknown(data, (*staticRRSet)(s), &s.UnknownFields)
+}
+
+// MarshalJSON encodes StaticRRSet together with its UnknownFields
+func (s StaticRRSet) MarshalJSON() ([]byte, error) {
+	type staticRRSet StaticRRSet
+	return marshalKeepingUnknown(staticRRSet(s), s.UnknownFields)
+}
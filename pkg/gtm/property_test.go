@@ -187,7 +187,7 @@ func TestGTM_CreateProperty(t *testing.T) {
 					IPv6:                   false,
 					Name:                   "origin",
 					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
+					StaticTTL:              ptr.To(600),
 					Type:                   "weighted-round-robin",
 					UseComputedTargets:     false,
 					LivenessTests: []LivenessTest{
@@ -197,24 +197,24 @@ func TestGTM_CreateProperty(t *testing.T) {
 							HTTPError4xx:                  true,
 							HTTPError5xx:                  true,
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
 						},
 					},
 					TrafficTargets: []TrafficTarget{
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 							Precedence:   nil,
 						},
@@ -324,15 +324,20 @@ func TestGTM_CreateProperty(t *testing.T) {
 `,
 			expectedResponse: &CreatePropertyResponse{
 				Resource: &Property{
-					BalanceByDownloadScore: false,
-					HandoutMode:            "normal",
-					IPv6:                   false,
-					Name:                   "origin",
-					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
-					DynamicTTL:             300,
-					Type:                   "weighted-round-robin",
-					UseComputedTargets:     false,
+					BalanceByDownloadScore:    false,
+					HandoutMode:               "normal",
+					IPv6:                      false,
+					Name:                      "origin",
+					ScoreAggregationType:      "mean",
+					StaticTTL:                 ptr.To(600),
+					DynamicTTL:                ptr.To(300),
+					FailbackDelay:             ptr.To(0),
+					FailoverDelay:             ptr.To(0),
+					StickinessBonusConstant:   ptr.To(0),
+					StickinessBonusPercentage: ptr.To(0),
+					Type:                      "weighted-round-robin",
+					UnknownFields:             UnknownFields{"mxRecords": json.RawMessage(`[]`)},
+					UseComputedTargets:        false,
 					LivenessTests: []LivenessTest{
 						{
 							DisableNonstandardPortWarning: false,
@@ -340,24 +345,25 @@ func TestGTM_CreateProperty(t *testing.T) {
 							HTTPError4xx:                  true,
 							HTTPError5xx:                  true,
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
+							UnknownFields:                 UnknownFields{"hostHeader": json.RawMessage(`"foo.example.com"`)},
 						},
 					},
 					TrafficTargets: []TrafficTarget{
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 						},
 					},
@@ -392,7 +398,7 @@ func TestGTM_CreateProperty(t *testing.T) {
 					IPv6:                   false,
 					Name:                   "origin",
 					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
+					StaticTTL:              ptr.To(600),
 					Type:                   "ranked-failover",
 					UseComputedTargets:     false,
 					LivenessTests: []LivenessTest{
@@ -404,11 +410,11 @@ func TestGTM_CreateProperty(t *testing.T) {
 							HTTPMethod:                    ptr.To("GET"),
 							HTTPRequestBody:               ptr.To("TestBody"),
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
 							Pre2023SecurityPosture:        true,
 							AlternateCACertificates:       []string{"test1"},
 						},
@@ -417,14 +423,14 @@ func TestGTM_CreateProperty(t *testing.T) {
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 							Precedence:   ptr.To(255),
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 							Precedence:   nil,
 						},
@@ -538,15 +544,20 @@ func TestGTM_CreateProperty(t *testing.T) {
 `,
 			expectedResponse: &CreatePropertyResponse{
 				Resource: &Property{
-					BalanceByDownloadScore: false,
-					HandoutMode:            "normal",
-					IPv6:                   false,
-					Name:                   "origin",
-					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
-					DynamicTTL:             300,
-					Type:                   "weighted-round-robin",
-					UseComputedTargets:     false,
+					BalanceByDownloadScore:    false,
+					HandoutMode:               "normal",
+					IPv6:                      false,
+					Name:                      "origin",
+					ScoreAggregationType:      "mean",
+					StaticTTL:                 ptr.To(600),
+					DynamicTTL:                ptr.To(300),
+					FailbackDelay:             ptr.To(0),
+					FailoverDelay:             ptr.To(0),
+					StickinessBonusConstant:   ptr.To(0),
+					StickinessBonusPercentage: ptr.To(0),
+					Type:                      "weighted-round-robin",
+					UnknownFields:             UnknownFields{"mxRecords": json.RawMessage(`[]`)},
+					UseComputedTargets:        false,
 					LivenessTests: []LivenessTest{
 						{
 							DisableNonstandardPortWarning: false,
@@ -558,25 +569,26 @@ func TestGTM_CreateProperty(t *testing.T) {
 							Pre2023SecurityPosture:        true,
 							AlternateCACertificates:       []string{"test1"},
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
+							UnknownFields:                 UnknownFields{"hostHeader": json.RawMessage(`"foo.example.com"`)},
 						},
 					},
 					TrafficTargets: []TrafficTarget{
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 							Precedence:   ptr.To(255),
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 							Precedence:   nil,
 						},
@@ -727,7 +739,7 @@ func TestGTM_UpdateProperty(t *testing.T) {
 					IPv6:                   false,
 					Name:                   "origin",
 					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
+					StaticTTL:              ptr.To(600),
 					Type:                   "weighted-round-robin",
 					UseComputedTargets:     false,
 					LivenessTests: []LivenessTest{
@@ -737,25 +749,25 @@ func TestGTM_UpdateProperty(t *testing.T) {
 							HTTPError4xx:                  true,
 							HTTPError5xx:                  true,
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
 						},
 					},
 					TrafficTargets: []TrafficTarget{
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 							Precedence:   ptr.To(255),
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 							Precedence:   nil,
 						},
@@ -865,15 +877,20 @@ func TestGTM_UpdateProperty(t *testing.T) {
 `,
 			expectedResponse: &UpdatePropertyResponse{
 				Resource: &Property{
-					BalanceByDownloadScore: false,
-					HandoutMode:            "normal",
-					IPv6:                   false,
-					Name:                   "origin",
-					ScoreAggregationType:   "mean",
-					StaticTTL:              600,
-					DynamicTTL:             300,
-					Type:                   "weighted-round-robin",
-					UseComputedTargets:     false,
+					BalanceByDownloadScore:    false,
+					HandoutMode:               "normal",
+					IPv6:                      false,
+					Name:                      "origin",
+					ScoreAggregationType:      "mean",
+					StaticTTL:                 ptr.To(600),
+					DynamicTTL:                ptr.To(300),
+					FailbackDelay:             ptr.To(0),
+					FailoverDelay:             ptr.To(0),
+					StickinessBonusConstant:   ptr.To(0),
+					StickinessBonusPercentage: ptr.To(0),
+					Type:                      "weighted-round-robin",
+					UnknownFields:             UnknownFields{"mxRecords": json.RawMessage(`[]`)},
+					UseComputedTargets:        false,
 					LivenessTests: []LivenessTest{
 						{
 							DisableNonstandardPortWarning: false,
@@ -881,25 +898,26 @@ func TestGTM_UpdateProperty(t *testing.T) {
 							HTTPError4xx:                  true,
 							HTTPError5xx:                  true,
 							Name:                          "health-check",
-							TestInterval:                  60,
+							TestInterval:                  ptr.To(60),
 							TestObject:                    "/status",
-							TestObjectPort:                80,
+							TestObjectPort:                ptr.To(80),
 							TestObjectProtocol:            "HTTP",
-							TestTimeout:                   25.0,
+							TestTimeout:                   ptr.To(float32(25.0)),
+							UnknownFields:                 UnknownFields{"hostHeader": json.RawMessage(`"foo.example.com"`)},
 						},
 					},
 					TrafficTargets: []TrafficTarget{
 						{
 							DatacenterID: 3134,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.5"},
 							Precedence:   ptr.To(255),
 						},
 						{
 							DatacenterID: 3133,
 							Enabled:      true,
-							Weight:       50.0,
+							Weight:       ptr.To(50.0),
 							Servers:      []string{"1.2.3.4"},
 						},
 					},
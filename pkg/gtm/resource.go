@@ -17,24 +17,26 @@ type (
 		DatacenterID         int  `json:"datacenterId"`
 		UseDefaultLoadObject bool `json:"useDefaultLoadObject"`
 		LoadObject
+		UnknownFields UnknownFields `json:"-"`
 	}
 
 	// Resource represents a GTM resource
 	Resource struct {
 		Type                        string             `json:"type"`
 		HostHeader                  string             `json:"hostHeader,omitempty"`
-		LeastSquaresDecay           float64            `json:"leastSquaresDecay,omitempty"`
+		LeastSquaresDecay           *float64           `json:"leastSquaresDecay,omitempty"`
 		Description                 string             `json:"description,omitempty"`
 		LeaderString                string             `json:"leaderString,omitempty"`
 		ConstrainedProperty         string             `json:"constrainedProperty,omitempty"`
 		ResourceInstances           []ResourceInstance `json:"resourceInstances,omitempty"`
 		AggregationType             string             `json:"aggregationType,omitempty"`
 		Links                       []Link             `json:"links,omitempty"`
-		LoadImbalancePercentage     float64            `json:"loadImbalancePercentage,omitempty"`
-		UpperBound                  int                `json:"upperBound,omitempty"`
+		LoadImbalancePercentage     *float64           `json:"loadImbalancePercentage,omitempty"`
+		UpperBound                  *int               `json:"upperBound,omitempty"`
 		Name                        string             `json:"name"`
-		MaxUMultiplicativeIncrement float64            `json:"maxUMultiplicativeIncrement,omitempty"`
-		DecayRate                   float64            `json:"decayRate,omitempty"`
+		MaxUMultiplicativeIncrement *float64           `json:"maxUMultiplicativeIncrement,omitempty"`
+		DecayRate                   *float64           `json:"decayRate,omitempty"`
+		UnknownFields               UnknownFields      `json:"-"`
 	}
 
 	// ResourceList is the structure returned by List Resources
@@ -298,3 +300,32 @@ func (g *gtm) DeleteResource(ctx context.Context, params DeleteResourceRequest)
 
 	return &result, nil
 }
+
+// UnmarshalJSON decodes Resource, keeping fields unknown to this version of the library in UnknownFields
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	type resource Resource
+	return unmarshalKeepingUnknown(data, (*resource)(r), &r.UnknownFields)
+}
+
+// MarshalJSON encodes Resource together with its UnknownFields
+func (r Resource) MarshalJSON() ([]byte, error) {
+	type resource Resource
+	return marshalKeepingUnknown(resource(r), r.UnknownFields)
+}
+
+// UnmarshalJSON decodes GetResourceResponse the same way as Resource
+func (r *GetResourceResponse) UnmarshalJSON(data []byte) error {
+	return (*Resource)(r).UnmarshalJSON(data)
+}
+
+// UnmarshalJSON decodes ResourceInstance, keeping fields unknown to this version of the library in UnknownFields
+func (r *ResourceInstance) UnmarshalJSON(data []byte) error {
+	type resourceInstance ResourceInstance
+	return unmarshalKeepingUnknown(data, (*resourceInstance)(r), &r.UnknownFields)
+}
+
+// MarshalJSON encodes ResourceInstance together with its UnknownFields
+func (r ResourceInstance) MarshalJSON() ([]byte, error) {
+	type resourceInstance ResourceInstance
+	return marshalKeepingUnknown(resourceInstance(r), r.UnknownFields)
+}
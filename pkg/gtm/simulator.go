@@ -0,0 +1,491 @@
+package gtm
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+type (
+	// SimulationClient describes the synthetic client (its resolver) for which handouts are simulated
+	SimulationClient struct {
+		IP      string
+		ASN     int64
+		Country string
+	}
+
+	// SimulationOptions contains the state of datacenters and servers used by the simulation
+	SimulationOptions struct {
+		// Liveness maps datacenter IDs to their liveness. Datacenters which are not listed are considered live
+		Liveness map[int]bool
+		// ServerLiveness maps server addresses to their liveness. Servers which are not listed are considered live
+		ServerLiveness map[string]bool
+		// Performance maps datacenter IDs to their score used by 'performance' properties, lower is better.
+		// Without scores, the first live traffic target is handed out
+		Performance map[int]float64
+		// Rand is the source of randomness used by weighted round-robin properties. Defaults to the math/rand global source
+		Rand *rand.Rand
+	}
+
+	// SimulationResult contains the simulated handout of a property
+	SimulationResult struct {
+		Property string
+		// DatacenterID is the ID of the chosen datacenter, it's 0 when a backup answer is handed out
+		DatacenterID int
+		// Addresses contains the handed out IP addresses
+		Addresses []string
+		// CName contains the handed out CNAME, if any
+		CName string
+		// Backup is set when the backup IP or CNAME of the property is handed out
+		Backup bool
+		// Reason explains how the datacenter was chosen
+		Reason string
+	}
+
+	// simulationTarget is an enabled traffic target together with the state of its datacenter
+	simulationTarget struct {
+		TrafficTarget
+		weight      float64
+		live        bool
+		liveServers []string
+	}
+)
+
+const (
+	// PropertyTypeFailover is the 'failover' property type
+	PropertyTypeFailover = "failover"
+	// PropertyTypeRankedFailover is the 'ranked-failover' property type
+	PropertyTypeRankedFailover = "ranked-failover"
+	// PropertyTypeWeightedRoundRobin is the 'weighted-round-robin' property type
+	PropertyTypeWeightedRoundRobin = "weighted-round-robin"
+	// PropertyTypeWeightedRoundRobinLoadFeedback is the 'weighted-round-robin-load-feedback' property type
+	PropertyTypeWeightedRoundRobinLoadFeedback = "weighted-round-robin-load-feedback"
+	// PropertyTypeWeightedHashed is the 'weighted-hashed' property type
+	PropertyTypeWeightedHashed = "weighted-hashed"
+	// PropertyTypeGeographic is the 'geographic' property type
+	PropertyTypeGeographic = "geographic"
+	// PropertyTypeCIDRMapping is the 'cidrmapping' property type
+	PropertyTypeCIDRMapping = "cidrmapping"
+	// PropertyTypeASMapping is the 'asmapping' property type
+	PropertyTypeASMapping = "asmapping"
+	// PropertyTypePerformance is the 'performance' property type
+	PropertyTypePerformance = "performance"
+	// PropertyTypeQTR is the 'qtr' property type
+	PropertyTypeQTR = "qtr"
+
+	// HandoutModeNormal hands out up to HandoutLimit live servers
+	HandoutModeNormal = "normal"
+	// HandoutModePersistent hands out up to HandoutLimit live servers
+	HandoutModePersistent = "persistent"
+	// HandoutModeOneIP hands out a single live server
+	HandoutModeOneIP = "one-ip"
+	// HandoutModeOneIPHashed hands out a single live server chosen by the hash of the client IP
+	HandoutModeOneIPHashed = "one-ip-hashed"
+	// HandoutModeAllLiveIPs hands out all live servers
+	HandoutModeAllLiveIPs = "all-live-ips"
+)
+
+var (
+	// ErrSimulation is returned when the handout simulation fails
+	ErrSimulation = errors.New("gtm simulation")
+)
+
+// SimulateDomain simulates the handouts of all properties of the domain for the given client,
+// the results are sorted by property name
+func SimulateDomain(domain *Domain, client SimulationClient, opts SimulationOptions) ([]SimulationResult, error) {
+	if domain == nil {
+		return nil, fmt.Errorf("%w: domain is required", ErrSimulation)
+	}
+
+	names := make([]string, 0, len(domain.Properties))
+	for _, p := range domain.Properties {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+
+	results := make([]SimulationResult, 0, len(names))
+	for _, name := range names {
+		result, err := SimulateProperty(domain, name, client, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// SimulateProperty simulates the handout of the given property for the client, offline, based on the domain configuration.
+//
+// A datacenter is live when it's not marked as down in SimulationOptions.Liveness and the fraction of its live servers
+// is at least Property.MinLiveFraction. When no datacenter can be chosen, BackupCName or BackupIP of the property is handed out.
+// Without a backup, all datacenters are treated as live, as GTM does when every datacenter is down.
+func SimulateProperty(domain *Domain, propertyName string, client SimulationClient, opts SimulationOptions) (*SimulationResult, error) {
+	if domain == nil {
+		return nil, fmt.Errorf("%w: domain is required", ErrSimulation)
+	}
+	var property *Property
+	for i := range domain.Properties {
+		if domain.Properties[i].Name == propertyName {
+			property = &domain.Properties[i]
+			break
+		}
+	}
+	if property == nil {
+		return nil, fmt.Errorf("%w: property %q not found in domain %q", ErrSimulation, propertyName, domain.Name)
+	}
+
+	var clientIP netip.Addr
+	if client.IP != "" {
+		ip, err := netip.ParseAddr(client.IP)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid client IP: %s", ErrSimulation, err)
+		}
+		clientIP = ip.Unmap()
+	}
+
+	targets := simulationTargets(property, opts)
+	target, reason, err := chooseTarget(domain, property, targets, clientIP, client, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: property %q: %w", ErrSimulation, propertyName, err)
+	}
+
+	if target == nil {
+		if backup := backupResult(property, reason); backup != nil {
+			return backup, nil
+		}
+		// GTM ignores liveness when there is nothing live to hand out
+		for i := range targets {
+			targets[i].live = true
+			targets[i].liveServers = targets[i].Servers
+		}
+		target, reason, err = chooseTarget(domain, property, targets, clientIP, client, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: property %q: %w", ErrSimulation, propertyName, err)
+		}
+		if target == nil {
+			return nil, fmt.Errorf("%w: property %q: %s", ErrSimulation, propertyName, reason)
+		}
+		reason += "; no live datacenter, liveness ignored"
+	}
+
+	result := SimulationResult{
+		Property:     property.Name,
+		DatacenterID: target.DatacenterID,
+		Reason:       reason,
+	}
+	if target.HandoutCName != "" {
+		result.CName = target.HandoutCName
+		return &result, nil
+	}
+	result.Addresses = handoutAddresses(property, target.liveServers, clientIP, opts)
+	return &result, nil
+}
+
+// simulationTargets returns enabled traffic targets of the property with their liveness
+func simulationTargets(property *Property, opts SimulationOptions) []simulationTarget {
+	var targets []simulationTarget
+	for _, tt := range property.TrafficTargets {
+		if !tt.Enabled {
+			continue
+		}
+		target := simulationTarget{TrafficTarget: tt, weight: deref(tt.Weight)}
+		for _, server := range tt.Servers {
+			if live, ok := opts.ServerLiveness[server]; !ok || live {
+				target.liveServers = append(target.liveServers, server)
+			}
+		}
+		target.live = datacenterLive(property, target, opts)
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func datacenterLive(property *Property, target simulationTarget, opts SimulationOptions) bool {
+	if live, ok := opts.Liveness[target.DatacenterID]; ok && !live {
+		return false
+	}
+	if target.HandoutCName != "" || len(target.Servers) == 0 {
+		return target.HandoutCName != ""
+	}
+	if len(target.liveServers) == 0 {
+		return false
+	}
+	fraction := float64(len(target.liveServers)) / float64(len(target.Servers))
+	return fraction >= deref(property.MinLiveFraction)
+}
+
+// chooseTarget returns the target chosen according to the property type, or nil if none of the candidates is live
+func chooseTarget(domain *Domain, property *Property, targets []simulationTarget, clientIP netip.Addr,
+	client SimulationClient, opts SimulationOptions) (*simulationTarget, string, error) {
+
+	switch property.Type {
+	case PropertyTypeFailover:
+		return chooseFailover(targets)
+	case PropertyTypeRankedFailover:
+		return chooseRankedFailover(targets)
+	case PropertyTypeWeightedRoundRobin, PropertyTypeWeightedRoundRobinLoadFeedback, PropertyTypeQTR:
+		return chooseWeighted(targets, randomFloat(opts.Rand), "weighted random choice")
+	case PropertyTypeWeightedHashed:
+		return chooseWeighted(targets, hashedFloat(property, clientIP), "weighted choice by client IP hash")
+	case PropertyTypePerformance:
+		return choosePerformance(targets, opts.Performance)
+	case PropertyTypeGeographic:
+		return chooseGeographic(domain, property, targets, client.Country)
+	case PropertyTypeCIDRMapping:
+		return chooseCIDRMapping(domain, property, targets, clientIP)
+	case PropertyTypeASMapping:
+		return chooseASMapping(domain, property, targets, client.ASN)
+	}
+	return nil, "", fmt.Errorf("unsupported property type %q", property.Type)
+}
+
+// chooseFailover returns the live target with the highest weight, the first one listed wins ties
+func chooseFailover(targets []simulationTarget) (*simulationTarget, string, error) {
+	return chooseInOrder(targets, func(a, b simulationTarget) bool { return a.weight > b.weight }, "by weight")
+}
+
+// chooseRankedFailover returns the live target with the lowest precedence
+func chooseRankedFailover(targets []simulationTarget) (*simulationTarget, string, error) {
+	return chooseInOrder(targets, func(a, b simulationTarget) bool { return precedence(a) < precedence(b) }, "by precedence")
+}
+
+func precedence(t simulationTarget) int {
+	if t.Precedence == nil {
+		return 0
+	}
+	return *t.Precedence
+}
+
+// chooseInOrder returns the first live target in the order defined by less
+func chooseInOrder(targets []simulationTarget, less func(a, b simulationTarget) bool, order string) (*simulationTarget, string, error) {
+	indexes := make([]int, len(targets))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return less(targets[indexes[a]], targets[indexes[b]])
+	})
+	for rank, i := range indexes {
+		if targets[i].live {
+			if rank == 0 {
+				return &targets[i], "primary target", nil
+			}
+			return &targets[i], "failover to the next target " + order, nil
+		}
+	}
+	return nil, "no live traffic target", nil
+}
+
+// chooseWeighted picks a live target with positive weight, pick has to be in range [0, 1)
+func chooseWeighted(targets []simulationTarget, pick float64, reason string) (*simulationTarget, string, error) {
+	var total float64
+	for _, t := range targets {
+		if t.live && t.weight > 0 {
+			total += t.weight
+		}
+	}
+	if total == 0 {
+		return nil, "no live traffic target with positive weight", nil
+	}
+
+	point := pick * total
+	var last *simulationTarget
+	for i := range targets {
+		t := &targets[i]
+		if !t.live || t.weight <= 0 {
+			continue
+		}
+		last = t
+		if point < t.weight {
+			return t, reason, nil
+		}
+		point -= t.weight
+	}
+	return last, reason, nil
+}
+
+// choosePerformance picks the live target with the lowest performance score
+func choosePerformance(targets []simulationTarget, scores map[int]float64) (*simulationTarget, string, error) {
+	var chosen *simulationTarget
+	for i := range targets {
+		t := &targets[i]
+		if !t.live {
+			continue
+		}
+		if chosen == nil {
+			chosen = t
+			continue
+		}
+		score, ok := scores[t.DatacenterID]
+		chosenScore, chosenOk := scores[chosen.DatacenterID]
+		if ok && (!chosenOk || score < chosenScore) {
+			chosen = t
+		}
+	}
+	if chosen == nil {
+		return nil, "no live traffic target", nil
+	}
+	if score, ok := scores[chosen.DatacenterID]; ok {
+		return chosen, fmt.Sprintf("best performance score %g", score), nil
+	}
+	return chosen, "first live traffic target, no performance scores", nil
+}
+
+func chooseGeographic(domain *Domain, property *Property, targets []simulationTarget, country string) (*simulationTarget, string, error) {
+	var geoMap *GeoMap
+	for i := range domain.GeographicMaps {
+		if domain.GeographicMaps[i].Name == property.MapName {
+			geoMap = &domain.GeographicMaps[i]
+		}
+	}
+	if geoMap == nil {
+		return nil, "", fmt.Errorf("geographic map %q not found", property.MapName)
+	}
+	for _, a := range geoMap.Assignments {
+		for _, c := range a.Countries {
+			if strings.EqualFold(c, country) {
+				return mappedTarget(targets, a.DatacenterID, fmt.Sprintf("country %s mapped by %q", c, geoMap.Name))
+			}
+		}
+	}
+	return defaultMappedTarget(targets, geoMap.DefaultDatacenter, geoMap.Name)
+}
+
+func chooseCIDRMapping(domain *Domain, property *Property, targets []simulationTarget, clientIP netip.Addr) (*simulationTarget, string, error) {
+	var cidrMap *CIDRMap
+	for i := range domain.CIDRMaps {
+		if domain.CIDRMaps[i].Name == property.MapName {
+			cidrMap = &domain.CIDRMaps[i]
+		}
+	}
+	if cidrMap == nil {
+		return nil, "", fmt.Errorf("CIDR map %q not found", property.MapName)
+	}
+
+	var best netip.Prefix
+	bestDatacenter := 0
+	if clientIP.IsValid() {
+		for _, a := range cidrMap.Assignments {
+			for _, block := range a.Blocks {
+				prefix, err := netip.ParsePrefix(block)
+				if err != nil {
+					return nil, "", fmt.Errorf("CIDR map %q: invalid block %q: %w", cidrMap.Name, block, err)
+				}
+				if prefix.Contains(clientIP) && (!best.IsValid() || prefix.Bits() > best.Bits()) {
+					best, bestDatacenter = prefix, a.DatacenterID
+				}
+			}
+		}
+	}
+	if best.IsValid() {
+		return mappedTarget(targets, bestDatacenter, fmt.Sprintf("block %s mapped by %q", best, cidrMap.Name))
+	}
+	return defaultMappedTarget(targets, cidrMap.DefaultDatacenter, cidrMap.Name)
+}
+
+func chooseASMapping(domain *Domain, property *Property, targets []simulationTarget, asn int64) (*simulationTarget, string, error) {
+	var asMap *ASMap
+	for i := range domain.ASMaps {
+		if domain.ASMaps[i].Name == property.MapName {
+			asMap = &domain.ASMaps[i]
+		}
+	}
+	if asMap == nil {
+		return nil, "", fmt.Errorf("AS map %q not found", property.MapName)
+	}
+	for _, a := range asMap.Assignments {
+		for _, n := range a.ASNumbers {
+			if n == asn {
+				return mappedTarget(targets, a.DatacenterID, fmt.Sprintf("AS%d mapped by %q", n, asMap.Name))
+			}
+		}
+	}
+	return defaultMappedTarget(targets, asMap.DefaultDatacenter, asMap.Name)
+}
+
+func defaultMappedTarget(targets []simulationTarget, dc *DatacenterBase, mapName string) (*simulationTarget, string, error) {
+	if dc == nil {
+		return nil, "", fmt.Errorf("map %q: %w", mapName, ErrNoDatacenterAssignedToMap)
+	}
+	return mappedTarget(targets, dc.DatacenterID, fmt.Sprintf("default datacenter of %q", mapName))
+}
+
+// mappedTarget returns the target of the datacenter the client is mapped to. Map based properties do not fail over
+// to other datacenters, so the datacenter not being live results in nil target
+func mappedTarget(targets []simulationTarget, datacenterID int, reason string) (*simulationTarget, string, error) {
+	for i := range targets {
+		if targets[i].DatacenterID == datacenterID {
+			if !targets[i].live {
+				return nil, fmt.Sprintf("%s, datacenter %d is not live", reason, datacenterID), nil
+			}
+			return &targets[i], reason, nil
+		}
+	}
+	return nil, fmt.Sprintf("%s, datacenter %d has no enabled traffic target", reason, datacenterID), nil
+}
+
+func backupResult(property *Property, reason string) *SimulationResult {
+	result := SimulationResult{Property: property.Name, Backup: true}
+	switch {
+	case property.BackupCName != "":
+		result.CName = property.BackupCName
+		result.Reason = reason + "; backup CNAME handed out"
+	case property.BackupIP != "":
+		result.Addresses = []string{property.BackupIP}
+		result.Reason = reason + "; backup IP handed out"
+	default:
+		return nil
+	}
+	return &result
+}
+
+// handoutAddresses limits live servers according to the handout mode and limit of the property
+func handoutAddresses(property *Property, servers []string, clientIP netip.Addr, opts SimulationOptions) []string {
+	if len(servers) == 0 {
+		return nil
+	}
+	switch property.HandoutMode {
+	case HandoutModeOneIP:
+		return []string{servers[int(randomFloat(opts.Rand)*float64(len(servers)))]}
+	case HandoutModeOneIPHashed:
+		return []string{servers[int(hashedFloat(property, clientIP)*float64(len(servers)))]}
+	case HandoutModeAllLiveIPs:
+		return append([]string(nil), servers...)
+	}
+	if property.HandoutLimit > 0 && len(servers) > property.HandoutLimit {
+		servers = servers[:property.HandoutLimit]
+	}
+	return append([]string(nil), servers...)
+}
+
+func randomFloat(r *rand.Rand) float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+	return r.Float64()
+}
+
+// hashedFloat maps the client IP, masked to the property's weighted hash bits, into range [0, 1)
+func hashedFloat(property *Property, clientIP netip.Addr) float64 {
+	if !clientIP.IsValid() {
+		return 0
+	}
+	bits := deref(property.WeightedHashBitsForIPv4)
+	if clientIP.Is6() {
+		bits = deref(property.WeightedHashBitsForIPv6)
+	}
+	if bits <= 0 || bits > clientIP.BitLen() {
+		bits = clientIP.BitLen()
+	}
+	prefix, err := clientIP.Prefix(bits)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(prefix.Addr().AsSlice())
+	return float64(h.Sum64()>>11) / float64(1<<53)
+}
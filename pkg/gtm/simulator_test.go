@@ -0,0 +1,214 @@
+package gtm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func simulationDomain() *Domain {
+	targets := []TrafficTarget{
+		{DatacenterID: 3131, Enabled: true, Weight: ptr.To(1.0), Servers: []string{"1.0.0.1", "1.0.0.2", "1.0.0.3"}},
+		{DatacenterID: 3132, Enabled: true, Weight: ptr.To(0.0), Servers: []string{"2.0.0.1"}},
+		{DatacenterID: 3133, Enabled: false, Weight: ptr.To(0.0), Servers: []string{"3.0.0.1"}},
+	}
+	return &Domain{
+		Name: "example.akadns.net",
+		Properties: []Property{
+			{Name: "failover", Type: "failover", HandoutMode: "normal", HandoutLimit: 8, TrafficTargets: targets},
+			{Name: "failover-backup", Type: "failover", HandoutMode: "normal", BackupCName: "backup.example.com", TrafficTargets: targets},
+			{Name: "ranked", Type: "ranked-failover", HandoutMode: "normal", TrafficTargets: []TrafficTarget{
+				{DatacenterID: 3131, Enabled: true, Precedence: ptr.To(10), Servers: []string{"1.0.0.1"}},
+				{DatacenterID: 3132, Enabled: true, Precedence: ptr.To(0), Servers: []string{"2.0.0.1"}},
+			}},
+			{Name: "weighted", Type: "weighted-round-robin", HandoutMode: "normal", HandoutLimit: 2, MinLiveFraction: ptr.To(0.5), BackupIP: "9.9.9.9",
+				TrafficTargets: []TrafficTarget{
+					{DatacenterID: 3131, Enabled: true, Weight: ptr.To(75.0), Servers: []string{"1.0.0.1", "1.0.0.2", "1.0.0.3"}},
+					{DatacenterID: 3132, Enabled: true, Weight: ptr.To(25.0), Servers: []string{"2.0.0.1"}},
+				}},
+			{Name: "hashed", Type: "weighted-hashed", HandoutMode: "one-ip-hashed", WeightedHashBitsForIPv4: ptr.To(24),
+				TrafficTargets: []TrafficTarget{
+					{DatacenterID: 3131, Enabled: true, Weight: ptr.To(50.0), Servers: []string{"1.0.0.1", "1.0.0.2", "1.0.0.3"}},
+					{DatacenterID: 3132, Enabled: true, Weight: ptr.To(50.0), Servers: []string{"2.0.0.1", "2.0.0.2"}},
+				}},
+			{Name: "geo", Type: "geographic", HandoutMode: "all-live-ips", MapName: "geo-map", TrafficTargets: targets},
+			{Name: "cidr", Type: "cidrmapping", HandoutMode: "normal", MapName: "cidr-map", TrafficTargets: []TrafficTarget{
+				{DatacenterID: 3131, Enabled: true, Servers: []string{"1.0.0.1"}},
+				{DatacenterID: 3132, Enabled: true, HandoutCName: "dc2.example.com"},
+			}},
+			{Name: "as", Type: "asmapping", HandoutMode: "normal", MapName: "as-map", TrafficTargets: targets},
+			{Name: "performance", Type: "performance", HandoutMode: "normal", TrafficTargets: targets},
+		},
+		GeographicMaps: []GeoMap{{
+			Name:              "geo-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 3131},
+			Assignments:       []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Countries: []string{"PL", "DE"}}},
+		}},
+		CIDRMaps: []CIDRMap{{
+			Name:              "cidr-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 3131},
+			Assignments: []CIDRAssignment{
+				{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Blocks: []string{"10.0.0.0/8"}},
+				{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Blocks: []string{"10.1.0.0/16"}},
+			},
+		}},
+		ASMaps: []ASMap{{
+			Name:              "as-map",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 3131},
+			Assignments:       []ASAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3132}, ASNumbers: []int64{12222, 16625}}},
+		}},
+	}
+}
+
+func TestSimulateProperty(t *testing.T) {
+	tests := map[string]struct {
+		property  string
+		client    SimulationClient
+		opts      SimulationOptions
+		expected  *SimulationResult
+		withError string
+	}{
+		"failover primary": {
+			property: "failover",
+			expected: &SimulationResult{Property: "failover", DatacenterID: 3131, Addresses: []string{"1.0.0.1", "1.0.0.2", "1.0.0.3"}, Reason: "primary target"},
+		},
+		"failover to secondary": {
+			property: "failover",
+			opts:     SimulationOptions{Liveness: map[int]bool{3131: false}},
+			expected: &SimulationResult{Property: "failover", DatacenterID: 3132, Addresses: []string{"2.0.0.1"}, Reason: "failover to the next target by weight"},
+		},
+		"all down without backup": {
+			property: "failover",
+			opts:     SimulationOptions{Liveness: map[int]bool{3131: false, 3132: false}},
+			expected: &SimulationResult{Property: "failover", DatacenterID: 3131, Addresses: []string{"1.0.0.1", "1.0.0.2", "1.0.0.3"},
+				Reason: "primary target; no live datacenter, liveness ignored"},
+		},
+		"all down with backup CNAME": {
+			property: "failover-backup",
+			opts:     SimulationOptions{ServerLiveness: map[string]bool{"1.0.0.1": false, "1.0.0.2": false, "1.0.0.3": false, "2.0.0.1": false}},
+			expected: &SimulationResult{Property: "failover-backup", CName: "backup.example.com", Backup: true, Reason: "no live traffic target; backup CNAME handed out"},
+		},
+		"ranked failover": {
+			property: "ranked",
+			opts:     SimulationOptions{Liveness: map[int]bool{3132: false}},
+			expected: &SimulationResult{Property: "ranked", DatacenterID: 3131, Addresses: []string{"1.0.0.1"}, Reason: "failover to the next target by precedence"},
+		},
+		"weighted with handout limit": {
+			property: "weighted",
+			opts:     SimulationOptions{Rand: rand.New(rand.NewSource(1))},
+			expected: &SimulationResult{Property: "weighted", DatacenterID: 3131, Addresses: []string{"1.0.0.1", "1.0.0.2"}, Reason: "weighted random choice"},
+		},
+		"weighted below min live fraction": {
+			property: "weighted",
+			opts: SimulationOptions{
+				ServerLiveness: map[string]bool{"1.0.0.1": false, "1.0.0.2": false},
+				Rand:           rand.New(rand.NewSource(1)),
+			},
+			expected: &SimulationResult{Property: "weighted", DatacenterID: 3132, Addresses: []string{"2.0.0.1"}, Reason: "weighted random choice"},
+		},
+		"weighted backup IP": {
+			property: "weighted",
+			opts:     SimulationOptions{Liveness: map[int]bool{3131: false, 3132: false}},
+			expected: &SimulationResult{Property: "weighted", Addresses: []string{"9.9.9.9"}, Backup: true,
+				Reason: "no live traffic target with positive weight; backup IP handed out"},
+		},
+		"geographic assignment": {
+			property: "geo",
+			client:   SimulationClient{Country: "pl"},
+			expected: &SimulationResult{Property: "geo", DatacenterID: 3132, Addresses: []string{"2.0.0.1"}, Reason: `country PL mapped by "geo-map"`},
+		},
+		"geographic default": {
+			property: "geo",
+			client:   SimulationClient{Country: "US"},
+			opts:     SimulationOptions{ServerLiveness: map[string]bool{"1.0.0.2": false}},
+			expected: &SimulationResult{Property: "geo", DatacenterID: 3131, Addresses: []string{"1.0.0.1", "1.0.0.3"}, Reason: `default datacenter of "geo-map"`},
+		},
+		"cidr longest prefix with handout CNAME": {
+			property: "cidr",
+			client:   SimulationClient{IP: "10.1.2.3"},
+			expected: &SimulationResult{Property: "cidr", DatacenterID: 3132, CName: "dc2.example.com", Reason: `block 10.1.0.0/16 mapped by "cidr-map"`},
+		},
+		"cidr shorter prefix": {
+			property: "cidr",
+			client:   SimulationClient{IP: "10.2.2.3"},
+			expected: &SimulationResult{Property: "cidr", DatacenterID: 3131, Addresses: []string{"1.0.0.1"}, Reason: `block 10.0.0.0/8 mapped by "cidr-map"`},
+		},
+		"as mapping": {
+			property: "as",
+			client:   SimulationClient{ASN: 16625},
+			expected: &SimulationResult{Property: "as", DatacenterID: 3132, Addresses: []string{"2.0.0.1"}, Reason: `AS16625 mapped by "as-map"`},
+		},
+		"performance": {
+			property: "performance",
+			opts:     SimulationOptions{Performance: map[int]float64{3131: 80, 3132: 20}},
+			expected: &SimulationResult{Property: "performance", DatacenterID: 3132, Addresses: []string{"2.0.0.1"}, Reason: "best performance score 20"},
+		},
+		"property not found": {
+			property:  "missing",
+			withError: `property "missing" not found in domain "example.akadns.net"`,
+		},
+		"invalid client IP": {
+			property:  "cidr",
+			client:    SimulationClient{IP: "10.1"},
+			withError: "invalid client IP",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := SimulateProperty(simulationDomain(), test.property, test.client, test.opts)
+			if test.withError != "" {
+				assert.True(t, errors.Is(err, ErrSimulation), "want: %s; got: %s", ErrSimulation, err)
+				assert.Contains(t, err.Error(), test.withError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestSimulateProperty_WeightedDistribution(t *testing.T) {
+	domain := simulationDomain()
+	opts := SimulationOptions{Rand: rand.New(rand.NewSource(42))}
+	counts := map[int]int{}
+	for i := 0; i < 10000; i++ {
+		result, err := SimulateProperty(domain, "weighted", SimulationClient{}, opts)
+		require.NoError(t, err)
+		counts[result.DatacenterID]++
+	}
+	assert.InDelta(t, 7500, counts[3131], 300)
+	assert.InDelta(t, 2500, counts[3132], 300)
+}
+
+func TestSimulateProperty_WeightedHashed(t *testing.T) {
+	domain := simulationDomain()
+	first, err := SimulateProperty(domain, "hashed", SimulationClient{IP: "192.0.2.1"}, SimulationOptions{})
+	require.NoError(t, err)
+	require.Len(t, first.Addresses, 1)
+
+	// clients within the same /24 get the same answer
+	second, err := SimulateProperty(domain, "hashed", SimulationClient{IP: "192.0.2.200"}, SimulationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestSimulateDomain(t *testing.T) {
+	results, err := SimulateDomain(simulationDomain(), SimulationClient{IP: "10.1.0.1", ASN: 12222, Country: "DE"}, SimulationOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Property)
+	}
+	assert.Equal(t, []string{"as", "cidr", "failover", "failover-backup", "geo", "hashed", "performance", "ranked", "weighted"}, names)
+
+	domain := simulationDomain()
+	domain.Properties = append(domain.Properties, Property{Name: "unknown", Type: "qtr-foo"})
+	_, err = SimulateDomain(domain, SimulationClient{}, SimulationOptions{})
+	assert.True(t, errors.Is(err, ErrSimulation), "want: %s; got: %s", ErrSimulation, err)
+	assert.Contains(t, err.Error(), `unsupported property type "qtr-foo"`)
+}
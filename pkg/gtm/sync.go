@@ -0,0 +1,492 @@
+package gtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type (
+	// SyncOptions contains options of SyncDomain
+	SyncOptions struct {
+		// DryRun makes SyncDomain return the planned changes without applying them
+		DryRun bool
+		// PollInterval is the interval between domain status checks. Defaults to DefaultSyncPollInterval
+		PollInterval time.Duration
+		// Timeout limits the time of waiting for the propagation of changes. Defaults to DefaultSyncTimeout
+		Timeout time.Duration
+		// Progress receives events about applied changes and propagation status, if set
+		Progress chan<- SyncEvent
+	}
+
+	// SyncObjectType is the type of domain object changed by the sync
+	SyncObjectType string
+
+	// SyncAction is the action performed on a domain object
+	SyncAction string
+
+	// SyncChange describes a single change of a domain object
+	SyncChange struct {
+		Action SyncAction
+		Object SyncObjectType
+		Name   string
+
+		apply func(ctx context.Context, client GTM, domainName string) error
+	}
+
+	// SyncEventType is the type of SyncEvent
+	SyncEventType string
+
+	// SyncEvent reports the progress of SyncDomain
+	SyncEvent struct {
+		Type SyncEventType
+		// Change is set for SyncEventChangeApplied events
+		Change *SyncChange
+		// Status is set for SyncEventPropagation events
+		Status *ResponseStatus
+	}
+
+	// SyncResult contains the outcome of SyncDomain
+	SyncResult struct {
+		// Changes lists changes in the order they were, or would be in case of a dry run, applied
+		Changes []SyncChange
+		// Status is the last status of the domain, it's nil when nothing was applied
+		Status *ResponseStatus
+	}
+)
+
+const (
+	// SyncObjectDatacenter is a datacenter
+	SyncObjectDatacenter SyncObjectType = "datacenter"
+	// SyncObjectGeoMap is a geographic map
+	SyncObjectGeoMap SyncObjectType = "geographicMap"
+	// SyncObjectCIDRMap is a CIDR map
+	SyncObjectCIDRMap SyncObjectType = "cidrMap"
+	// SyncObjectASMap is an AS map
+	SyncObjectASMap SyncObjectType = "asMap"
+	// SyncObjectProperty is a property
+	SyncObjectProperty SyncObjectType = "property"
+	// SyncObjectResource is a resource
+	SyncObjectResource SyncObjectType = "resource"
+
+	// SyncActionCreate creates an object
+	SyncActionCreate SyncAction = "CREATE"
+	// SyncActionUpdate updates an object
+	SyncActionUpdate SyncAction = "UPDATE"
+	// SyncActionDelete deletes an object
+	SyncActionDelete SyncAction = "DELETE"
+
+	// SyncEventChangeApplied is sent after each applied change
+	SyncEventChangeApplied SyncEventType = "CHANGE_APPLIED"
+	// SyncEventPropagation is sent after each domain status check
+	SyncEventPropagation SyncEventType = "PROPAGATION"
+	// SyncEventComplete is sent when changes are propagated
+	SyncEventComplete SyncEventType = "COMPLETE"
+
+	// PropagationStatusComplete is the propagation status of a domain whose changes are propagated
+	PropagationStatusComplete = "COMPLETE"
+	// PropagationStatusDenied is the propagation status of a domain whose changes were rejected
+	PropagationStatusDenied = "DENIED"
+
+	// DefaultSyncPollInterval is the default interval between domain status checks
+	DefaultSyncPollInterval = 10 * time.Second
+	// DefaultSyncTimeout is the default time of waiting for the propagation of changes
+	DefaultSyncTimeout = 30 * time.Minute
+)
+
+var (
+	// ErrSyncDomain is returned when SyncDomain fails
+	ErrSyncDomain = errors.New("sync domain")
+	// ErrPropagationDenied is returned when the domain changes are denied
+	ErrPropagationDenied = errors.New("propagation denied")
+)
+
+// SyncDomain brings the domain to the desired state. The current domain is fetched with GetDomain and compared with
+// the desired one, then datacenters, maps, properties and resources are created, updated or deleted one by one.
+// Creates and updates go in dependency order (datacenters, geographic, CIDR and AS maps, properties, resources),
+// followed by deletes in the reverse order. Finally, the domain status is polled until the propagation is complete.
+//
+// Datacenters are matched by ID, or by nickname if the desired datacenter has no ID. Default datacenters
+// (MapDefaultDC, Ipv4DefaultDC and Ipv6DefaultDC) are created with dedicated endpoints and never deleted.
+// Objects are updated only if fields set in the desired object differ. Fields which are not set, e.g. nil pointers,
+// are left with values populated by the server: an update sends the current object, including its unknown fields,
+// with fields set in the desired one applied. Other domain settings are not synced, use UpdateDomain for them.
+func SyncDomain(ctx context.Context, client GTM, desired *Domain, opts SyncOptions) (*SyncResult, error) {
+	if desired == nil || desired.Name == "" {
+		return nil, fmt.Errorf("%w: desired domain with name is required", ErrSyncDomain)
+	}
+	current, err := client.GetDomain(ctx, GetDomainRequest{DomainName: desired.Name})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSyncDomain, err)
+	}
+
+	result := SyncResult{Changes: PlanDomainSync((*Domain)(current), desired)}
+	if opts.DryRun || len(result.Changes) == 0 {
+		return &result, nil
+	}
+
+	for i := range result.Changes {
+		change := &result.Changes[i]
+		if err := change.apply(ctx, client, desired.Name); err != nil {
+			return &result, fmt.Errorf("%w: %s %s %q: %w", ErrSyncDomain, change.Action, change.Object, change.Name, err)
+		}
+		if err := sendSyncEvent(ctx, opts.Progress, SyncEvent{Type: SyncEventChangeApplied, Change: change}); err != nil {
+			return &result, fmt.Errorf("%w: %w", ErrSyncDomain, err)
+		}
+	}
+
+	status, err := waitForPropagation(ctx, client, desired.Name, opts)
+	result.Status = status
+	if err != nil {
+		return &result, fmt.Errorf("%w: %w", ErrSyncDomain, err)
+	}
+	return &result, nil
+}
+
+// PlanDomainSync returns changes needed to turn the current domain into the desired one, see SyncDomain
+func PlanDomainSync(current, desired *Domain) []SyncChange {
+	if current == nil {
+		current = &Domain{}
+	}
+	if desired == nil {
+		desired = &Domain{}
+	}
+
+	datacenters := planDatacenters(current.Datacenters, desired.Datacenters)
+	geoMaps := planObjects(SyncObjectGeoMap, current.GeographicMaps, desired.GeographicMaps, func(m GeoMap) string { return m.Name },
+		func(m GeoMap) GeoMap { m.Links, m.UnknownFields = nil, nil; return m },
+		func(ctx context.Context, client GTM, domain string, m GeoMap) error {
+			_, err := client.CreateGeoMap(ctx, CreateGeoMapRequest{GeoMap: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m GeoMap) error {
+			_, err := client.UpdateGeoMap(ctx, UpdateGeoMapRequest{GeoMap: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m GeoMap) error {
+			_, err := client.DeleteGeoMap(ctx, DeleteGeoMapRequest{MapName: m.Name, DomainName: domain})
+			return err
+		})
+	cidrMaps := planObjects(SyncObjectCIDRMap, current.CIDRMaps, desired.CIDRMaps, func(m CIDRMap) string { return m.Name },
+		func(m CIDRMap) CIDRMap { m.Links, m.UnknownFields = nil, nil; return m },
+		func(ctx context.Context, client GTM, domain string, m CIDRMap) error {
+			_, err := client.CreateCIDRMap(ctx, CreateCIDRMapRequest{CIDR: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m CIDRMap) error {
+			_, err := client.UpdateCIDRMap(ctx, UpdateCIDRMapRequest{CIDR: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m CIDRMap) error {
+			_, err := client.DeleteCIDRMap(ctx, DeleteCIDRMapRequest{MapName: m.Name, DomainName: domain})
+			return err
+		})
+	asMaps := planObjects(SyncObjectASMap, current.ASMaps, desired.ASMaps, func(m ASMap) string { return m.Name },
+		func(m ASMap) ASMap { m.Links, m.UnknownFields = nil, nil; return m },
+		func(ctx context.Context, client GTM, domain string, m ASMap) error {
+			_, err := client.CreateASMap(ctx, CreateASMapRequest{ASMap: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m ASMap) error {
+			_, err := client.UpdateASMap(ctx, UpdateASMapRequest{ASMap: &m, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, m ASMap) error {
+			_, err := client.DeleteASMap(ctx, DeleteASMapRequest{ASMapName: m.Name, DomainName: domain})
+			return err
+		})
+	properties := planObjects(SyncObjectProperty, current.Properties, desired.Properties, func(p Property) string { return p.Name },
+		func(p Property) Property {
+			p.Links, p.LastModified, p.UnknownFields = nil, "", nil
+			p.LivenessTests = append([]LivenessTest(nil), p.LivenessTests...)
+			for i := range p.LivenessTests {
+				p.LivenessTests[i].Links, p.LivenessTests[i].UnknownFields = nil, nil
+			}
+			p.TrafficTargets = append([]TrafficTarget(nil), p.TrafficTargets...)
+			for i := range p.TrafficTargets {
+				p.TrafficTargets[i].UnknownFields = nil
+			}
+			return p
+		},
+		func(ctx context.Context, client GTM, domain string, p Property) error {
+			_, err := client.CreateProperty(ctx, CreatePropertyRequest{Property: &p, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, p Property) error {
+			_, err := client.UpdateProperty(ctx, UpdatePropertyRequest{Property: &p, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, p Property) error {
+			_, err := client.DeleteProperty(ctx, DeletePropertyRequest{PropertyName: p.Name, DomainName: domain})
+			return err
+		})
+	resources := planObjects(SyncObjectResource, current.Resources, desired.Resources, func(r Resource) string { return r.Name },
+		func(r Resource) Resource { r.Links, r.UnknownFields = nil, nil; return r },
+		func(ctx context.Context, client GTM, domain string, r Resource) error {
+			_, err := client.CreateResource(ctx, CreateResourceRequest{Resource: &r, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, r Resource) error {
+			_, err := client.UpdateResource(ctx, UpdateResourceRequest{Resource: &r, DomainName: domain})
+			return err
+		},
+		func(ctx context.Context, client GTM, domain string, r Resource) error {
+			_, err := client.DeleteResource(ctx, DeleteResourceRequest{ResourceName: r.Name, DomainName: domain})
+			return err
+		})
+
+	plans := []objectPlan{datacenters, geoMaps, cidrMaps, asMaps, properties, resources}
+	var changes []SyncChange
+	for _, p := range plans {
+		changes = append(changes, p.upserts...)
+	}
+	for i := len(plans) - 1; i >= 0; i-- {
+		changes = append(changes, plans[i].deletes...)
+	}
+	return changes
+}
+
+// objectPlan contains changes of a single object type
+type objectPlan struct {
+	upserts []SyncChange
+	deletes []SyncChange
+}
+
+type syncFunc[T any] func(ctx context.Context, client GTM, domain string, obj T) error
+
+// planObjects compares objects of a single type matched by key, normalize removes fields which should not be compared
+func planObjects[T any](objectType SyncObjectType, current, desired []T, key func(T) string, normalize func(T) T,
+	create, update, remove syncFunc[T]) objectPlan {
+
+	currentByKey := make(map[string]T, len(current))
+	for _, obj := range current {
+		currentByKey[key(obj)] = obj
+	}
+	desiredKeys := make(map[string]bool, len(desired))
+
+	var plan objectPlan
+	for _, obj := range desired {
+		k := key(obj)
+		desiredKeys[k] = true
+		existing, ok := currentByKey[k]
+		switch {
+		case !ok:
+			plan.upserts = append(plan.upserts, newSyncChange(SyncActionCreate, objectType, k, obj, create))
+		case !equalNormalized(normalize(existing), normalize(obj)):
+			plan.upserts = append(plan.upserts, newSyncChange(SyncActionUpdate, objectType, k, obj, updateMerged(existing, update)))
+		}
+	}
+	for _, obj := range current {
+		if k := key(obj); !desiredKeys[k] && remove != nil {
+			plan.deletes = append(plan.deletes, newSyncChange(SyncActionDelete, objectType, k, obj, remove))
+		}
+	}
+	return plan
+}
+
+// updateMerged returns update which sends the current object with fields set in the desired one applied,
+// as updates replace whole objects
+func updateMerged[T any](current T, update syncFunc[T]) syncFunc[T] {
+	return func(ctx context.Context, client GTM, domain string, desired T) error {
+		merged, err := mergeUnset(current, desired)
+		if err != nil {
+			return err
+		}
+		return update(ctx, client, domain, merged)
+	}
+}
+
+// mergeUnset returns the desired object with fields which are not set taken from the current one, see fillUnset
+func mergeUnset[T any](current, desired T) (T, error) {
+	var merged T
+	currentValue, err := jsonValue(current)
+	if err != nil {
+		return merged, err
+	}
+	desiredValue, err := jsonValue(desired)
+	if err != nil {
+		return merged, err
+	}
+	data, err := json.Marshal(fillUnset(desiredValue, currentValue))
+	if err != nil {
+		return merged, err
+	}
+	err = json.Unmarshal(data, &merged)
+	return merged, err
+}
+
+func newSyncChange[T any](action SyncAction, objectType SyncObjectType, name string, obj T, apply syncFunc[T]) SyncChange {
+	return SyncChange{
+		Action: action,
+		Object: objectType,
+		Name:   name,
+		apply: func(ctx context.Context, client GTM, domain string) error {
+			return apply(ctx, client, domain, obj)
+		},
+	}
+}
+
+// equalNormalized compares JSON representations of objects, so that nil and empty slices omitted from JSON are equal.
+// Links, read-only and unknown fields are removed by normalize functions before the comparison. Fields which are
+// not set in the desired object, e.g. ones populated by the server with defaults, are taken from the current one,
+// so that only fields set in the desired object are compared.
+func equalNormalized(current, desired interface{}) bool {
+	currentValue, errCurrent := jsonValue(current)
+	desiredValue, errDesired := jsonValue(desired)
+	if errCurrent != nil || errDesired != nil {
+		return false
+	}
+	return reflect.DeepEqual(currentValue, fillUnset(desiredValue, currentValue))
+}
+
+func jsonValue(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// fillUnset returns the desired JSON value with fields which are missing or null taken from the current value.
+// Objects are filled recursively, and so are elements of arrays of the same length.
+func fillUnset(desired, current interface{}) interface{} {
+	switch d := desired.(type) {
+	case nil:
+		return current
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			return desired
+		}
+		for key, value := range c {
+			d[key] = fillUnset(d[key], value)
+		}
+		return d
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok || len(c) != len(d) {
+			return desired
+		}
+		for i := range d {
+			d[i] = fillUnset(d[i], c[i])
+		}
+		return d
+	}
+	return desired
+}
+
+func isDefaultDatacenter(id int) bool {
+	return id == MapDefaultDC || id == Ipv4DefaultDC || id == Ipv6DefaultDC
+}
+
+func planDatacenters(current, desired []Datacenter) objectPlan {
+	byNickname := make(map[string]int)
+	for _, dc := range current {
+		if dc.Nickname != "" {
+			byNickname[dc.Nickname] = dc.DatacenterID
+		}
+	}
+	resolved := make([]Datacenter, len(desired))
+	for i, dc := range desired {
+		if dc.DatacenterID == 0 {
+			dc.DatacenterID = byNickname[dc.Nickname]
+		}
+		resolved[i] = dc
+	}
+
+	key := func(dc Datacenter) string {
+		if dc.DatacenterID == 0 {
+			return dc.Nickname
+		}
+		return strconv.Itoa(dc.DatacenterID)
+	}
+	create := func(ctx context.Context, client GTM, domain string, dc Datacenter) error {
+		var err error
+		switch dc.DatacenterID {
+		case MapDefaultDC:
+			_, err = client.CreateMapsDefaultDatacenter(ctx, domain)
+		case Ipv4DefaultDC:
+			_, err = client.CreateIPv4DefaultDatacenter(ctx, domain)
+		case Ipv6DefaultDC:
+			_, err = client.CreateIPv6DefaultDatacenter(ctx, domain)
+		default:
+			_, err = client.CreateDatacenter(ctx, CreateDatacenterRequest{Datacenter: &dc, DomainName: domain})
+		}
+		return err
+	}
+	update := func(ctx context.Context, client GTM, domain string, dc Datacenter) error {
+		_, err := client.UpdateDatacenter(ctx, UpdateDatacenterRequest{Datacenter: &dc, DomainName: domain})
+		return err
+	}
+	remove := func(ctx context.Context, client GTM, domain string, dc Datacenter) error {
+		_, err := client.DeleteDatacenter(ctx, DeleteDatacenterRequest{DatacenterID: dc.DatacenterID, DomainName: domain})
+		return err
+	}
+
+	plan := planObjects(SyncObjectDatacenter, current, resolved, key, func(dc Datacenter) Datacenter { dc.Links, dc.UnknownFields, dc.Virtual = nil, nil, false; return dc },
+		create, update, remove)
+
+	// default datacenters can't be deleted
+	deletes := plan.deletes[:0]
+	for _, change := range plan.deletes {
+		if id, _ := strconv.Atoi(change.Name); !isDefaultDatacenter(id) {
+			deletes = append(deletes, change)
+		}
+	}
+	plan.deletes = deletes
+	return plan
+}
+
+// waitForPropagation polls the domain status until the propagation is complete
+func waitForPropagation(ctx context.Context, client GTM, domainName string, opts SyncOptions) (*ResponseStatus, error) {
+	pollInterval, timeout := opts.PollInterval, opts.Timeout
+	if pollInterval <= 0 {
+		pollInterval = DefaultSyncPollInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultSyncTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		resp, err := client.GetDomainStatus(ctx, GetDomainStatusRequest{DomainName: domainName})
+		if err != nil {
+			return nil, err
+		}
+		status := (*ResponseStatus)(resp)
+		if err := sendSyncEvent(ctx, opts.Progress, SyncEvent{Type: SyncEventPropagation, Status: status}); err != nil {
+			return status, err
+		}
+		switch status.PropagationStatus {
+		case PropagationStatusComplete:
+			return status, sendSyncEvent(ctx, opts.Progress, SyncEvent{Type: SyncEventComplete, Status: status})
+		case PropagationStatusDenied:
+			return status, fmt.Errorf("%w: %s", ErrPropagationDenied, status.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("waiting for propagation, last status %s: %w", status.PropagationStatus, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func sendSyncEvent(ctx context.Context, progress chan<- SyncEvent, event SyncEvent) error {
+	if progress == nil {
+		return nil
+	}
+	select {
+	case progress <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,312 @@
+package gtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncCall is a request expected by the scripted sync server together with the response to it
+type syncCall struct {
+	method       string
+	path         string
+	status       int
+	responseBody string
+	// requestBody, if set, checks the decoded request body
+	requestBody func(body map[string]interface{})
+}
+
+func syncServer(t *testing.T, current *Domain, calls []syncCall) (*httptest.Server, func()) {
+	var mu sync.Mutex
+	var i int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodGet && r.URL.Path == "/config-gtm/v1/domains/"+current.Name {
+			w.WriteHeader(http.StatusOK)
+			assert.NoError(t, json.NewEncoder(w).Encode(current))
+			return
+		}
+		if !assert.Less(t, i, len(calls), "unexpected request %s %s", r.Method, r.URL) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		call := calls[i]
+		i++
+		assert.Equal(t, call.method, r.Method)
+		assert.Equal(t, call.path, r.URL.Path)
+		if call.requestBody != nil {
+			var body map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			call.requestBody(body)
+		}
+		w.WriteHeader(call.status)
+		body := call.responseBody
+		if body == "" {
+			body = "{}"
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	return server, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, len(calls), i, "not all expected requests were sent")
+	}
+}
+
+func syncDomains() (current *Domain, desired *Domain) {
+	property := func(name string, weight float64) Property {
+		return Property{
+			Name: name, Type: "weighted-round-robin", ScoreAggregationType: "mean", HandoutMode: "normal", HandoutLimit: 8,
+			TrafficTargets: []TrafficTarget{{DatacenterID: 3131, Enabled: true, Weight: ptr.To(weight), Servers: []string{"1.2.3.4"}}},
+		}
+	}
+	geoMap := GeoMap{
+		Name:              "geo",
+		DefaultDatacenter: &DatacenterBase{DatacenterID: 5400, Nickname: "Default"},
+		Assignments:       []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Countries: []string{"PL"}}},
+	}
+
+	current = &Domain{
+		Name:        "example.akadns.net",
+		Type:        "full",
+		Datacenters: []Datacenter{{DatacenterID: 3131, Nickname: "dc1", City: "Krakow"}, {DatacenterID: 3132, Nickname: "old"}, {DatacenterID: 5400, Nickname: "Default"}},
+		Properties: []Property{
+			func() Property {
+				p := property("unchanged", 1)
+				p.Links = []Link{{Rel: "self", Href: "/properties/unchanged"}}
+				p.LastModified = "2024-01-01T00:00:00.000+00:00"
+				return p
+			}(),
+			property("changed", 1),
+			property("removed", 1),
+		},
+		GeographicMaps: []GeoMap{geoMap},
+		Resources:      []Resource{{Name: "old-resource", Type: "Download score"}},
+	}
+	desired = &Domain{
+		Name:           "example.akadns.net",
+		Type:           "full",
+		Datacenters:    []Datacenter{{Nickname: "dc1", City: "Warsaw"}, {Nickname: "new"}},
+		Properties:     []Property{property("unchanged", 1), property("changed", 2), property("added", 1)},
+		GeographicMaps: []GeoMap{geoMap},
+		CIDRMaps: []CIDRMap{{
+			Name:              "cidr",
+			DefaultDatacenter: &DatacenterBase{DatacenterID: 5400, Nickname: "Default"},
+			Assignments:       []CIDRAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Blocks: []string{"10.0.0.0/8"}}},
+		}},
+	}
+	return current, desired
+}
+
+func TestPlanDomainSync(t *testing.T) {
+	current, desired := syncDomains()
+	changes := PlanDomainSync(current, desired)
+
+	type change struct {
+		Action SyncAction
+		Object SyncObjectType
+		Name   string
+	}
+	var actual []change
+	for _, c := range changes {
+		actual = append(actual, change{c.Action, c.Object, c.Name})
+	}
+	assert.Equal(t, []change{
+		{SyncActionUpdate, SyncObjectDatacenter, "3131"},
+		{SyncActionCreate, SyncObjectDatacenter, "new"},
+		{SyncActionCreate, SyncObjectCIDRMap, "cidr"},
+		{SyncActionUpdate, SyncObjectProperty, "changed"},
+		{SyncActionCreate, SyncObjectProperty, "added"},
+		{SyncActionDelete, SyncObjectResource, "old-resource"},
+		{SyncActionDelete, SyncObjectProperty, "removed"},
+		{SyncActionDelete, SyncObjectDatacenter, "3132"},
+	}, actual)
+
+	assert.Empty(t, PlanDomainSync(current, current))
+}
+
+func TestPlanDomainSync_ServerPopulatedFields(t *testing.T) {
+	desiredProperty := Property{
+		Name: "origin", Type: "failover", ScoreAggregationType: "mean", HandoutMode: "normal", HandoutLimit: 8,
+		TrafficTargets: []TrafficTarget{{DatacenterID: 3131, Enabled: true, Weight: ptr.To(1.0), Servers: []string{"1.2.3.4"}}},
+		LivenessTests:  []LivenessTest{{Name: "http", TestObjectProtocol: "HTTP", TestInterval: ptr.To(60), TestObject: "/"}},
+	}
+	currentProperty := desiredProperty
+	currentProperty.Links = []Link{{Rel: "self", Href: "/properties/origin"}}
+	currentProperty.LastModified = "2024-01-01T00:00:00.000+00:00"
+	currentProperty.HealthMax = ptr.To(0.0)
+	currentProperty.StaticTTL = ptr.To(600)
+	currentProperty.TrafficTargets = []TrafficTarget{{DatacenterID: 3131, Enabled: true, Weight: ptr.To(1.0), Servers: []string{"1.2.3.4"}, Precedence: ptr.To(0)}}
+	currentProperty.LivenessTests = []LivenessTest{{
+		Name: "http", TestObjectProtocol: "HTTP", TestInterval: ptr.To(60), TestObject: "/",
+		TestTimeout: ptr.To(float32(25)), HTTPMethod: ptr.To("GET"), Links: []Link{{Rel: "self", Href: "/properties/origin"}},
+	}}
+
+	current := &Domain{
+		Name:        "example.akadns.net",
+		Datacenters: []Datacenter{{DatacenterID: 3131, Nickname: "dc1", Virtual: true, ScorePenalty: ptr.To(0), Links: []Link{{Rel: "self"}}}},
+		Properties:  []Property{currentProperty},
+		Resources:   []Resource{{Name: "cpu", Type: "Download score", UpperBound: ptr.To(0), DecayRate: ptr.To(0.5)}},
+	}
+	desired := &Domain{
+		Name:        "example.akadns.net",
+		Datacenters: []Datacenter{{Nickname: "dc1"}},
+		Properties:  []Property{desiredProperty},
+		Resources:   []Resource{{Name: "cpu", Type: "Download score"}},
+	}
+	assert.Empty(t, PlanDomainSync(current, desired))
+
+	// fields set in the desired objects are still compared
+	desired.Properties[0].StaticTTL = ptr.To(300)
+	desired.Resources[0].UpperBound = ptr.To(10)
+	changes := PlanDomainSync(current, desired)
+	require.Len(t, changes, 2)
+	assert.Equal(t, SyncObjectProperty, changes[0].Object)
+	assert.Equal(t, SyncObjectResource, changes[1].Object)
+}
+
+func TestSyncDomain(t *testing.T) {
+	const domainPath = "/config-gtm/v1/domains/example.akadns.net"
+	changeCalls := []syncCall{
+		{method: http.MethodPut, path: domainPath + "/datacenters/3131", status: http.StatusOK},
+		{method: http.MethodPost, path: domainPath + "/datacenters", status: http.StatusCreated},
+		{method: http.MethodPut, path: domainPath + "/cidr-maps/cidr", status: http.StatusCreated},
+		{method: http.MethodPut, path: domainPath + "/properties/changed", status: http.StatusOK},
+		{method: http.MethodPut, path: domainPath + "/properties/added", status: http.StatusCreated},
+		{method: http.MethodDelete, path: domainPath + "/resources/old-resource", status: http.StatusOK},
+		{method: http.MethodDelete, path: domainPath + "/properties/removed", status: http.StatusOK},
+		{method: http.MethodDelete, path: domainPath + "/datacenters/3132", status: http.StatusOK},
+	}
+	statusCall := func(status string) syncCall {
+		return syncCall{method: http.MethodGet, path: domainPath + "/status/current", status: http.StatusOK,
+			responseBody: `{"propagationStatus": "` + status + `", "message": "status ` + status + `"}`}
+	}
+
+	tests := map[string]struct {
+		opts           SyncOptions
+		calls          []syncCall
+		expectedStatus string
+		withError      error
+	}{
+		"changes applied and propagated": {
+			opts:           SyncOptions{PollInterval: time.Millisecond},
+			calls:          append(append([]syncCall{}, changeCalls...), statusCall("PENDING"), statusCall("COMPLETE")),
+			expectedStatus: PropagationStatusComplete,
+		},
+		"dry run": {
+			opts: SyncOptions{DryRun: true},
+		},
+		"propagation denied": {
+			opts:           SyncOptions{PollInterval: time.Millisecond},
+			calls:          append(append([]syncCall{}, changeCalls...), statusCall("DENIED")),
+			expectedStatus: PropagationStatusDenied,
+			withError:      ErrPropagationDenied,
+		},
+		"propagation timeout": {
+			opts:           SyncOptions{PollInterval: time.Hour, Timeout: 10 * time.Millisecond},
+			calls:          append(append([]syncCall{}, changeCalls...), statusCall("PENDING")),
+			expectedStatus: "PENDING",
+			withError:      context.DeadlineExceeded,
+		},
+		"change fails": {
+			calls: []syncCall{
+				{method: http.MethodPut, path: domainPath + "/datacenters/3131", status: http.StatusBadRequest,
+					responseBody: `{"type": "bad_request", "title": "Bad Request", "status": 400}`},
+			},
+			withError: &Error{Type: "bad_request", Title: "Bad Request", StatusCode: http.StatusBadRequest},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			current, desired := syncDomains()
+			mockServer, verify := syncServer(t, current, test.calls)
+			defer mockServer.Close()
+			client := mockAPIClient(t, mockServer)
+
+			result, err := SyncDomain(context.Background(), client, desired, test.opts)
+			verify()
+			require.NotNil(t, result)
+			assert.Len(t, result.Changes, 8)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, ErrSyncDomain), "want: %s; got: %s", ErrSyncDomain, err)
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if test.expectedStatus == "" {
+				assert.Nil(t, result.Status)
+				return
+			}
+			require.NotNil(t, result.Status)
+			assert.Equal(t, test.expectedStatus, result.Status.PropagationStatus)
+		})
+	}
+}
+
+func TestSyncDomain_UpdateKeepsUnsetFields(t *testing.T) {
+	current, _ := syncDomains()
+	current.Properties[1].StaticTTL = ptr.To(600)
+	current.Properties[1].UnknownFields = UnknownFields{"newField": json.RawMessage(`"kept"`)}
+	current.Properties[1].TrafficTargets[0].UnknownFields = UnknownFields{"targetField": json.RawMessage(`1`)}
+	desired, _ := syncDomains()
+	desired.Properties[1].HandoutLimit = 4
+
+	const domainPath = "/config-gtm/v1/domains/example.akadns.net"
+	mockServer, verify := syncServer(t, current, []syncCall{
+		{method: http.MethodPut, path: domainPath + "/properties/changed", status: http.StatusOK,
+			requestBody: func(body map[string]interface{}) {
+				assert.Equal(t, float64(4), body["handoutLimit"])
+				assert.Equal(t, float64(600), body["staticTTL"])
+				assert.Equal(t, "kept", body["newField"])
+				assert.Equal(t, float64(1), body["trafficTargets"].([]interface{})[0].(map[string]interface{})["targetField"])
+			}},
+		{method: http.MethodGet, path: domainPath + "/status/current", status: http.StatusOK, responseBody: `{"propagationStatus": "COMPLETE"}`},
+	})
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	result, err := SyncDomain(context.Background(), client, desired, SyncOptions{PollInterval: time.Millisecond})
+	verify()
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 1)
+	assert.Equal(t, SyncActionUpdate, result.Changes[0].Action)
+}
+
+func TestSyncDomain_Progress(t *testing.T) {
+	current, _ := syncDomains()
+	desired, _ := syncDomains()
+	desired.Properties[1].HandoutLimit = 4
+
+	const domainPath = "/config-gtm/v1/domains/example.akadns.net"
+	mockServer, verify := syncServer(t, current, []syncCall{
+		{method: http.MethodPut, path: domainPath + "/properties/changed", status: http.StatusOK},
+		{method: http.MethodGet, path: domainPath + "/status/current", status: http.StatusOK, responseBody: `{"propagationStatus": "COMPLETE"}`},
+	})
+	defer mockServer.Close()
+	client := mockAPIClient(t, mockServer)
+
+	progress := make(chan SyncEvent, 10)
+	_, err := SyncDomain(context.Background(), client, desired, SyncOptions{Progress: progress})
+	require.NoError(t, err)
+	verify()
+	close(progress)
+
+	var types []SyncEventType
+	for e := range progress {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []SyncEventType{SyncEventChangeApplied, SyncEventPropagation, SyncEventComplete}, types)
+}
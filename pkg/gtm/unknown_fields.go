@@ -0,0 +1,123 @@
+package gtm
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownFields contains JSON fields of an object which are not mapped to its struct fields.
+// They are kept when the object is decoded and sent back as they were when it's encoded, so that
+// read-modify-write of objects does not drop fields added to the API after this version of the library.
+// DatacenterBase and LoadObject don't keep unknown fields, as they are embedded in other types, so unknown fields of
+// default datacenters of maps and default load objects of datacenters are dropped.
+type UnknownFields map[string]json.RawMessage
+
+// knownFieldNames caches lowercase JSON names of struct fields per type
+var knownFieldNames sync.Map
+
+// unmarshalKeepingUnknown decodes data into known, which has to be a pointer to a struct without custom
+// unmarshalling, and stores fields not matching any of its JSON field names in unknown
+func unmarshalKeepingUnknown(data []byte, known interface{}, unknown *UnknownFields) error {
+	if err := json.Unmarshal(data, known); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	names := jsonFieldNames(reflect.TypeOf(known).Elem())
+	for name := range fields {
+		// encoding/json matches field names case-insensitively
+		if names[strings.ToLower(name)] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+	*unknown = fields
+	return nil
+}
+
+// marshalKeepingUnknown encodes known, which has to be a struct without custom marshalling, and appends unknown fields
+// sorted by name. Unknown fields are skipped if their names match any JSON field name of known.
+func marshalKeepingUnknown(known interface{}, unknown UnknownFields) ([]byte, error) {
+	data, err := json.Marshal(known)
+	if err != nil || len(unknown) == 0 {
+		return data, err
+	}
+
+	names := jsonFieldNames(reflect.TypeOf(known))
+	keys := make([]string, 0, len(unknown))
+	for k := range unknown {
+		if !names[strings.ToLower(k)] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, k := range keys {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		value := unknown[k]
+		if len(value) == 0 {
+			value = json.RawMessage("null")
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonFieldNames returns lowercase JSON names of fields of the struct type, including fields of embedded structs
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	if names, ok := knownFieldNames.Load(t); ok {
+		return names.(map[string]bool)
+	}
+
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for n := range jsonFieldNames(embedded) {
+					names[n] = true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+
+	knownFieldNames.Store(t, names)
+	return names
+}
@@ -0,0 +1,169 @@
+package gtm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v9/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperty_JSONRoundTrip(t *testing.T) {
+	input := `{
+		"name": "origin",
+		"type": "failover",
+		"healthThreshold": 0,
+		"healthMax": null,
+		"futureSetting": {"enabled":true},
+		"trafficTargets": [{"datacenterId": 3131, "enabled": true, "weight": 0, "futureTargetField": 1}],
+		"livenessTests": [{"name": "lt", "errorPenalty": 0, "hostHeader": "foo.example.com"}]
+	}`
+
+	var p Property
+	require.NoError(t, json.Unmarshal([]byte(input), &p))
+	assert.Equal(t, ptr.To(0.0), p.HealthThreshold)
+	assert.Nil(t, p.HealthMax)
+	assert.Equal(t, UnknownFields{"futureSetting": json.RawMessage(`{"enabled":true}`)}, p.UnknownFields)
+	assert.Equal(t, UnknownFields{"futureTargetField": json.RawMessage(`1`)}, p.TrafficTargets[0].UnknownFields)
+	assert.Equal(t, UnknownFields{"hostHeader": json.RawMessage(`"foo.example.com"`)}, p.LivenessTests[0].UnknownFields)
+	assert.Equal(t, ptr.To(0.0), p.LivenessTests[0].ErrorPenalty)
+
+	p.Comments = "modified"
+	output, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(output, &fields))
+	assert.JSONEq(t, `{"enabled": true}`, string(fields["futureSetting"]))
+	assert.JSONEq(t, `0`, string(fields["healthThreshold"]))
+	assert.JSONEq(t, `"modified"`, string(fields["comments"]))
+	assert.NotContains(t, fields, "healthMax")
+	assert.JSONEq(t, `[{"datacenterId": 3131, "enabled": true, "weight": 0, "futureTargetField": 1}]`, string(fields["trafficTargets"]))
+
+	var again Property
+	require.NoError(t, json.Unmarshal(output, &again))
+	assert.Equal(t, p, again)
+}
+
+func TestNestedObjects_JSONRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		object interface{}
+	}{
+		"geographic map": {
+			input:  `{"name": "geo", "assignments": [{"datacenterId": 3131, "countries": ["PL"], "futureField": 1}]}`,
+			object: &GeoMap{},
+		},
+		"CIDR map": {
+			input:  `{"name": "cidr", "assignments": [{"datacenterId": 3131, "blocks": ["10.0.0.0/8"], "futureField": 1}]}`,
+			object: &CIDRMap{},
+		},
+		"AS map": {
+			input:  `{"name": "as", "assignments": [{"datacenterId": 3131, "asNumbers": [1], "futureField": 1}]}`,
+			object: &ASMap{},
+		},
+		"resource": {
+			input: `{"name": "r", "type": "t", "resourceInstances": [{"datacenterId": 3131, "useDefaultLoadObject": false,
+				"loadObject": "/load", "futureField": 1}]}`,
+			object: &Resource{},
+		},
+		"property": {
+			input: `{"name": "p", "type": "static", "staticRRSets": [{"type": "A", "ttl": 30, "rdata": ["1.2.3.4"], "futureField": 1}],
+				"livenessTests": [{"name": "lt", "httpHeaders": [{"name": "h", "value": "v", "futureField": 1}]}]}`,
+			object: &Property{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, json.Unmarshal([]byte(test.input), test.object))
+			output, err := json.Marshal(test.object)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Count(test.input, `"futureField": 1`), strings.Count(string(output), `"futureField":1`), string(output))
+		})
+	}
+}
+
+func TestZeroValues_JSONRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		object interface{}
+	}{
+		"property": {
+			input: `{"name": "p", "type": "failover", "stickinessBonusPercentage": 0, "stickinessBonusConstant": 0,
+				"staticTTL": 0, "minLiveFraction": 0, "dynamicTTL": 0, "failoverDelay": 0, "failbackDelay": 0,
+				"weightedHashBitsForIPv4": 0, "weightedHashBitsForIPv6": 0}`,
+			object: &Property{},
+		},
+		"traffic target": {
+			input:  `{"datacenterId": 3131, "enabled": false, "weight": 0}`,
+			object: &TrafficTarget{},
+		},
+		"liveness test": {
+			input:  `{"name": "lt", "testInterval": 0, "testObjectPort": 0, "testTimeout": 0}`,
+			object: &LivenessTest{},
+		},
+		"datacenter": {
+			input:  `{"datacenterId": 3131, "latitude": 0, "longitude": 0, "scorePenalty": 0}`,
+			object: &Datacenter{},
+		},
+		"domain": {
+			input: `{"name": "d", "type": "basic", "defaultTimeoutPenalty": 0, "servermonitorLivenessCount": 0,
+				"servermonitorLoadCount": 0, "pingInterval": 0, "maxTTL": 0, "loadImbalancePercentage": 0,
+				"defaultHealthMax": 0, "mapUpdateInterval": 0, "maxProperties": 0, "maxResources": 0,
+				"defaultErrorPenalty": 0, "maxTestTimeout": 0, "defaultHealthMultiplier": 0, "minTTL": 0,
+				"defaultHealthThreshold": 0, "minTestInterval": 0, "pingPacketSize": 0}`,
+			object: &Domain{},
+		},
+		"resource": {
+			input:  `{"name": "r", "type": "t", "upperBound": 0}`,
+			object: &Resource{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, json.Unmarshal([]byte(test.input), test.object))
+			output, err := json.Marshal(test.object)
+			require.NoError(t, err)
+
+			var input, fields map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &input))
+			require.NoError(t, json.Unmarshal(output, &fields))
+			for key, value := range input {
+				assert.Equal(t, value, fields[key], key)
+			}
+		})
+	}
+}
+
+func TestUnknownFields_KnownNamesTakePrecedence(t *testing.T) {
+	// encoding/json matches field names case-insensitively, so such fields are not unknown
+	var m GeoMap
+	require.NoError(t, json.Unmarshal([]byte(`{"Name": "geo", "other": "x"}`), &m))
+	assert.Equal(t, "geo", m.Name)
+	assert.Equal(t, UnknownFields{"other": json.RawMessage(`"x"`)}, m.UnknownFields)
+
+	m.UnknownFields["name"] = json.RawMessage(`"ignored"`)
+	output, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "geo", "defaultDatacenter": null, "other": "x"}`, string(output))
+
+	empty, err := marshalKeepingUnknown(struct{}{}, UnknownFields{"b": json.RawMessage(`2`), "a": nil})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":null,"b":2}`, string(empty))
+}
+
+func TestGetResponses_KeepUnknownFields(t *testing.T) {
+	var domain GetDomainResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "example.akadns.net", "type": "basic", "newDomainSetting": 5,
+		"datacenters": [{"datacenterId": 3131, "pingInterval": 0, "newDatacenterSetting": "x"}]}`), &domain))
+	assert.Equal(t, UnknownFields{"newDomainSetting": json.RawMessage(`5`)}, domain.UnknownFields)
+	assert.Equal(t, ptr.To(0), domain.Datacenters[0].PingInterval)
+	assert.Equal(t, UnknownFields{"newDatacenterSetting": json.RawMessage(`"x"`)}, domain.Datacenters[0].UnknownFields)
+
+	var resource GetResourceResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "cpu", "type": "t", "decayRate": 0, "newResourceSetting": true}`), &resource))
+	assert.Equal(t, ptr.To(0.0), resource.DecayRate)
+	assert.Equal(t, UnknownFields{"newResourceSetting": json.RawMessage(`true`)}, resource.UnknownFields)
+}
@@ -60,10 +60,6 @@ func (s *session) Exec(r *http.Request, out interface{}, in ...interface{}) (*ht
 		r.ContentLength = int64(len(data))
 	}
 
-	s.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return s.Sign(req)
-	}
-
 	if err := s.Sign(r); err != nil {
 		return nil, err
 	}
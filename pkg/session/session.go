@@ -91,6 +91,14 @@ func New(opts ...Option) (Session, error) {
 		s.signer = config
 	}
 
+	// redirected requests have to be signed again. The client is copied, so that the one passed with WithClient
+	// or http.DefaultClient is not modified, and it is not modified by Exec, so that it can be used concurrently
+	client := *s.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return s.Sign(req)
+	}
+	s.client = &client
+
 	return s, nil
 }
 
@@ -68,6 +68,13 @@ func TestNew(t *testing.T) {
 			}
 			res, err := New(options...)
 			require.NoError(t, err)
+			sess := res.(*session)
+			require.NotNil(t, sess.client.CheckRedirect)
+			assert.NotSame(t, test.expected.client, sess.client)
+			if test.client != nil {
+				assert.Nil(t, test.client.CheckRedirect)
+			}
+			sess.client.CheckRedirect = nil
 			assert.Equal(t, test.expected, res)
 		})
 	}